@@ -0,0 +1,165 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/chrstphlbr/pa/pkg/bench"
+	"github.com/chrstphlbr/pa/pkg/bootstrap"
+	"github.com/chrstphlbr/pa/pkg/stat"
+)
+
+// paramAggregation groups the results of benchmarks that only differ in the performance parameter param,
+// to report a combined CI (geometric mean of the ratios) and the ratios per parameter value (trend) per group
+type paramAggregation struct {
+	param              string
+	significanceLevels []float64
+	keys               []string
+	groups             map[string]*paramGroup
+}
+
+type paramGroup struct {
+	benchmark *bench.B
+	members   []paramMember
+}
+
+type paramMember struct {
+	value     string
+	ciRatios  []stat.CIRatio
+	simRatios []float64
+}
+
+func newParamAggregation(param string, significanceLevels []float64) *paramAggregation {
+	return &paramAggregation{
+		param:              param,
+		significanceLevels: significanceLevels,
+		groups:             map[string]*paramGroup{},
+	}
+}
+
+// add adds a result, unless it has no ratio (i.e., only one version has a result) or the benchmark does not have the parameter
+func (a *paramAggregation) add(res bootstrap.CIRatioResult) {
+	if res.Err != nil || len(res.SimulatedRatios) == 0 {
+		return
+	}
+	value, ok := res.Benchmark.PerfParams.Get()[a.param]
+	if !ok {
+		return
+	}
+
+	b := res.Benchmark.WithoutPerfParam(a.param)
+	key := b.String()
+	g, ok := a.groups[key]
+	if !ok {
+		g = &paramGroup{
+			benchmark: b,
+		}
+		a.groups[key] = g
+		a.keys = append(a.keys, key)
+	}
+	g.members = append(g.members, paramMember{
+		value:     value,
+		ciRatios:  res.CIRatios,
+		simRatios: res.SimulatedRatios,
+	})
+}
+
+// print writes the combined CI and the trend over the parameter values of every group as comment rows
+func (a *paramAggregation) print(w io.Writer) {
+	for _, key := range a.keys {
+		g := a.groups[key]
+		sort.SliceStable(g.members, func(i, j int) bool {
+			return bench.ComparePerfParamValues(g.members[i].value, g.members[j].value) < 0
+		})
+
+		metrics := make([]float64, len(g.members))
+		simRatios := make([][]float64, len(g.members))
+		for i, m := range g.members {
+			metrics[i] = m.ciRatios[0].CIRatio.Metric
+			simRatios[i] = m.simRatios
+		}
+
+		cis, err := bootstrap.GeometricMeanCI(metrics, simRatios, nil, a.significanceLevels)
+		if err != nil {
+			fmt.Fprintf(w, "# aggregate over %s: %s: error: %v\n", a.param, key, err)
+			continue
+		}
+
+		for l, ci := range cis {
+			fmt.Fprintf(w, "# aggregate over %s: %s: geometric mean ratio %e [%e, %e] at %.2f (%d values)\n", a.param, key, ci.Metric, ci.Lower, ci.Upper, ci.Level, len(g.members))
+
+			trend := make([]string, len(g.members))
+			for i, m := range g.members {
+				cir := m.ciRatios[l].CIRatio
+				trend[i] = fmt.Sprintf("%s=%s: %e [%e, %e]", a.param, m.value, cir.Metric, cir.Lower, cir.Upper)
+			}
+			fmt.Fprintf(w, "# trend over %s: %s: %s\n", a.param, key, strings.Join(trend, "; "))
+		}
+	}
+}
+
+// suiteAggregation combines the ratios of all benchmarks into a CI of their (weighted) geometric mean,
+// i.e., the overall performance change of the suite
+type suiteAggregation struct {
+	significanceLevels []float64
+	weights            *bench.Weights
+	gm                 *bootstrap.GeometricMean
+	skipped            int
+	err                error
+	// nonPositive are the errors of the benchmarks that are skipped because of a non-positive ratio (see bootstrap.ErrNonPositiveRatio)
+	nonPositive []error
+}
+
+// newSuiteAggregation weights all benchmarks equally if weights is nil
+func newSuiteAggregation(weights *bench.Weights, significanceLevels []float64) *suiteAggregation {
+	return &suiteAggregation{
+		significanceLevels: significanceLevels,
+		weights:            weights,
+		gm:                 bootstrap.NewGeometricMean(),
+	}
+}
+
+// add adds a result, unless it has no ratio (i.e., only one version has a result)
+func (a *suiteAggregation) add(res bootstrap.CIRatioResult) {
+	if res.Err != nil || len(res.SimulatedRatios) == 0 {
+		a.skipped++
+		return
+	}
+
+	w := 1.0
+	if a.weights != nil {
+		w, _ = a.weights.Weight(res.Benchmark)
+	}
+	err := a.gm.Add(res.CIRatios[0].CIRatio.Metric, res.SimulatedRatios, w)
+	if err == nil {
+		return
+	}
+	err = fmt.Errorf("%s: %w", res.Benchmark, err)
+	if errors.Is(err, bootstrap.ErrNonPositiveRatio) {
+		a.nonPositive = append(a.nonPositive, err)
+	} else if a.err == nil {
+		a.err = err
+	}
+}
+
+// print writes the suite's CIs and the skipped benchmarks with a non-positive ratio as comment rows
+func (a *suiteAggregation) print(w io.Writer) {
+	for _, err := range a.nonPositive {
+		fmt.Fprintf(w, "# suite: skipped %v\n", err)
+	}
+	if a.err != nil {
+		fmt.Fprintf(w, "# suite: error: %v\n", a.err)
+		return
+	}
+	cis, err := a.gm.CIs(a.significanceLevels)
+	if err != nil {
+		fmt.Fprintf(w, "# suite: error: %v\n", err)
+		return
+	}
+	for _, ci := range cis {
+		fmt.Fprintf(w, "# suite: geometric mean ratio %e [%e, %e] at %.2f (%d benchmarks, %d without ratio)\n", ci.Metric, ci.Lower, ci.Upper, ci.Level, a.gm.Len(), a.skipped)
+	}
+}
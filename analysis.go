@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/chrstphlbr/pa/internal/ordered"
+	"github.com/chrstphlbr/pa/pkg/bench"
+	"github.com/chrstphlbr/pa/pkg/bootstrap"
+	"github.com/chrstphlbr/pa/pkg/permutation"
+	"github.com/chrstphlbr/pa/pkg/stat"
+)
+
+// detAnalyses are the analyses of det per benchmark, i.e., bootstrap CIs (unless ciFunc and ciRatioFunc are nil), a permutation test (unless testFunc is nil), a Mann-Whitney U test (if rank is true), and summaries of both versions (if summary is true)
+type detAnalyses struct {
+	ciFunc      bootstrap.CIFunc
+	ciRatioFunc bootstrap.CIRatioFunc
+	testFunc    permutation.TestFunc
+	rank        bool
+	summary     bool
+}
+
+// detResult is the result of all analyses of a benchmark, where Test and Rank are only set if both versions have a result
+type detResult struct {
+	bootstrap.CIRatioResult
+	Test     *permutation.Result
+	Rank     *stat.RankResult
+	SummaryA *versionSummary
+	SummaryB *versionSummary
+}
+
+// detResults performs the analyses of up to maxInFlight benchmarks concurrently, while the results are still sent in benchmark order (see bootstrap.ConcurrentCIRatios)
+func detResults(ctx context.Context, c1, c2 bench.Chan, an detAnalyses, maxInFlight int) <-chan detResult {
+	out := make(chan detResult)
+	o := ordered.New(ctx, maxInFlight, func() { close(out) })
+
+	go func() {
+		defer o.Close()
+		for p := range bench.PairChansContext(ctx, c1, c2) {
+			p := p
+			o.Submit(func() func() {
+				res := an.analyze(ctx, p)
+				return func() {
+					select {
+					case out <- res:
+					case <-ctx.Done():
+					}
+				}
+			})
+		}
+	}()
+
+	return out
+}
+
+func (an detAnalyses) analyze(ctx context.Context, p bench.ExecutionPair) detResult {
+	var res detResult
+	if an.ciFunc != nil {
+		res.CIRatioResult = bootstrap.PairResult(ctx, p, an.ciFunc, an.ciRatioFunc)
+		if res.Err != nil {
+			return res
+		}
+	} else {
+		res.Err = p.Err
+		if p.A != nil {
+			res.Benchmark = p.A.Benchmark
+		} else if p.B != nil {
+			res.Benchmark = p.B.Benchmark
+		}
+	}
+	if an.summary {
+		res.SummaryA, res.SummaryB = summarize(p.A), summarize(p.B)
+	}
+	if p.A == nil || p.B == nil {
+		return res
+	}
+
+	if an.testFunc != nil {
+		t, err := an.testFunc(ctx, p.A, p.B)
+		if err != nil {
+			res.Err = fmt.Errorf("%v: %w", p.A.Benchmark, err)
+			return res
+		}
+		res.Test = &t
+	}
+
+	if an.rank {
+		r, err := stat.MannWhitneyU(p.A.FlatSlice(bench.MeanInvocations), p.B.FlatSlice(bench.MeanInvocations))
+		if err != nil {
+			res.Err = fmt.Errorf("%v: %w", p.A.Benchmark, err)
+			return res
+		}
+		res.Rank = &r
+	}
+	return res
+}
+
+// rankColumns formats the p-value, A12, and Cliff's delta of a Mann-Whitney U test, which are NaN if there is no test (i.e., only one version has a result)
+func rankColumns(r *stat.RankResult) string {
+	if r == nil {
+		return "NaN;NaN;NaN"
+	}
+	return fmt.Sprintf("%e;%e;%e", r.PValue, r.A12, r.CliffsDelta)
+}
+
+// permutationTest configures the permutation test of det, which is disabled if Permutations is 0
+type permutationTest struct {
+	Permutations int
+	Level        permutation.Level
+	// Only defines whether the test is performed instead of computing bootstrap CIs
+	Only bool
+}
+
+// permutationColumns formats the p-value and the number of permutations, which are NaN and 0 if there is no test (i.e., only one version has a result)
+func permutationColumns(r *permutation.Result) string {
+	if r == nil {
+		return "NaN;0"
+	}
+	return fmt.Sprintf("%e;%d", r.PValue, r.Permutations)
+}
+
+// permutationLine formats a CSV row of the permutation test only
+func permutationLine(b *bench.B, r *permutation.Result) string {
+	ratio, level := "NaN", ""
+	if r != nil {
+		ratio, level = fmt.Sprintf("%e", r.Ratio), r.Level.String()
+	}
+	return fmt.Sprintf("%s;%s;%s;%s;%s;%s", b.Name, b.FunctionParams, b.PerfParams, ratio, permutationColumns(r), level)
+}
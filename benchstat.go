@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/chrstphlbr/pa/pkg/bench"
+	"github.com/chrstphlbr/pa/pkg/stat"
+)
+
+type outputFormat int
+
+const (
+	outputCSV outputFormat = iota
+	outputBenchstat
+)
+
+func (o outputFormat) String() string {
+	switch o {
+	case outputCSV:
+		return "csv"
+	case outputBenchstat:
+		return "benchstat"
+	}
+	return fmt.Sprintf("outputFormat(%d)", int(o))
+}
+
+func parseOutputFormat(s string) (outputFormat, error) {
+	switch s {
+	case "csv":
+		return outputCSV, nil
+	case "benchstat":
+		return outputBenchstat, nil
+	}
+	return outputCSV, fmt.Errorf("invalid output format '%s' (available: csv, benchstat)", s)
+}
+
+// versionSummary summarizes the iterations' mean invocations of a version similar to benchstat, i.e., the median and the maximum relative deviation from it of the values within Tukey's fences (1.5 times the interquartile range)
+type versionSummary struct {
+	Median float64
+	Spread float64
+	N      int
+}
+
+func summarize(e *bench.Execution) *versionSummary {
+	if e == nil {
+		return nil
+	}
+	values := e.FlatSlice(bench.MeanInvocations)
+	sort.Float64s(values)
+	m := stat.Median(values)
+	q1, q3 := sortedQuantile(values, 0.25), sortedQuantile(values, 0.75)
+	lower, upper := q1-1.5*(q3-q1), q3+1.5*(q3-q1)
+	var spread float64
+	for _, v := range values {
+		if v < lower || v > upper {
+			continue
+		}
+		spread = math.Max(spread, math.Abs(v-m))
+	}
+	if m != 0 {
+		spread /= math.Abs(m)
+	}
+	return &versionSummary{
+		Median: m,
+		Spread: spread,
+		N:      len(values),
+	}
+}
+
+// sortedQuantile returns the p-quantile of the sorted values with linear interpolation
+func sortedQuantile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return math.NaN()
+	}
+	h := p * float64(len(sorted)-1)
+	l := int(math.Floor(h))
+	if l+1 >= len(sorted) {
+		return sorted[l]
+	}
+	return sorted[l] + (h-float64(l))*(sorted[l+1]-sorted[l])
+}
+
+func (s *versionSummary) String() string {
+	if s == nil {
+		return ""
+	}
+	return fmt.Sprintf("%.3g ± %.0f%%", s.Median, s.Spread*100)
+}
+
+// benchstatTable formats the results of det in the table layout of benchstat, with a row per benchmark and its summaries of both versions,
+// the percentage change of the ratio (or '~' if the ratio's CI at the first significance level contains 1), the p-value (see hypothesisTest), and the number of iterations per version
+type benchstatTable struct {
+	test *hypothesisTest
+	rows []string
+}
+
+func newBenchstatTable(test *hypothesisTest) *benchstatTable {
+	return &benchstatTable{
+		test: test,
+	}
+}
+
+func (t *benchstatTable) add(res detResult) {
+	var delta, note string
+	if len(res.SimulatedRatios) > 0 && len(res.CIRatios) > 0 {
+		cir := res.CIRatios[0].CIRatio
+		if cir.Lower <= 1 && cir.Upper >= 1 {
+			delta = "~"
+		} else {
+			delta = fmt.Sprintf("%+.2f%%", (cir.Metric-1)*100)
+		}
+		note = fmt.Sprintf("(p=%.3f n=%d+%d)", t.test.pValue(res.CIRatioResult), res.SummaryA.N, res.SummaryB.N)
+	}
+
+	t.rows = append(t.rows, strings.Join([]string{
+		benchstatName(res.Benchmark),
+		res.SummaryA.String(),
+		res.SummaryB.String(),
+		delta,
+		note,
+	}, "\t"))
+}
+
+func (t *benchstatTable) print(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "name\told time/op\tnew time/op\tdelta\t")
+	for _, row := range t.rows {
+		fmt.Fprintf(tw, "%s\n", row)
+	}
+	tw.Flush()
+}
+
+// benchstatName formats a benchmark name in the style of Go's sub-benchmarks, e.g., 'Encode(x)/size=1024/mode=fast'
+func benchstatName(b *bench.B) string {
+	var sb strings.Builder
+	sb.WriteString(b.Name)
+	if len(b.FunctionParams) > 0 {
+		sb.WriteString("(")
+		sb.WriteString(b.FunctionParams.String())
+		sb.WriteString(")")
+	}
+	pps := b.PerfParams.Get()
+	for _, k := range b.PerfParams.Keys() {
+		sb.WriteString("/")
+		sb.WriteString(k)
+		sb.WriteString("=")
+		sb.WriteString(pps[k])
+	}
+	return sb.String()
+}
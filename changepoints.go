@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/chrstphlbr/pa/internal/ordered"
+	"github.com/chrstphlbr/pa/pkg/bench"
+	"github.com/chrstphlbr/pa/pkg/bootstrap"
+	"github.com/chrstphlbr/pa/pkg/changepoint"
+	"github.com/chrstphlbr/pa/pkg/stat"
+	"golang.org/x/exp/rand"
+)
+
+// changepointDetection configures the change-point detection (see changepoint.EDivisive)
+type changepointDetection struct {
+	Permutations int
+	MinSize      int
+}
+
+// changepointResult holds the change points of a benchmark's statistic across its commits and the ratio of the segments before and after every change point
+type changepointResult struct {
+	Benchmark    *bench.B
+	Commits      []string
+	ChangePoints []changepoint.ChangePoint
+	Ratios       [][]stat.CIRatio
+	Err          error
+}
+
+// groupCommits aligns the executions of the groups, where every group is a commit labelled by the group's label
+func groupCommits(ctx context.Context, cs []bench.Chan, groups []seriesGroup) <-chan bench.CommitExecutions {
+	out := make(chan bench.CommitExecutions)
+
+	go func() {
+		defer close(out)
+		for ae := range bench.AlignChansContext(ctx, cs...) {
+			ce := bench.CommitExecutions{
+				Benchmark: ae.Benchmark(),
+				Err:       ae.Err,
+			}
+			for i, e := range ae.Execs {
+				if e == nil {
+					continue
+				}
+				e.Commit = groups[i].Label
+				ce.Execs = append(ce.Execs, e)
+			}
+			select {
+			case out <- ce:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out
+}
+
+func changepointResults(ctx context.Context, cc <-chan bench.CommitExecutions, statFunc stat.StatisticFunc, ciRatioFunc bootstrap.CIRatioFunc, sigLevel float64, cpd changepointDetection, maxInFlight int) <-chan changepointResult {
+	out := make(chan changepointResult)
+	o := ordered.New(ctx, maxInFlight, func() { close(out) })
+
+	go func() {
+		defer o.Close()
+		for ce := range cc {
+			ce := ce
+			o.Submit(func() func() {
+				res := changepointAnalysis(ctx, ce, statFunc, ciRatioFunc, sigLevel, cpd)
+				return func() {
+					select {
+					case out <- res:
+					case <-ctx.Done():
+					}
+				}
+			})
+		}
+	}()
+
+	return out
+}
+
+// changepointSeed makes the seeds of concurrent change-point detections distinct
+var changepointSeed uint64
+
+// changepointAnalysis detects the change points of the statistic over the mean invocations of every commit of a benchmark and computes the ratio CIs of the segments before and after the change points, where a segment is bounded by the neighbouring change points and its commits are merged (see bench.CommitExecutions.Merge)
+func changepointAnalysis(ctx context.Context, ce bench.CommitExecutions, statFunc stat.StatisticFunc, ciRatioFunc bootstrap.CIRatioFunc, sigLevel float64, cpd changepointDetection) changepointResult {
+	if ce.Err != nil {
+		return changepointResult{
+			Err: ce.Err,
+		}
+	}
+
+	res := changepointResult{
+		Benchmark: ce.Benchmark,
+		Commits:   ce.Commits(),
+	}
+	fail := func(err error) changepointResult {
+		return changepointResult{
+			Benchmark: res.Benchmark,
+			Err:       fmt.Errorf("%v: %w", res.Benchmark, err),
+		}
+	}
+
+	series := make([]float64, len(ce.Execs))
+	for i, e := range ce.Execs {
+		series[i] = statFunc(e.FlatSlice(bench.MeanInvocations))
+	}
+
+	seed := uint64(time.Now().UnixNano()) + atomic.AddUint64(&changepointSeed, 1)
+	cps, err := changepoint.EDivisive(ctx, series, sigLevel, cpd.Permutations, cpd.MinSize, rand.New(rand.NewSource(seed)))
+	if err != nil {
+		return fail(err)
+	}
+	res.ChangePoints = cps
+
+	// the segments before and after every change point are bounded by the neighbouring change points
+	bounds := make([]int, 0, len(cps)+2)
+	bounds = append(bounds, 0)
+	for _, cp := range cps {
+		bounds = append(bounds, cp.Index)
+	}
+	bounds = append(bounds, len(ce.Execs))
+
+	res.Ratios = make([][]stat.CIRatio, len(cps))
+	for i := range cps {
+		before, err := ce.Merge(bounds[i], bounds[i+1])
+		if err != nil {
+			return fail(err)
+		}
+		after, err := ce.Merge(bounds[i+1], bounds[i+2])
+		if err != nil {
+			return fail(err)
+		}
+		cirs, _, err := ciRatioFunc(ctx, before, after)
+		if err != nil {
+			return fail(err)
+		}
+		res.Ratios[i] = cirs
+	}
+	return res
+}
+
+// changepoints reports the change points across the commits of every benchmark, either from the column 'commit' of the first file (if there are no groups) or with a commit per group
+func changepoints(ctx, inputCtx context.Context, cfg *config, ciRatioFunc bootstrap.CIRatioFunc) {
+	var cc <-chan bench.CommitExecutions
+	if cfg.groups == nil {
+		var err error
+		cc, err = commitInput(ctx, inputCtx, cfg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return
+		}
+	} else {
+		cs, err := groupInputs(inputCtx, cfg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return
+		}
+		cc = groupCommits(ctx, cs, cfg.groups)
+	}
+
+	rc := changepointResults(ctx, cc, cfg.statFunc.Func, ciRatioFunc, cfg.sigLevels[0], cfg.cpd, cfg.concurrentBenchmarks)
+
+	printMemStats(cfg.printMem)
+
+	var benchmarks, changed, changes int
+	for res := range rc {
+		if res.Err != nil {
+			fmt.Fprintf(os.Stderr, "Error while retrieving change-point result: %v\n", res.Err)
+			continue
+		}
+
+		benchmarks++
+		if len(res.ChangePoints) > 0 {
+			changed++
+			changes += len(res.ChangePoints)
+		}
+
+		b := res.Benchmark
+		for i, cp := range res.ChangePoints {
+			for _, cir := range res.Ratios[i] {
+				var line string
+				if cfg.outputMetric {
+					line = fmt.Sprintf(
+						"%s;%s;%s;%s;%s;%e;%e;%e;%e;%.2f",
+						b.Name, b.FunctionParams, b.PerfParams,
+						res.Commits[cp.Index], res.Commits[cp.Index-1], cp.PValue,
+						cir.CIRatio.Metric, cir.CIRatio.Lower, cir.CIRatio.Upper, cir.CIRatio.Level,
+					)
+				} else {
+					line = fmt.Sprintf(
+						"%s;%s;%s;%s;%s;%e;%e;%e;%.2f",
+						b.Name, b.FunctionParams, b.PerfParams,
+						res.Commits[cp.Index], res.Commits[cp.Index-1], cp.PValue,
+						cir.CIRatio.Lower, cir.CIRatio.Upper, cir.CIRatio.Level,
+					)
+				}
+				fmt.Fprintln(os.Stdout, line)
+			}
+		}
+		printMemStats(cfg.printMem)
+	}
+
+	fmt.Fprintf(os.Stdout, "# change points: %d in %d of %d benchmarks\n", changes, changed, benchmarks)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/chrstphlbr/pa/pkg/bench"
+	"github.com/chrstphlbr/pa/pkg/bootstrap"
+	"github.com/chrstphlbr/pa/pkg/stat"
+)
+
+// changepointCSV returns 12 commits of benchmark b1, which shifts from 10 to 20 at commit c6, and of benchmark b2, which does not shift
+func changepointCSV() string {
+	var sb strings.Builder
+	sb.WriteString("project;commit;benchmark;params;instance;trial;fork;iteration;mode;unit;value_count;value\n")
+	for _, b := range []string{"b1", "b2"} {
+		for c := 0; c < 12; c++ {
+			base := 10.0
+			if b == "b1" && c >= 6 {
+				base = 20
+			}
+			for it := 1; it <= 5; it++ {
+				fmt.Fprintf(&sb, "p;c%d;%s;;i1;1;1;%d;avgt;ns/op;1;%g\n", c, b, it, base+float64(it)/10)
+			}
+		}
+	}
+	return sb.String()
+}
+
+func TestChangepointAnalysis(t *testing.T) {
+	ctx := context.Background()
+	c, err := bench.FromCSVPerCommit(ctx, strings.NewReader(changepointCSV()), bench.Reduction{})
+	if err != nil {
+		t.Fatalf("Could not read CSV: %v", err)
+	}
+
+	sigLevel := 0.05
+	ciRatioFunc := bootstrap.CIRatioFuncSetup(bootstrap.FixedSimulations(200), 1, stat.Mean, []float64{sigLevel}, bootstrap.ResampleAllLevels(bootstrap.MeanInvocations))
+	cpd := changepointDetection{
+		Permutations: 199,
+		MinSize:      2,
+	}
+
+	var benchmarks int
+	for ce := range bench.GroupCommits(c) {
+		res := changepointAnalysis(ctx, ce, stat.Mean, ciRatioFunc, sigLevel, cpd)
+		if res.Err != nil {
+			t.Fatalf("Unexpected error: %v", res.Err)
+		}
+		benchmarks++
+		if len(res.Commits) != 12 {
+			t.Fatalf("%s: expected 12 commits, was %d", res.Benchmark.Name, len(res.Commits))
+		}
+
+		switch res.Benchmark.Name {
+		case "b1":
+			if len(res.ChangePoints) != 1 {
+				t.Fatalf("b1: expected 1 change point, was %d", len(res.ChangePoints))
+			}
+			cp := res.ChangePoints[0]
+			if commit := res.Commits[cp.Index]; commit != "c6" {
+				t.Fatalf("b1: expected change point at commit c6, was %s", commit)
+			}
+			if cp.PValue > sigLevel {
+				t.Fatalf("b1: expected p-value <= %g, was %g", sigLevel, cp.PValue)
+			}
+			if len(res.Ratios) != 1 || len(res.Ratios[0]) != 1 {
+				t.Fatalf("b1: expected one ratio CI, was %v", res.Ratios)
+			}
+			ratio := res.Ratios[0][0].CIRatio
+			if ratio.Lower < 1.9 || ratio.Upper > 2.1 {
+				t.Fatalf("b1: expected ratio CI around 2, was [%g, %g]", ratio.Lower, ratio.Upper)
+			}
+		case "b2":
+			if len(res.ChangePoints) != 0 {
+				t.Fatalf("b2: expected no change points, was %v", res.ChangePoints)
+			}
+		default:
+			t.Fatalf("Unexpected benchmark %s", res.Benchmark.Name)
+		}
+	}
+	if benchmarks != 2 {
+		t.Fatalf("Expected 2 benchmarks, was %d", benchmarks)
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/chrstphlbr/pa/pkg/bench"
+)
+
+// convert writes the executions of the input file to the output file in the binary format (see bench.BinaryMagic), with one execution per benchmark and commit to keep the project and commit of every execution
+func convert(ctx context.Context, cfg *config) {
+	in, out := cfg.f1[0], cfg.f2[0]
+	c, err := inputPerCommit(ctx, cfg, in)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return
+	}
+	if cfg.filter.Filter != nil {
+		c = bench.FilterChanContext(ctx, cfg.filter.Filter, c)
+	}
+
+	f, err := os.Create(out)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not create file '%s': %v\n", out, err)
+		return
+	}
+
+	n, err := bench.WriteBinary(ctx, f, c)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(out)
+		fmt.Fprintf(os.Stderr, "Could not convert '%s': %v\n", in, err)
+		return
+	}
+
+	fi, err := os.Stat(out)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return
+	}
+	fmt.Fprintf(os.Stdout, "# converted %d executions of %s to %s (%d bytes, format version %d)\n", n, in, out, fi.Size(), bench.BinaryVersion)
+}
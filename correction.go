@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/chrstphlbr/pa/pkg/bootstrap"
+	"github.com/chrstphlbr/pa/pkg/stat"
+)
+
+// hypothesisTest tests the null hypothesis that a benchmark's ratio equals null
+type hypothesisTest struct {
+	method bootstrap.PValueMethod
+	null   float64
+}
+
+// pValue returns NaN for results without ratio (i.e., only one version has a result)
+func (t *hypothesisTest) pValue(res bootstrap.CIRatioResult) float64 {
+	if len(res.SimulatedRatios) == 0 {
+		return math.NaN()
+	}
+	return t.method.PValue(res.SimulatedRatios, t.null)
+}
+
+// comparisonCorrection buffers all results to correct their p-values (see hypothesisTest) for multiple comparisons,
+// as the number of comparisons is only known once all benchmarks are computed
+type comparisonCorrection struct {
+	correction         stat.Correction
+	test               *hypothesisTest
+	significanceLevels []float64
+	results            []bootstrap.CIRatioResult
+	// lines are the rows of every result (per significance level) without the correction's columns
+	lines [][]string
+}
+
+func newComparisonCorrection(correction stat.Correction, test *hypothesisTest, significanceLevels []float64) *comparisonCorrection {
+	return &comparisonCorrection{
+		correction:         correction,
+		test:               test,
+		significanceLevels: significanceLevels,
+	}
+}
+
+func (c *comparisonCorrection) add(res bootstrap.CIRatioResult, lines []string) {
+	c.results = append(c.results, res)
+	c.lines = append(c.lines, lines)
+}
+
+// print writes the buffered rows followed by the columns p-value, adjusted p-value, and the ratio CI at the adjusted significance level (see stat.Correction.SigLevel).
+// Results without ratio (i.e., only one version has a result) have a NaN p-value and do not count as comparisons.
+func (c *comparisonCorrection) print(w io.Writer) {
+	ps := make([]float64, len(c.results))
+	for i, res := range c.results {
+		ps[i] = c.test.pValue(res)
+	}
+	adj := c.correction.Adjust(ps)
+
+	adjSigLevels := make([]float64, len(c.significanceLevels))
+	for i, sl := range c.significanceLevels {
+		adjSigLevels[i] = c.correction.SigLevel(sl, adj)
+	}
+
+	for i, res := range c.results {
+		var adjCIs []stat.CI
+		if !math.IsNaN(ps[i]) {
+			adjCIs = bootstrap.SimulationsCI(res.CIRatios[0].CIRatio.Metric, res.SimulatedRatios, adjSigLevels)
+		}
+		for l, line := range c.lines[i] {
+			var adjCI stat.CI
+			if adjCIs != nil {
+				adjCI = adjCIs[l]
+			}
+			fmt.Fprintf(w, "%s;%e;%e;%e;%e;%f\n", line, ps[i], adj[i], adjCI.Lower, adjCI.Upper, adjCI.Level)
+		}
+	}
+}
@@ -3,7 +3,15 @@ module github.com/chrstphlbr/pa
 go 1.14
 
 require (
-	golang.org/x/exp v0.0.0-20181106170214-d68db9428509
+	github.com/klauspost/compress v1.11.13
+	github.com/ulikunitz/xz v0.5.15
+	github.com/xitongsys/parquet-go v1.6.0
+	golang.org/x/exp v0.0.0-20200224162631-6cc2880d07d6
 	gonum.org/v1/gonum v0.0.0-20181106105144-6439751d3175
 	gonum.org/v1/netlib v0.0.0-20181029234149-ec6d1f5cefe6 // indirect
 )
+
+// github.com/xitongsys/parquet-go requires a newer golang.org/x/exp through
+// cloud.google.com/go/storage; keep the PCG source of golang.org/x/exp/rand
+// so that seeded resampling results stay reproducible.
+replace golang.org/x/exp => golang.org/x/exp v0.0.0-20181106170214-d68db9428509
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/chrstphlbr/pa/pkg/bench"
+	"github.com/chrstphlbr/pa/pkg/store"
+)
+
+// input reads the executions of a file (see openInput), which is either CSV, Parquet, or in the binary format (see bench.BinaryMagic), or of a snapshot of the store (see baselinePrefix), which is not reduced again
+func input(ctx context.Context, cfg *config, fn string) (bench.Chan, error) {
+	return readInput(ctx, cfg, fn, false)
+}
+
+// inputPerCommit reads the executions of a file like input, but with one execution per benchmark and commit (see bench.FromCSVPerCommit)
+func inputPerCommit(ctx context.Context, cfg *config, fn string) (bench.Chan, error) {
+	return readInput(ctx, cfg, fn, true)
+}
+
+func readInput(ctx context.Context, cfg *config, fn string, perCommit bool) (bench.Chan, error) {
+	if strings.HasPrefix(fn, baselinePrefix) {
+		s, err := store.Open(cfg.storeDir)
+		if err != nil {
+			return nil, err
+		}
+		e, err := s.Find(strings.TrimPrefix(fn, baselinePrefix))
+		if err != nil {
+			return nil, err
+		}
+		return s.Chan(ctx, e)
+	}
+
+	f, err := openInput(fn)
+	if err != nil {
+		return nil, err
+	}
+
+	r := bufio.NewReader(f)
+	if hasMagic(r, bench.ParquetMagic) {
+		return parquetInput(ctx, fn, f, r, cfg.reduction, perCommit)
+	}
+	if hasMagic(r, bench.BinaryMagic) {
+		read := bench.FromBinaryWithReduction
+		if perCommit {
+			read = bench.FromBinaryPerCommit
+		}
+		c, err := read(ctx, r, cfg.reduction)
+		if err != nil {
+			return nil, fmt.Errorf("could not read binary file '%s': %v", fn, err)
+		}
+		return c, nil
+	}
+
+	read := bench.FromCSVWithFormat
+	if perCommit {
+		read = bench.FromCSVPerCommitWithFormat
+	}
+	c, err := read(ctx, r, cfg.reduction, cfg.csvFormat)
+	if err != nil {
+		return nil, fmt.Errorf("could not read from CSV for file '%s': %v", fn, err)
+	}
+	return c, nil
+}
+
+// hasMagic returns whether r starts with magic
+func hasMagic(r *bufio.Reader, magic string) bool {
+	m, _ := r.Peek(len(magic))
+	return string(m) == magic
+}
+
+// parquetInput reads the executions of the Parquet file fn, which requires random access, i.e., an uncompressed file is read directly and otherwise the decompressed data r of f (or stdin) is read into memory
+func parquetInput(ctx context.Context, fn string, f io.Closer, r io.Reader, reduction bench.Reduction, perCommit bool) (bench.Chan, error) {
+	var ra io.ReaderAt
+	var size int64
+	if fn != stdinFile {
+		raw, err := os.Open(fn)
+		if err != nil {
+			return nil, fmt.Errorf("could not open file '%s'", fn)
+		}
+		magic := make([]byte, len(bench.ParquetMagic))
+		_, err = raw.ReadAt(magic, 0)
+		fi, serr := raw.Stat()
+		if err == nil && serr == nil && string(magic) == bench.ParquetMagic {
+			f.Close()
+			ra, size = raw, fi.Size()
+		} else {
+			// compressed
+			raw.Close()
+		}
+	}
+	if ra == nil {
+		data, err := ioutil.ReadAll(r)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("could not read Parquet file '%s': %v", fn, err)
+		}
+		ra, size = bytes.NewReader(data), int64(len(data))
+	}
+
+	read := bench.FromParquetWithReduction
+	if perCommit {
+		read = bench.FromParquetPerCommit
+	}
+	c, err := read(ctx, ra, size, reduction)
+	if err != nil {
+		return nil, fmt.Errorf("could not read Parquet file '%s': %v", fn, err)
+	}
+	return c, nil
+}
+
+// readCSVFormat returns the CSV format of the delimiter, which is a single character or 'tab', and the columns file (see bench.ParseCSVColumns), if any
+func readCSVFormat(delim, columnsFile string) (bench.CSVFormat, error) {
+	var f bench.CSVFormat
+	if columnsFile != "" {
+		cf, err := os.Open(columnsFile)
+		if err != nil {
+			return f, err
+		}
+		defer cf.Close()
+		f, err = bench.ParseCSVColumns(cf)
+		if err != nil {
+			return f, fmt.Errorf("columns file '%s': %w", columnsFile, err)
+		}
+	}
+
+	if delim == "tab" {
+		delim = "\t"
+	}
+	rs := []rune(delim)
+	if len(rs) != 1 || rs[0] == '"' || rs[0] == '\r' || rs[0] == '\n' {
+		return f, fmt.Errorf("invalid delimiter '%s', expected a single character other than '\"' or a line break", delim)
+	}
+	f.Delimiter = rs[0]
+	return f, nil
+}
+
+// stdinFile is the file argument for reading from stdin
+const stdinFile = "-"
+
+// openInput opens the file fn, or stdin if fn is stdinFile, and decompresses it if it is compressed (see bench.Decompress)
+func openInput(fn string) (io.ReadCloser, error) {
+	var f io.ReadCloser = os.Stdin
+	if fn != stdinFile {
+		var err error
+		f, err = os.Open(fn)
+		if err != nil {
+			return nil, fmt.Errorf("could not open file '%s'", fn)
+		}
+	}
+
+	r, _, err := bench.Decompress(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("could not decompress file '%s': %v", fn, err)
+	}
+	return decompressedFile{
+		ReadCloser: r,
+		f:          f,
+	}, nil
+}
+
+// decompressedFile closes both the decompressor and the file
+type decompressedFile struct {
+	io.ReadCloser
+	f io.Closer
+}
+
+func (d decompressedFile) Close() error {
+	err := d.ReadCloser.Close()
+	ferr := d.f.Close()
+	if err != nil {
+		return err
+	}
+	return ferr
+}
+
+// inputExts are the extensions of input files in directories, optionally followed by a compression extension (inputCompressionExts)
+var (
+	inputExts            = []string{".csv", ".pab", ".parquet"}
+	inputCompressionExts = []string{"", ".gz", ".zst", ".xz", ".bz2"}
+)
+
+// trimInputExt returns the name without its input extension (see inputExts) and whether name has one
+func trimInputExt(name string) (string, bool) {
+	for _, ext := range inputExts {
+		for _, cext := range inputCompressionExts {
+			if strings.HasSuffix(name, ext+cext) {
+				return strings.TrimSuffix(name, ext+cext), true
+			}
+		}
+	}
+	return name, false
+}
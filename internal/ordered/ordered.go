@@ -0,0 +1,67 @@
+package ordered
+
+import "context"
+
+// Executor executes jobs concurrently and emits their results in submission order.
+// At most maxInFlight jobs are executing or waiting for their result to be emitted at the same time,
+// which bounds the number of executions held in memory.
+// If ctx is done, no further jobs are accepted and results are no longer emitted.
+type Executor struct {
+	ctx      context.Context
+	inFlight chan struct{}
+	futures  chan chan func()
+}
+
+// New creates an executor, which calls done after all results have been emitted (or discarded because ctx is done) and Close was called
+func New(ctx context.Context, maxInFlight int, done func()) *Executor {
+	if maxInFlight < 1 {
+		maxInFlight = 1
+	}
+
+	o := &Executor{
+		ctx:      ctx,
+		inFlight: make(chan struct{}, maxInFlight),
+		futures:  make(chan chan func(), maxInFlight),
+	}
+
+	go func() {
+		defer done()
+		for f := range o.futures {
+			emit := <-f
+			if ctx.Err() == nil {
+				emit()
+			}
+			<-o.inFlight
+		}
+	}()
+
+	return o
+}
+
+// Submit executes job concurrently and blocks while maxInFlight jobs are executing or waiting to be emitted.
+// job returns a function that emits its result, which is called in submission order.
+// emit functions must not block if ctx is done.
+// Submit returns false if ctx is done, in which case job is not executed.
+func (o *Executor) Submit(job func() (emit func())) bool {
+	// select chooses randomly if a slot is free and ctx is done
+	if o.ctx.Err() != nil {
+		return false
+	}
+	select {
+	case o.inFlight <- struct{}{}:
+	case <-o.ctx.Done():
+		return false
+	}
+	f := make(chan func(), 1)
+	// futures has capacity maxInFlight, hence this never blocks while holding an inFlight slot
+	o.futures <- f
+	go func() {
+		f <- job()
+	}()
+	return true
+}
+
+// Close signals that no more jobs are submitted
+func (o *Executor) Close() {
+	close(o.futures)
+}
@@ -0,0 +1,138 @@
+package ordered_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/chrstphlbr/pa/internal/ordered"
+)
+
+// waitDone fails the test if done is not closed within a few seconds
+func waitDone(t *testing.T, done <-chan struct{}) {
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Executor not done")
+	}
+}
+
+func TestExecutorOrder(t *testing.T) {
+	done := make(chan struct{})
+	o := ordered.New(context.Background(), 4, func() { close(done) })
+
+	const jobs = 20
+	var emitted []int
+	for i := 0; i < jobs; i++ {
+		i := i
+		ok := o.Submit(func() func() {
+			// later jobs finish earlier
+			time.Sleep(time.Duration(jobs-i) * time.Millisecond)
+			return func() {
+				emitted = append(emitted, i)
+			}
+		})
+		if !ok {
+			t.Fatalf("Could not submit job %d", i)
+		}
+	}
+	o.Close()
+	waitDone(t, done)
+
+	if len(emitted) != jobs {
+		t.Fatalf("Expected %d results, was %d", jobs, len(emitted))
+	}
+	for i, e := range emitted {
+		if e != i {
+			t.Fatalf("Expected result %d at %d, was %d", i, i, e)
+		}
+	}
+}
+
+func TestExecutorMaxInFlight(t *testing.T) {
+	const maxInFlight = 3
+	done := make(chan struct{})
+	o := ordered.New(context.Background(), maxInFlight, func() { close(done) })
+
+	release := make(chan struct{})
+	var running, maxRunning, submitted int32
+	go func() {
+		defer o.Close()
+		for i := 0; i < 10; i++ {
+			o.Submit(func() func() {
+				r := atomic.AddInt32(&running, 1)
+				for {
+					m := atomic.LoadInt32(&maxRunning)
+					if r <= m || atomic.CompareAndSwapInt32(&maxRunning, m, r) {
+						break
+					}
+				}
+				<-release
+				return func() {
+					atomic.AddInt32(&running, -1)
+				}
+			})
+			atomic.AddInt32(&submitted, 1)
+		}
+	}()
+
+	// Submit blocks while maxInFlight jobs wait for their results to be emitted
+	time.Sleep(50 * time.Millisecond)
+	if s := atomic.LoadInt32(&submitted); s != maxInFlight {
+		t.Fatalf("Expected %d submitted jobs, was %d", maxInFlight, s)
+	}
+
+	close(release)
+	waitDone(t, done)
+
+	if m := atomic.LoadInt32(&maxRunning); m > maxInFlight {
+		t.Fatalf("Expected at most %d jobs in flight, was %d", maxInFlight, m)
+	}
+	if s := atomic.LoadInt32(&submitted); s != 10 {
+		t.Fatalf("Expected 10 submitted jobs, was %d", s)
+	}
+}
+
+func TestExecutorCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	o := ordered.New(ctx, 2, func() { close(done) })
+
+	release := make(chan struct{})
+	var emitted int32
+	var wg sync.WaitGroup
+	wg.Add(1)
+	ok := o.Submit(func() func() {
+		defer wg.Done()
+		<-release
+		return func() {
+			atomic.AddInt32(&emitted, 1)
+		}
+	})
+	if !ok {
+		t.Fatalf("Could not submit job")
+	}
+
+	cancel()
+	var executed bool
+	if o.Submit(func() func() {
+		executed = true
+		return func() {}
+	}) {
+		t.Fatalf("Expected Submit to fail after cancellation")
+	}
+
+	close(release)
+	wg.Wait()
+	o.Close()
+	waitDone(t, done)
+
+	if executed {
+		t.Fatalf("Expected job submitted after cancellation not to be executed")
+	}
+	if e := atomic.LoadInt32(&emitted); e != 0 {
+		t.Fatalf("Expected no results emitted after cancellation, was %d", e)
+	}
+}
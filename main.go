@@ -4,16 +4,23 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/chrstphlbr/pa/pkg/bootstrap"
 
 	"github.com/chrstphlbr/pa/pkg/bench"
 
+	"github.com/chrstphlbr/pa/pkg/changepoint"
+
+	"github.com/chrstphlbr/pa/pkg/permutation"
+
 	"github.com/chrstphlbr/pa/pkg/stat"
 )
 
@@ -25,6 +32,16 @@ func (c cmd) String() string {
 		return "CI"
 	case 1:
 		return "Detection"
+	case 2:
+		return "Series"
+	case 3:
+		return "Changepoints"
+	case 4:
+		return "StoreAdd"
+	case 5:
+		return "StoreList"
+	case 6:
+		return "Convert"
 	}
 	return "INVALID_COMMAND"
 }
@@ -32,43 +49,233 @@ func (c cmd) String() string {
 const (
 	cmdCI cmd = iota
 	cmdDet
+	cmdSeries
+	cmdChangepoints
+	cmdStoreAdd
+	cmdStoreList
+	cmdConvert
 )
 
+// subcommands are selected by the first argument, whereas otherwise ci or det is selected by the number of files
+var subcommands = map[string]cmd{
+	"series":       cmdSeries,
+	"changepoints": cmdChangepoints,
+	"convert":      cmdConvert,
+}
+
+// storeCommands are selected by the argument after 'store'
+var storeCommands = map[string]cmd{
+	"add":  cmdStoreAdd,
+	"list": cmdStoreList,
+}
+
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "Usage:\n")
+	fmt.Fprintf(out, "  pa [flags] file             CIs of a single version\n")
+	fmt.Fprintf(out, "  pa [flags] file1 file2      performance changes between two versions (-m for multiple files per version); a file can also be a stored snapshot '@baseline:<commit>'\n")
+	fmt.Fprintf(out, "  pa series [flags] file|group...\n")
+	fmt.Fprintf(out, "                              CIs and changes across the commits of a file (column 'commit', in the order of the rows) or across multiple versions in argument order, where a group is a directory or a file (or see -manifest)\n")
+	fmt.Fprintf(out, "  pa changepoints [flags] file|group...\n")
+	fmt.Fprintf(out, "                              change points across the commits of a file (column 'commit') or across groups (as for series)\n")
+	fmt.Fprintf(out, "  pa store add [flags] file...\n")
+	fmt.Fprintf(out, "                              store the executions of the files per project and commit (columns 'project' and 'commit') in the store (-store)\n")
+	fmt.Fprintf(out, "  pa store list [flags]       list the snapshots of the store (-store)\n")
+	fmt.Fprintf(out, "  pa convert [flags] file out convert the executions of a file to the compact binary format, which is read like CSV files\n")
+	fmt.Fprintf(out, "Files are CSV, Parquet, or binary (see convert) and can be compressed (gzip, zstd, xz, or bzip2), and '-' reads one file from stdin.\n")
+	fmt.Fprintf(out, "Flags:\n")
+	flag.PrintDefaults()
+}
+
 type statisticFunc struct {
 	Name string
 	Func stat.StatisticFunc
+	// Streaming defines whether Func is the mean, which the bootstrap simulations compute without storing the resampled values (see bootstrap.ResamplingPlan)
+	Streaming bool
+}
+
+// benchmarkFilter keeps the benchmarks matching all Include and none of the Exclude expressions (see bench.ParseFilter)
+type benchmarkFilter struct {
+	Include []string
+	Exclude []string
+	Filter  bench.Filter
 }
 
-const defaultRoundingPrecision = 5
+// filterFlag collects the values of a repeatable flag
+type filterFlag []string
 
-func parseArgs() (c cmd, sim int, sigLevs []float64, statFunc statisticFunc, f1, f2 []string, invocationSamples int, transformer1, transformer2 *bench.NamedExecutionTransformer, outputMetric bool, printMem bool) {
+func (f *filterFlag) String() string {
+	return fmt.Sprint(*f)
+}
+
+func (f *filterFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// config is the configuration of a run as parsed from the command line (see parseArgs)
+type config struct {
+	cmd    cmd
+	groups []seriesGroup
+	// f1 and f2 are the files of the first and second version, or the input and output file of convert
+	f1, f2               []string
+	sims                 bootstrap.Simulations
+	sigLevels            []float64
+	statFunc             statisticFunc
+	plan                 bootstrap.ResamplingPlan
+	transformer1         *bench.NamedExecutionTransformer
+	transformer2         *bench.NamedExecutionTransformer
+	filter               benchmarkFilter
+	aggParam             string
+	suite                bool
+	weights              *bench.Weights
+	correction           stat.Correction
+	test                 *hypothesisTest
+	perm                 permutationTest
+	rank                 bool
+	cpd                  changepointDetection
+	output               outputFormat
+	outputMetric         bool
+	printMem             bool
+	concurrentBenchmarks int
+	reduction            bench.Reduction
+	csvFormat            bench.CSVFormat
+	storeDir             string
+	timeout              time.Duration
+	benchmarkTimeout     time.Duration
+	// reports collects the reports of the transformers, which are printed after all results
+	reports *transformerReports
+}
+
+func parseArgs(args []string) *config {
+	cfg := &config{}
 	sfStr := flag.String("st", "mean", "The statistic to be calculated")
-	s := flag.Int("bs", 10000, "Number of bootstrap simulations")
+	s := flag.Int("bs", 10000, "Number of bootstrap simulations (maximum number if -bst > 0)")
+	bst := flag.Float64("bst", 0, "Relative tolerance of the CI bounds for adaptive bootstrap simulations (0 for a fixed number of simulations as defined by -bs)")
+	bsb := flag.Int("bsb", bootstrap.DefaultBatchSize, "Number of bootstrap simulations per batch if -bst > 0")
 	sls := flag.String("sl", "0.01", "Significance levels (multiple seperated by ',')")
 	is := flag.Int("is", 0, "Number of invocation samples (0 for mean across all invocations, -1 for all, > 0 for number of samples)")
+	levels := flag.String("levels", "", "Resampling strategy per level of the execution hierarchy, e.g., 'instance:resample,trial:resample,fork:resample,iteration:keep,invocation:mean'. Levels instance, trial, fork, and iteration are either 'resample' or 'keep'; invocation is one of 'mean', 'all', 'keep', or 'sample<n>' and overrides -is. Unspecified levels are resampled")
 	m := flag.Int("m", 1, "Number of multiple files belongig to one group (test or control); e.g., 3 means 6 files in total, 3 test and 3 control")
 	om := flag.Bool("os", false, "Include statistic (e.g., mean) in output")
 	rm := flag.Bool("mem", false, "Print runtime memory to Stdout")
-	transformers := flag.String("tra", "id:id", "The transformer(s) applied to the execution file(s), in the form of 'transformer1:transformer2', where transformer1 is applied to the first (control) group and transformer2 is applied to the second (test) group. Transformers can be one of 'id' (identity, no transformation) or 'f0.0' ('f' for factor followed by a user-specified float64 value)")
-	flag.Parse()
+	ir := flag.String("ir", "none", "Invocation reduction while reading the file(s) to bound memory, one of 'none', 'hist' (merge invocations of an iteration into at most -ic histogram buckets), or 'reservoir' (keep a random sample of at most -ic invocations per iteration); with a reduction, the file(s) are read twice to report the reduction in the header")
+	ic := flag.Int("ic", 1000, "Maximum number of histogram buckets ('hist') or invocations ('reservoir') per iteration if -ir is set")
+	cb := flag.Int("cb", runtime.NumCPU(), "Number of benchmarks computed concurrently, which bounds the number of executions held in memory")
+	to := flag.Duration("timeout", 0, "Overall timeout (e.g., '10m'), after which the computation is aborted and only the results computed so far are reported (0 for no timeout)")
+	bt := flag.Duration("bt", 0, "Timeout per benchmark (e.g., '30s'), after which the benchmark's computation is aborted and reported as error (0 for no timeout)")
+	transformers := flag.String("tra", "id:id", "The transformer chain(s) applied to the execution file(s), in the form of 'chain1:chain2', where chain1 is applied to the first (control) group and chain2 is applied to the second (test) group. A chain consists of one or more transformers separated by '|' (e.g., 'warmup(5)|f0.001|winsorize(0.99)'), which are applied from left to right. A transformer is of the form 'name(arg1,arg2)', 'name', or, for a single argument, '<name><arg>' (e.g., 'f0.001'). Available transformers:\n"+bench.DefaultTransformers.Usage())
+	agg := flag.String("agg", "", "Performance parameter (e.g., 'size') to aggregate over when comparing two versions: benchmarks only differing in this parameter are combined into a CI of the geometric mean of their ratios (bootstrapped jointly), reported together with the ratios per parameter value (trend) as comment rows at the end")
+	suiteAgg := flag.Bool("suite", false, "Report the overall performance change of the suite when comparing two versions: a CI of the geometric mean of all benchmarks' ratios (bootstrapped jointly), as comment rows at the end")
+	weightFile := flag.String("weights", "", "File with benchmark weights for the suite aggregate (implies -suite), with one '<filter expression>;<weight>' per line (same expression syntax as -include); a benchmark gets the weight of the first matching line, unmatched benchmarks have weight 1")
+	mc := flag.String("mc", "none", "Multiple-comparison correction when comparing two versions, one of 'none', 'bonferroni', 'holm' (both control the family-wise error rate), or 'bh' (Benjamini-Hochberg, controls the false discovery rate). Adds the columns bootstrap p-value (of the ratio being 1), adjusted p-value, and the ratio CI at the adjusted significance level; results are written once all benchmarks are computed")
+	pv := flag.String("pv", "", "Hypothesis test when comparing two versions: adds a column with the two-sided bootstrap p-value of the null hypothesis that the ratio equals -null, computed from the simulated ratios ('paired'); also used by -mc (default 'paired')")
+	null := flag.Float64("null", 1, "Ratio of the null hypothesis of -pv and -mc (e.g., 1.05 to test for a change other than 5%)")
+	permutations := flag.Int("perm", 0, "Number of permutations of a hierarchical permutation test when comparing two versions, which adds the columns p-value and number of permutations (0 disables the test); all assignments are evaluated if there are at most this many")
+	permLevel := flag.String("permlevel", "auto", "Level whose units are permuted between the versions by -perm, one of 'instance', 'fork', or 'auto' (instances if both versions have at least two, forks otherwise)")
+	permOnly := flag.Bool("permonly", false, "Only perform the permutation test (-perm) instead of computing bootstrap CIs")
+	rankTest := flag.Bool("rank", false, "Mann-Whitney U (Wilcoxon rank-sum) test of the iterations' mean invocations when comparing two versions, which adds the columns p-value, Vargha-Delaney A12, and Cliff's delta")
+	out := flag.String("o", "csv", "Output format, either 'csv' or 'benchstat' (two versions only), which formats the results in benchstat's table layout: the median and maximum relative deviation per version, the percentage change of the ratio (or '~' if the ratio's CI at the first significance level contains 1), and the p-value (see -pv) and number of iterations")
+	manifest := flag.String("manifest", "", "Manifest of the versions of series or changepoints, with one '<label>;<file>' per line in version order (consecutive lines with the same label form one version), instead of group arguments")
+	cpPerm := flag.Int("cpperm", changepoint.DefaultPermutations, "Number of permutations of the significance test of every change point of changepoints")
+	sd := flag.String("store", ".pa-store", "Directory of the result store, which holds the snapshots added by 'store add' and referenced by '@baseline:<commit>' (or '@baseline:<project>/<commit>') instead of a file")
+	cpMin := flag.Int("cpmin", changepoint.DefaultMinSize, "Minimum number of commits between change points of changepoints")
+	delim := flag.String("delim", ";", "Delimiter of CSV input files, a single character or 'tab'; fields containing the delimiter are quoted")
+	columnsFile := flag.String("columns", "", "File mapping the columns of CSV input files to their names in the header, with one '<column>;<name>[;<default>]' per line, where column is one of "+strings.Join(bench.CSVColumns, ", ")+", name is its name in the header (empty if the files do not have it), and default is its value if the header has no column name (e.g., 'instance;;local')")
+	var include, exclude filterFlag
+	flag.Var(&include, "include", "Only analyze the benchmarks matching the expression, which is either a predicate over a performance parameter (e.g., 'size=1024' or 'threads>=4', with operators =, !=, <, <=, >, and >=) or otherwise a regular expression over the benchmark name; repeatable, benchmarks must match all expressions")
+	flag.Var(&exclude, "exclude", "Do not analyze the benchmarks matching the expression (same syntax as -include); repeatable, benchmarks matching any expression are excluded")
+	flag.Usage = usage
 
-	args := flag.Args()
+	sub, isSub := cmdCI, false
+	if len(args) > 0 && args[0] == "store" {
+		if len(args) > 1 {
+			sub, isSub = storeCommands[args[1]]
+		}
+		if !isSub {
+			fmt.Fprint(os.Stdout, "Expected a store command ('add' or 'list')\n\n")
+			flag.Usage()
+			os.Exit(1)
+		}
+		args = args[2:]
+	} else if len(args) > 0 {
+		sub, isSub = subcommands[args[0]]
+		if isSub {
+			args = args[1:]
+		}
+	}
+	flag.CommandLine.Parse(args)
+	cfg.storeDir = *sd
+
+	args = flag.Args()
 	largs := len(args)
-	if largs == 1 {
+	if sub == cmdStoreAdd || sub == cmdStoreList {
+		cfg.cmd = sub
+		if sub == cmdStoreAdd && largs < 1 {
+			fmt.Fprint(os.Stdout, "Expected at least one file argument\n\n")
+			flag.Usage()
+			os.Exit(1)
+		}
+		if sub == cmdStoreList && largs > 0 {
+			fmt.Fprint(os.Stdout, "Expected no arguments\n\n")
+			flag.Usage()
+			os.Exit(1)
+		}
+		cfg.f1 = args
+	} else if sub == cmdConvert {
+		cfg.cmd = sub
+		if largs != 2 {
+			fmt.Fprintf(os.Stdout, "Expected an input and an output file, got %d arguments\n\n", largs)
+			flag.Usage()
+			os.Exit(1)
+		}
+		cfg.f1 = []string{args[0]}
+		cfg.f2 = []string{args[1]}
+	} else if isSub {
+		cfg.cmd = sub
+		var err error
+		if *manifest != "" {
+			if largs > 0 {
+				fmt.Fprint(os.Stdout, "Expected either a manifest (-manifest) or group arguments\n\n")
+				flag.Usage()
+				os.Exit(1)
+			}
+			cfg.groups, err = readManifest(*manifest)
+		} else if largs == 1 && !isDir(args[0]) {
+			// single file -> commits from its column 'commit'
+			cfg.f1 = []string{args[0]}
+		} else {
+			cfg.groups, err = seriesGroupsFromArgs(args)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stdout, "Could not read groups: %v\n\n", err)
+			flag.Usage()
+			os.Exit(1)
+		}
+		if cfg.f1 == nil && len(cfg.groups) < 2 {
+			fmt.Fprintf(os.Stdout, "Expected at least two groups, got %d\n\n", len(cfg.groups))
+			flag.Usage()
+			os.Exit(1)
+		}
+	} else if *manifest != "" {
+		fmt.Fprint(os.Stdout, "A manifest (-manifest) requires the series or changepoints command\n\n")
+		flag.Usage()
+		os.Exit(1)
+	} else if largs == 1 {
 		// single file -> only report confidence intervals
-		c = cmdCI
-		f1 = []string{args[0]}
+		cfg.cmd = cmdCI
+		cfg.f1 = []string{args[0]}
 	} else if largs == 2 {
 		// two files -> report performance changes
-		c = cmdDet
-		f1 = []string{args[0]}
-		f2 = []string{args[1]}
+		cfg.cmd = cmdDet
+		cfg.f1 = []string{args[0]}
+		cfg.f2 = []string{args[1]}
 	} else if largs < 1 {
 		fmt.Fprintf(os.Stdout, "Expected at least one file argument\n\n")
 		flag.Usage()
 		os.Exit(1)
 	} else {
-		c = cmdDet
+		cfg.cmd = cmdDet
 		// multiple files for test and control group
 		if largs / *m != 2 {
 			fmt.Fprintf(os.Stdout, "-m must be half of number of arguments\n\n")
@@ -76,16 +283,33 @@ func parseArgs() (c cmd, sim int, sigLevs []float64, statFunc statisticFunc, f1,
 			os.Exit(1)
 		}
 
-		f1 = []string{}
+		cfg.f1 = []string{}
 		for i := 0; i < *m; i++ {
-			f1 = append(f1, args[i])
+			cfg.f1 = append(cfg.f1, args[i])
 		}
-		f2 = []string{}
+		cfg.f2 = []string{}
 		for i := *m; i < *m*2; i++ {
-			f2 = append(f2, args[i])
+			cfg.f2 = append(cfg.f2, args[i])
 		}
 	}
 
+	// stdin can only be read once
+	stdins := 0
+	files := append(append([]string{}, cfg.f1...), cfg.f2...)
+	for _, g := range cfg.groups {
+		files = append(files, g.Files...)
+	}
+	for _, f := range files {
+		if f == stdinFile {
+			stdins++
+		}
+	}
+	if stdins > 1 {
+		fmt.Fprintf(os.Stdout, "Only one input file can be stdin ('%s')\n\n", stdinFile)
+		flag.Usage()
+		os.Exit(1)
+	}
+
 	// significance levels
 	slsSplitted := strings.Split(*sls, ",")
 	slsFloat := make([]float64, 0, len(slsSplitted))
@@ -105,13 +329,72 @@ func parseArgs() (c cmd, sim int, sigLevs []float64, statFunc statisticFunc, f1,
 		os.Exit(1)
 	}
 
+	var sampler bootstrap.InvocationSampling
+	if *is == 0 {
+		sampler = bootstrap.MeanInvocations
+	} else if *is == -1 {
+		sampler = bootstrap.AllInvocations
+	} else {
+		sampler = bootstrap.SampleInvocations(*is)
+	}
+
+	var err error
+	cfg.plan, err = bootstrap.ParseResamplingPlan(*levels, sampler)
+	if err != nil {
+		fmt.Fprintf(os.Stdout, "Could not parse resampling levels: %v\n\n", err)
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if *cb < 1 {
+		fmt.Fprint(os.Stdout, "Invalid number of concurrent benchmarks, must be >= 1\n\n")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	switch *ir {
+	case "none":
+		cfg.reduction = bench.Reduction{Mode: bench.NoReduction}
+	case "hist":
+		cfg.reduction = bench.Reduction{Mode: bench.HistogramReduction, Cap: *ic}
+	case "reservoir":
+		cfg.reduction = bench.Reduction{Mode: bench.ReservoirReduction, Cap: *ic}
+	default:
+		fmt.Fprintf(os.Stdout, "Unknown invocation reduction '%s'\n\n", *ir)
+		flag.Usage()
+		os.Exit(1)
+	}
+	if cfg.reduction.Mode != bench.NoReduction && *ic < 1 {
+		fmt.Fprint(os.Stdout, "Invalid invocation reduction cap, must be >= 1\n\n")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if *to < 0 || *bt < 0 {
+		fmt.Fprint(os.Stdout, "Invalid timeout, must be >= 0\n\n")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if *bst < 0 {
+		fmt.Fprint(os.Stdout, "Invalid bootstrap tolerance, must be >= 0\n\n")
+		flag.Usage()
+		os.Exit(1)
+	}
+	if *bst > 0 {
+		cfg.sims = bootstrap.AdaptiveSimulations(*s, *bsb, *bst)
+	} else {
+		cfg.sims = bootstrap.FixedSimulations(*s)
+	}
+
 	statisticFunction := *sfStr
 	var sf statisticFunc
 	switch statisticFunction {
 	case "mean":
 		sf = statisticFunc{
-			Name: "Mean",
-			Func: stat.Mean,
+			Name:      "Mean",
+			Func:      stat.Mean,
+			Streaming: true,
 		}
 	case "cov":
 		sf = statisticFunc{
@@ -128,143 +411,440 @@ func parseArgs() (c cmd, sim int, sigLevs []float64, statFunc statisticFunc, f1,
 		flag.Usage()
 		os.Exit(1)
 	}
+	cfg.plan.Streaming = sf.Streaming
 
-	transformer1, transformer2, err := parseTransformers(*transformers)
+	cfg.reports = &transformerReports{}
+	cfg.transformer1, cfg.transformer2, err = parseTransformers(*transformers, cfg.reports.reporter(1), cfg.reports.reporter(2))
 	if err != nil {
 		fmt.Fprintf(os.Stdout, "Could not parse transformers: %v\n", err)
 		flag.Usage()
 		os.Exit(1)
 	}
 
-	return c, *s, slsFloat, sf, f1, f2, *is, transformer1, transformer2, *om, *rm
+	if *agg != "" && cfg.cmd != cmdDet {
+		fmt.Fprint(os.Stdout, "Aggregation (-agg) requires two versions to compare\n\n")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	cfg.suite = *suiteAgg || *weightFile != ""
+	if cfg.suite && cfg.cmd != cmdDet {
+		fmt.Fprint(os.Stdout, "Suite aggregation (-suite, -weights) requires two versions to compare\n\n")
+		flag.Usage()
+		os.Exit(1)
+	}
+	if *weightFile != "" {
+		cfg.weights, err = readWeights(*weightFile)
+		if err != nil {
+			fmt.Fprintf(os.Stdout, "Could not read weights: %v\n", err)
+			flag.Usage()
+			os.Exit(1)
+		}
+	}
+
+	cfg.csvFormat, err = readCSVFormat(*delim, *columnsFile)
+	if err != nil {
+		fmt.Fprintf(os.Stdout, "Could not read CSV format: %v\n\n", err)
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	cfg.correction, err = stat.ParseCorrection(*mc)
+	if err != nil {
+		fmt.Fprintf(os.Stdout, "%v\n\n", err)
+		flag.Usage()
+		os.Exit(1)
+	}
+	if cfg.correction != stat.NoCorrection && cfg.cmd != cmdDet {
+		fmt.Fprint(os.Stdout, "Multiple-comparison correction (-mc) requires two versions to compare\n\n")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if *pv != "" || cfg.correction != stat.NoCorrection {
+		if cfg.cmd != cmdDet {
+			fmt.Fprint(os.Stdout, "Hypothesis tests (-pv) require two versions to compare\n\n")
+			flag.Usage()
+			os.Exit(1)
+		}
+		if *null <= 0 {
+			fmt.Fprintf(os.Stdout, "Null ratio (-null) must be positive, was %g\n\n", *null)
+			flag.Usage()
+			os.Exit(1)
+		}
+		method := bootstrap.PairedPValues
+		if *pv != "" {
+			method, err = bootstrap.ParsePValueMethod(*pv)
+			if err != nil {
+				fmt.Fprintf(os.Stdout, "%v\n\n", err)
+				flag.Usage()
+				os.Exit(1)
+			}
+		}
+		cfg.test = &hypothesisTest{
+			method: method,
+			null:   *null,
+		}
+	}
+
+	if *permutations > 0 || *permOnly {
+		if cfg.cmd != cmdDet {
+			fmt.Fprint(os.Stdout, "Permutation tests (-perm) require two versions to compare\n\n")
+			flag.Usage()
+			os.Exit(1)
+		}
+		if *permutations <= 0 {
+			fmt.Fprint(os.Stdout, "Permutation test only (-permonly) requires a number of permutations (-perm)\n\n")
+			flag.Usage()
+			os.Exit(1)
+		}
+		if *permOnly && (*agg != "" || cfg.suite || cfg.test != nil) {
+			fmt.Fprint(os.Stdout, "Permutation test only (-permonly) cannot be combined with -agg, -suite, -weights, -pv, or -mc, which require bootstrap CIs\n\n")
+			flag.Usage()
+			os.Exit(1)
+		}
+		cfg.perm.Permutations = *permutations
+		cfg.perm.Only = *permOnly
+		cfg.perm.Level, err = permutation.ParseLevel(*permLevel)
+		if err != nil {
+			fmt.Fprintf(os.Stdout, "%v\n\n", err)
+			flag.Usage()
+			os.Exit(1)
+		}
+	}
+
+	if *rankTest && cfg.cmd != cmdDet {
+		fmt.Fprint(os.Stdout, "Rank tests (-rank) require two versions to compare\n\n")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	cfg.output, err = parseOutputFormat(*out)
+	if err != nil {
+		fmt.Fprintf(os.Stdout, "%v\n\n", err)
+		flag.Usage()
+		os.Exit(1)
+	}
+	if cfg.output == outputBenchstat {
+		if cfg.cmd != cmdDet {
+			fmt.Fprint(os.Stdout, "Output format benchstat requires two versions to compare\n\n")
+			flag.Usage()
+			os.Exit(1)
+		}
+		if cfg.correction != stat.NoCorrection || cfg.perm.Permutations > 0 || *rankTest {
+			fmt.Fprint(os.Stdout, "Output format benchstat cannot be combined with -mc, -perm, or -rank\n\n")
+			flag.Usage()
+			os.Exit(1)
+		}
+		if cfg.test == nil {
+			cfg.test = &hypothesisTest{
+				method: bootstrap.PairedPValues,
+				null:   *null,
+			}
+		}
+	}
+
+	if cfg.cmd == cmdChangepoints {
+		if *cpPerm < 1 || *cpMin < 1 {
+			fmt.Fprint(os.Stdout, "Invalid change-point detection, permutations (-cpperm) and minimum commits (-cpmin) must be >= 1\n\n")
+			flag.Usage()
+			os.Exit(1)
+		}
+		cfg.cpd = changepointDetection{
+			Permutations: *cpPerm,
+			MinSize:      *cpMin,
+		}
+	}
+
+	cfg.filter, err = parseFilter(include, exclude)
+	if err != nil {
+		fmt.Fprintf(os.Stdout, "Could not parse filters: %v\n", err)
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	cfg.sigLevels = slsFloat
+	cfg.statFunc = sf
+	cfg.aggParam = *agg
+	cfg.rank = *rankTest
+	cfg.outputMetric = *om
+	cfg.printMem = *rm
+	cfg.concurrentBenchmarks = *cb
+	cfg.timeout = *to
+	cfg.benchmarkTimeout = *bt
+	return cfg
 }
 
 func main() {
-	cmd, sim, sigLevels, sf, f1, f2, is, transformer1, transformer2, outputMetric, printMem := parseArgs()
+	cfg := parseArgs(os.Args[1:])
 	maxNrWorkers := runtime.NumCPU()
 
-	var sampler bench.InvocationSampler
-	var samplingType string
-	if is == 0 {
-		sampler = bench.MeanInvocations
-		samplingType = "Mean"
-	} else if is == -1 {
-		sampler = bench.AllInvocations
-		samplingType = "All"
-	} else {
-		sampler = bench.SampleInvocations(is)
-		samplingType = fmt.Sprintf("%d invocations per iteration", is)
+	switch cfg.cmd {
+	case cmdStoreAdd:
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		handleInterrupts(cancel, cancel)
+		storeAdd(ctx, cfg)
+		return
+	case cmdStoreList:
+		storeList(cfg)
+		return
+	case cmdConvert:
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		handleInterrupts(cancel, cancel)
+		convert(ctx, cfg)
+		return
 	}
 
+	// ctx cancels the whole computation, whereas inputCtx only stops reading further benchmarks such that the ones in progress are still reported
+	ctx, cancel := context.WithCancel(context.Background())
+	if cfg.timeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), cfg.timeout)
+	}
+	defer cancel()
+	inputCtx, stopInput := context.WithCancel(ctx)
+	defer stopInput()
+	handleInterrupts(stopInput, cancel)
+
 	var outHeader strings.Builder
 	outHeader.WriteString("#Execute CIs:\n")
-	outHeader.WriteString(fmt.Sprintf("# cmd = %s\n", cmd))
+	outHeader.WriteString(fmt.Sprintf("# cmd = %s\n", cfg.cmd))
 	outHeader.WriteString(fmt.Sprintf("# number of cores = %d\n", maxNrWorkers))
-	outHeader.WriteString(fmt.Sprintf("# bootstrap simulations = %d\n", sim))
-	outHeader.WriteString(fmt.Sprintf("# significance levels = %v\n", sigLevels))
-	outHeader.WriteString(fmt.Sprintf("# statistic = %s\n", sf.Name))
-	outHeader.WriteString(fmt.Sprintf("# include statistic in output = %t\n", outputMetric))
-	outHeader.WriteString(fmt.Sprintf("# invocation sampling = %s\n", samplingType))
-	outHeader.WriteString(fmt.Sprintf("# transformer 1 = %s\n", transformer1.Name))
-	outHeader.WriteString(fmt.Sprintf("# transformer 2 = %s\n", transformer2.Name))
-	outHeader.WriteString(fmt.Sprintf("# files 1 = %s\n", f1))
-	outHeader.WriteString(fmt.Sprintf("# files 2 = %s\n", f2))
+	outHeader.WriteString(fmt.Sprintf("# concurrent benchmarks = %d\n", cfg.concurrentBenchmarks))
+	outHeader.WriteString(fmt.Sprintf("# bootstrap simulations = %d\n", cfg.sims.Max))
+	if cfg.sims.Adaptive() {
+		outHeader.WriteString(fmt.Sprintf("# adaptive bootstrap = tolerance %g, batch size %d\n", cfg.sims.Tolerance, cfg.sims.BatchSize))
+	}
+	if cfg.timeout > 0 {
+		outHeader.WriteString(fmt.Sprintf("# timeout = %v\n", cfg.timeout))
+	}
+	if cfg.benchmarkTimeout > 0 {
+		outHeader.WriteString(fmt.Sprintf("# benchmark timeout = %v\n", cfg.benchmarkTimeout))
+	}
+	outHeader.WriteString(fmt.Sprintf("# significance levels = %v\n", cfg.sigLevels))
+	outHeader.WriteString(fmt.Sprintf("# statistic = %s\n", cfg.statFunc.Name))
+	outHeader.WriteString(fmt.Sprintf("# output format = %s\n", cfg.output))
+	outHeader.WriteString(fmt.Sprintf("# include statistic in output = %t\n", cfg.outputMetric))
+	outHeader.WriteString(fmt.Sprintf("# invocation sampling = %s\n", cfg.plan.Invocations))
+	outHeader.WriteString(fmt.Sprintf("# resampling levels = %s\n", cfg.plan))
+	outHeader.WriteString(fmt.Sprintf("# invocation reduction = %s\n", cfg.reduction))
+	if cfg.reduction.Mode != bench.NoReduction {
+		outHeader.WriteString(reductionHeader(inputCtx, cfg))
+	}
+	outHeader.WriteString(fmt.Sprintf("# transformer 1 = %s\n", cfg.transformer1.Name))
+	outHeader.WriteString(fmt.Sprintf("# transformer 2 = %s\n", cfg.transformer2.Name))
+	if len(cfg.filter.Include) > 0 {
+		outHeader.WriteString(fmt.Sprintf("# include = %q\n", cfg.filter.Include))
+	}
+	if len(cfg.filter.Exclude) > 0 {
+		outHeader.WriteString(fmt.Sprintf("# exclude = %q\n", cfg.filter.Exclude))
+	}
+	if cfg.aggParam != "" {
+		outHeader.WriteString(fmt.Sprintf("# aggregate over = %s\n", cfg.aggParam))
+	}
+	if cfg.suite {
+		outHeader.WriteString(fmt.Sprintf("# suite aggregate = %t\n", cfg.suite))
+	}
+	if cfg.weights != nil {
+		outHeader.WriteString(fmt.Sprintf("# weights = %d expressions\n", cfg.weights.Len()))
+	}
+	if cfg.test != nil {
+		outHeader.WriteString(fmt.Sprintf("# hypothesis test = %s p-value, null ratio %g\n", cfg.test.method, cfg.test.null))
+	}
+	if cfg.perm.Permutations > 0 {
+		outHeader.WriteString(fmt.Sprintf("# permutation test = %d permutations, level %s, only %t\n", cfg.perm.Permutations, cfg.perm.Level, cfg.perm.Only))
+	}
+	if cfg.rank {
+		outHeader.WriteString(fmt.Sprintf("# rank test = %t\n", cfg.rank))
+	}
+	if cfg.correction != stat.NoCorrection {
+		outHeader.WriteString(fmt.Sprintf("# multiple-comparison correction = %s\n", cfg.correction))
+	}
+	if cfg.cmd == cmdChangepoints {
+		outHeader.WriteString(fmt.Sprintf("# change-point detection = e-divisive, %d permutations, minimum %d commits\n", cfg.cpd.Permutations, cfg.cpd.MinSize))
+	}
+	if cfg.csvFormat.Delimiter != ';' || len(cfg.csvFormat.Columns) > 0 || len(cfg.csvFormat.Defaults) > 0 {
+		outHeader.WriteString(fmt.Sprintf("# csv format = delimiter %q, %d mapped columns, %d defaults\n", cfg.csvFormat.Delimiter, len(cfg.csvFormat.Columns), len(cfg.csvFormat.Defaults)))
+	}
+	if strings.Contains(strings.Join(append(cfg.f1, cfg.f2...), " "), baselinePrefix) {
+		outHeader.WriteString(fmt.Sprintf("# store = %s\n", cfg.storeDir))
+	}
+	if cfg.groups != nil {
+		for i, g := range cfg.groups {
+			outHeader.WriteString(fmt.Sprintf("# version %d = %s %s\n", i+1, g.Label, g.Files))
+		}
+	} else {
+		outHeader.WriteString(fmt.Sprintf("# files 1 = %s\n", cfg.f1))
+		outHeader.WriteString(fmt.Sprintf("# files 2 = %s\n", cfg.f2))
+	}
 	fmt.Fprint(os.Stdout, outHeader.String())
 	fmt.Fprintln(os.Stdout, "")
 
-	ciFunc := bootstrap.CIFuncSetup(sim, maxNrWorkers, sf.Func, sigLevels, sampler)
-	ciRatioFunc := bootstrap.CIRatioFuncSetup(sim, maxNrWorkers, sf.Func, sigLevels, sampler)
+	ciFunc := bootstrap.CIFuncWithTimeout(bootstrap.CIFuncSetup(cfg.sims, maxNrWorkers, cfg.statFunc.Func, cfg.sigLevels, cfg.plan), cfg.benchmarkTimeout)
+	ciRatioFunc := bootstrap.CIRatioFuncWithTimeout(bootstrap.CIRatioFuncSetup(cfg.sims, maxNrWorkers, cfg.statFunc.Func, cfg.sigLevels, cfg.plan), cfg.benchmarkTimeout)
 
 	var exec func()
-	switch cmd {
+	switch cfg.cmd {
 	case cmdCI:
 		exec = func() {
-			ci(ciFunc, f1[0], transformer1.ExecutionTransformer, outputMetric, printMem)
+			ci(ctx, inputCtx, cfg, ciFunc)
 		}
 	case cmdDet:
+		var agg *paramAggregation
+		if cfg.aggParam != "" {
+			agg = newParamAggregation(cfg.aggParam, cfg.sigLevels)
+		}
+		var suiteAgg *suiteAggregation
+		if cfg.suite {
+			suiteAgg = newSuiteAggregation(cfg.weights, cfg.sigLevels)
+		}
+		var cc *comparisonCorrection
+		if cfg.correction != stat.NoCorrection {
+			cc = newComparisonCorrection(cfg.correction, cfg.test, cfg.sigLevels)
+		}
+		var table *benchstatTable
+		if cfg.output == outputBenchstat {
+			table = newBenchstatTable(cfg.test)
+		}
+		an := detAnalyses{
+			ciFunc:      ciFunc,
+			ciRatioFunc: ciRatioFunc,
+			rank:        cfg.rank,
+			summary:     cfg.output == outputBenchstat,
+		}
+		if cfg.perm.Permutations > 0 {
+			an.testFunc = permutation.TestFuncSetup(cfg.perm.Permutations, maxNrWorkers, cfg.statFunc.Func, cfg.perm.Level)
+			if cfg.perm.Only {
+				an.ciFunc, an.ciRatioFunc = nil, nil
+			}
+		}
 		exec = func() {
-			det(ciFunc, ciRatioFunc, f1, f2, transformer1.ExecutionTransformer, transformer2.ExecutionTransformer, outputMetric, printMem)
+			det(ctx, inputCtx, cfg, an, agg, suiteAgg, cc, table)
+		}
+	case cmdSeries:
+		exec = func() {
+			series(ctx, inputCtx, cfg, ciFunc, ciRatioFunc)
+		}
+	case cmdChangepoints:
+		exec = func() {
+			changepoints(ctx, inputCtx, cfg, ciRatioFunc)
 		}
 	default:
-		fmt.Fprintf(os.Stdout, "Invalid command '%s' (available: 'ci' and 'det')\n\n", cmd)
+		fmt.Fprintf(os.Stdout, "Invalid command '%s' (available: 'ci', 'det', 'series', and 'changepoints')\n\n", cfg.cmd)
 		flag.Usage()
 		os.Exit(1)
 	}
 
 	start := time.Now()
 	exec()
+	cfg.reports.print(os.Stdout)
+	if err := inputCtx.Err(); err != nil {
+		fmt.Fprintf(os.Stdout, "#Incomplete results: %s\n", incompleteReason(ctx, err))
+	}
 	fmt.Fprintf(os.Stdout, "#Total execution took %v\n", time.Since(start))
 }
 
-func ci(ciFunc bootstrap.CIFunc, fp string, transformer bench.ExecutionTransformer, outputMetric, printMem bool) {
-	f, err := os.Open(fp)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Could not open file '%s'\n", fp)
-		return
-	}
+// handleInterrupts stops reading further benchmarks on the first interrupt (SIGINT), such that the results computed so far are still reported, and aborts on the second one
+func handleInterrupts(stopInput, abort context.CancelFunc) {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt)
+	go func() {
+		<-sigs
+		fmt.Fprintln(os.Stderr, "Interrupted: finishing benchmarks in progress (interrupt again to abort)")
+		stopInput()
+		<-sigs
+		fmt.Fprintln(os.Stderr, "Interrupted: aborting")
+		abort()
+		signal.Stop(sigs)
+	}()
+}
 
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+func incompleteReason(ctx context.Context, inputErr error) string {
+	switch ctx.Err() {
+	case context.DeadlineExceeded:
+		return "timeout"
+	case context.Canceled:
+		return "aborted"
+	}
+	if inputErr == context.DeadlineExceeded {
+		return "timeout"
+	}
+	return "interrupted"
+}
 
-	c, err := bench.FromCSV(ctx, f)
+func ci(ctx, inputCtx context.Context, cfg *config, ciFunc bootstrap.CIFunc) {
+	c, err := input(inputCtx, cfg, cfg.f1[0])
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "%v\n", err)
 		return
 	}
-	if transformer != nil {
-		c = bench.TransformChan(transformer, c)
+	if cfg.filter.Filter != nil {
+		c = bench.FilterChanContext(inputCtx, cfg.filter.Filter, c)
+	}
+	if cfg.transformer1.ExecutionTransformer != nil {
+		c = bench.TransformChanContext(inputCtx, cfg.transformer1.ExecutionTransformer, c)
 	}
 
-	rc := bootstrap.CIs(c, ciFunc)
+	rc := bootstrap.ConcurrentCIs(ctx, c, ciFunc, cfg.concurrentBenchmarks)
 
-	printMemStats(printMem)
+	printMemStats(cfg.printMem)
 
 	for res := range rc {
 		if res.Err != nil {
-			fmt.Fprintf(os.Stderr, "Error while retrieving CI result: %v", res.Err)
+			fmt.Fprintf(os.Stderr, "Error while retrieving CI result: %v\n", res.Err)
 			continue
 		}
 
 		b := res.Benchmark
 		cis := res.CIs
 		for _, ci := range cis {
-			if outputMetric {
+			var line string
+			if cfg.outputMetric {
 				// include statistic/metric in output
-				fmt.Fprintf(os.Stdout, "%s;%s;%s;%e;%e;%e;%.2f\n", b.Name, b.FunctionParams, b.PerfParams, ci.Metric, ci.Lower, ci.Upper, ci.Level)
+				line = fmt.Sprintf("%s;%s;%s;%e;%e;%e;%.2f", b.Name, b.FunctionParams, b.PerfParams, ci.Metric, ci.Lower, ci.Upper, ci.Level)
 			} else {
 				// only print CIs
-				fmt.Fprintf(os.Stdout, "%s;%s;%s;%e;%e;%.2f\n", b.Name, b.FunctionParams, b.PerfParams, ci.Lower, ci.Upper, ci.Level)
+				line = fmt.Sprintf("%s;%s;%s;%e;%e;%.2f", b.Name, b.FunctionParams, b.PerfParams, ci.Lower, ci.Upper, ci.Level)
 			}
+			if cfg.sims.Adaptive() {
+				// include number of performed bootstrap simulations
+				line = fmt.Sprintf("%s;%d", line, ci.Simulations)
+			}
+			fmt.Fprintln(os.Stdout, line)
 		}
-		printMemStats(printMem)
+		printMemStats(cfg.printMem)
 	}
 }
 
-func det(ciFunc bootstrap.CIFunc, ciRatioFunc bootstrap.CIRatioFunc, fp1, fp2 []string, transformer1, transformer2 bench.ExecutionTransformer, outputMetric, printMem bool) {
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	c1, err := mergedInput(ctx, fp1)
+func det(ctx, inputCtx context.Context, cfg *config, an detAnalyses, agg *paramAggregation, suite *suiteAggregation, cc *comparisonCorrection, table *benchstatTable) {
+	c1, err := mergedInput(inputCtx, cfg, cfg.f1)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "%v\n", err)
 		return
 	}
-	if transformer1 != nil {
-		c1 = bench.TransformChan(transformer1, c1)
+	if cfg.filter.Filter != nil {
+		c1 = bench.FilterChanContext(inputCtx, cfg.filter.Filter, c1)
+	}
+	if cfg.transformer1.ExecutionTransformer != nil {
+		c1 = bench.TransformChanContext(inputCtx, cfg.transformer1.ExecutionTransformer, c1)
 	}
 
-	c2, err := mergedInput(ctx, fp2)
+	c2, err := mergedInput(inputCtx, cfg, cfg.f2)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "%v\n", err)
 		return
 	}
-	if transformer2 != nil {
-		c2 = bench.TransformChan(transformer2, c2)
+	if cfg.filter.Filter != nil {
+		c2 = bench.FilterChanContext(inputCtx, cfg.filter.Filter, c2)
+	}
+	if cfg.transformer2.ExecutionTransformer != nil {
+		c2 = bench.TransformChanContext(inputCtx, cfg.transformer2.ExecutionTransformer, c2)
 	}
 
-	rc := bootstrap.CIRatios(c1, c2, ciFunc, ciRatioFunc)
+	rc := detResults(ctx, c1, c2, an, cfg.concurrentBenchmarks)
 
-	printMemStats(printMem)
+	printMemStats(cfg.printMem)
 
 	for res := range rc {
 		if res.Err != nil {
@@ -272,89 +852,267 @@ func det(ciFunc bootstrap.CIFunc, ciRatioFunc bootstrap.CIRatioFunc, fp1, fp2 []
 			continue
 		}
 
-		b := res.Benchmark
-		cirs := res.CIRatios
-		for _, cir := range cirs {
-			if outputMetric {
-				// include statistic/metric in output
-				fmt.Fprintf(
-					os.Stdout,
-					"%s;%s;%s;%e;%e;%e;%.2f;%e;%e;%e;%.2f;%e;%e;%e;%.2f\n",
-					b.Name, b.FunctionParams, b.PerfParams,
-					cir.CIA.Metric, cir.CIA.Lower, cir.CIA.Upper, cir.CIA.Level,
-					cir.CIB.Metric, cir.CIB.Lower, cir.CIB.Upper, cir.CIB.Level,
-					cir.CIRatio.Metric, cir.CIRatio.Lower, cir.CIRatio.Upper, cir.CIRatio.Level,
-				)
-			} else {
-				// only print CIs
-				fmt.Fprintf(
-					os.Stdout,
-					"%s;%s;%s;%e;%e;%.2f;%e;%e;%.2f;%e;%e;%.2f\n",
-					b.Name, b.FunctionParams, b.PerfParams,
-					cir.CIA.Lower, cir.CIA.Upper, cir.CIA.Level,
-					cir.CIB.Lower, cir.CIB.Upper, cir.CIB.Level,
-					cir.CIRatio.Lower, cir.CIRatio.Upper, cir.CIRatio.Level,
-				)
+		var lines []string
+		if an.ciFunc == nil {
+			// permutation test only
+			lines = append(lines, permutationLine(res.Benchmark, res.Test))
+		}
+		for _, cir := range res.CIRatios {
+			line := ratioLine(res.Benchmark, cir, cfg.outputMetric, cfg.sims.Adaptive())
+			if an.testFunc != nil {
+				line = fmt.Sprintf("%s;%s", line, permutationColumns(res.Test))
 			}
+			lines = append(lines, line)
 		}
-		printMemStats(printMem)
+		if an.rank {
+			for i, line := range lines {
+				lines[i] = fmt.Sprintf("%s;%s", line, rankColumns(res.Rank))
+			}
+		}
+
+		if table != nil {
+			table.add(res)
+		} else if cc != nil {
+			cc.add(res.CIRatioResult, lines)
+		} else if cfg.test != nil {
+			p := cfg.test.pValue(res.CIRatioResult)
+			for _, line := range lines {
+				fmt.Fprintf(os.Stdout, "%s;%e\n", line, p)
+			}
+		} else {
+			for _, line := range lines {
+				fmt.Fprintln(os.Stdout, line)
+			}
+		}
+		if agg != nil {
+			agg.add(res.CIRatioResult)
+		}
+		if suite != nil {
+			suite.add(res.CIRatioResult)
+		}
+		printMemStats(cfg.printMem)
+	}
+
+	if table != nil {
+		table.print(os.Stdout)
+	}
+	if cc != nil {
+		cc.print(os.Stdout)
+	}
+	if agg != nil {
+		agg.print(os.Stdout)
+	}
+	if suite != nil {
+		suite.print(os.Stdout)
 	}
 }
 
-func mergedInput(ctx context.Context, fs []string) (bench.Chan, error) {
+// ratioLine formats a CSV row of the two-version analysis
+func ratioLine(b *bench.B, cir stat.CIRatio, outputMetric, outputSimulations bool) string {
+	var line string
+	if outputMetric {
+		// include statistic/metric in output
+		line = fmt.Sprintf(
+			"%s;%s;%s;%e;%e;%e;%.2f;%e;%e;%e;%.2f;%e;%e;%e;%.2f",
+			b.Name, b.FunctionParams, b.PerfParams,
+			cir.CIA.Metric, cir.CIA.Lower, cir.CIA.Upper, cir.CIA.Level,
+			cir.CIB.Metric, cir.CIB.Lower, cir.CIB.Upper, cir.CIB.Level,
+			cir.CIRatio.Metric, cir.CIRatio.Lower, cir.CIRatio.Upper, cir.CIRatio.Level,
+		)
+	} else {
+		// only print CIs
+		line = fmt.Sprintf(
+			"%s;%s;%s;%e;%e;%.2f;%e;%e;%.2f;%e;%e;%.2f",
+			b.Name, b.FunctionParams, b.PerfParams,
+			cir.CIA.Lower, cir.CIA.Upper, cir.CIA.Level,
+			cir.CIB.Lower, cir.CIB.Upper, cir.CIB.Level,
+			cir.CIRatio.Lower, cir.CIRatio.Upper, cir.CIRatio.Level,
+		)
+	}
+	if outputSimulations {
+		// include number of performed bootstrap simulations (of whichever version has a result)
+		line = fmt.Sprintf("%s;%d", line, simulations(cir))
+	}
+	return line
+}
+
+// simulations returns the number of bootstrap simulations of a CIRatio, which might only have a result for one version
+func simulations(cir stat.CIRatio) int {
+	if cir.CIRatio.Simulations != 0 {
+		return cir.CIRatio.Simulations
+	} else if cir.CIA.Simulations != 0 {
+		return cir.CIA.Simulations
+	}
+	return cir.CIB.Simulations
+}
+
+func mergedInput(ctx context.Context, cfg *config, fs []string) (bench.Chan, error) {
 	var chans []bench.Chan
 	for _, fn := range fs {
-		f, err := os.Open(fn)
-		if err != nil {
-			return nil, fmt.Errorf("could not open file1 '%s'", fn)
-		}
-
-		c1, err := bench.FromCSV(ctx, f)
+		c1, err := input(ctx, cfg, fn)
 		if err != nil {
-			return nil, fmt.Errorf("could not read from CSV for file '%s': %v", fn, err)
+			return nil, err
 		}
 		chans = append(chans, c1)
 	}
-	return bench.MergeChans(chans...), nil
+	return bench.MergeChansContext(ctx, chans...), nil
 }
 
-func parseTransformers(str string) (transformer1, transformer2 *bench.NamedExecutionTransformer, err error) {
-	colonIdx := strings.Index(str, ":")
-	if colonIdx == -1 {
-		transformer1, err = parseTransformer(str)
-		return transformer1, nil, err
-	} else {
-		transformer1, err1 := parseTransformer(str[:colonIdx])
-		if err1 != nil {
-			return nil, nil, fmt.Errorf("error transformer1: %w", err1)
-		}
-		transformer2, err2 := parseTransformer(str[colonIdx+1:])
-		if err2 != nil {
-			return nil, nil, fmt.Errorf("error transformer2: %w", err2)
+// parseTransformers parses the transformer chains of both groups (see bench.TransformerRegistry.Parse), separated by ':'.
+// If only one chain is given, the second group is not transformed.
+func parseTransformers(str string, report1, report2 bench.Reporter) (transformer1, transformer2 *bench.NamedExecutionTransformer, err error) {
+	chains := strings.Split(str, ":")
+	switch len(chains) {
+	case 1:
+		chains = append(chains, "id")
+	case 2:
+	default:
+		return nil, nil, fmt.Errorf("expected at most two transformer chains separated by ':', got %d", len(chains))
+	}
+
+	transformer1, err = bench.DefaultTransformers.Parse(chains[0], report1)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error transformer1: %w", err)
+	}
+	transformer2, err = bench.DefaultTransformers.Parse(chains[1], report2)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error transformer2: %w", err)
+	}
+	return transformer1, transformer2, nil
+}
+
+func parseFilter(include, exclude []string) (benchmarkFilter, error) {
+	f := benchmarkFilter{
+		Include: include,
+		Exclude: exclude,
+	}
+	if len(include) == 0 && len(exclude) == 0 {
+		return f, nil
+	}
+
+	parse := func(exprs []string) ([]bench.Filter, error) {
+		fs := make([]bench.Filter, 0, len(exprs))
+		for _, expr := range exprs {
+			bf, err := bench.ParseFilter(expr)
+			if err != nil {
+				return nil, err
+			}
+			fs = append(fs, bf)
 		}
-		return transformer1, transformer2, nil
+		return fs, nil
+	}
+
+	in, err := parse(include)
+	if err != nil {
+		return f, err
+	}
+	ex, err := parse(exclude)
+	if err != nil {
+		return f, err
 	}
+	f.Filter = bench.IncludeExcludeFilter(in, ex)
+	return f, nil
 }
 
-func parseTransformer(str string) (*bench.NamedExecutionTransformer, error) {
-	var t bench.NamedExecutionTransformer
+func readWeights(path string) (*bench.Weights, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return bench.ParseWeights(f, 1)
+}
+
+// inputFiles returns the files that the command reads and whether it reads them per commit
+func inputFiles(cfg *config) ([]string, bool) {
+	var fs []string
 	switch {
-	case str == "id":
-		t = bench.NamedExecutionTransformer{
-			ExecutionTransformer: nil,
-			Name:                 "ID",
+	case cfg.groups != nil:
+		for _, g := range cfg.groups {
+			fs = append(fs, g.Files...)
 		}
-	case strings.HasPrefix(str, "f"):
-		f, err := strconv.ParseFloat(str[1:], 64)
+		return fs, false
+	case cfg.cmd == cmdSeries, cfg.cmd == cmdChangepoints:
+		return cfg.f1[:1], true
+	case cfg.cmd == cmdCI:
+		return cfg.f1[:1], false
+	}
+	fs = append(fs, cfg.f1...)
+	return append(fs, cfg.f2...), false
+}
+
+// readReductionStats reads the input files with the invocation reduction and sums up how much data was reduced, such that the header reports it before the files are read again for the analysis.
+// Stdin cannot be read twice and therefore returns an error.
+func readReductionStats(ctx context.Context, cfg *config) (bench.ReductionStats, error) {
+	var stats bench.ReductionStats
+	fs, perCommit := inputFiles(cfg)
+	for _, fn := range fs {
+		if fn == stdinFile {
+			return stats, fmt.Errorf("stdin is only read once")
+		}
+		c, err := readInput(ctx, cfg, fn, perCommit)
 		if err != nil {
-			return nil, fmt.Errorf("could not parse factor transformer: %w", err)
+			return stats, err
 		}
-		t = bench.NamedExecutionTransformer{
-			ExecutionTransformer: bench.ConstantFactorExecutionTransformerFunc(f, defaultRoundingPrecision),
-			Name:                 fmt.Sprintf("ConstantFactor(%g)", f),
+		if cfg.filter.Filter != nil {
+			c = bench.FilterChanContext(ctx, cfg.filter.Filter, c)
+		}
+		for ev := range c {
+			switch ev.Type {
+			case bench.ExecNext:
+				stats.Add(ev.Exec.Reduction)
+			case bench.ExecError:
+				if err == nil {
+					err = ev.Err
+				}
+			}
+		}
+		if err != nil {
+			return stats, err
+		}
+	}
+	return stats, ctx.Err()
+}
+
+// reductionHeader returns the comment row of the header reporting how much data the invocation reduction reduced (see readReductionStats)
+func reductionHeader(ctx context.Context, cfg *config) string {
+	s, err := readReductionStats(ctx, cfg)
+	if err != nil {
+		return fmt.Sprintf("# invocation reduction: unknown (%v)\n", err)
+	}
+	return fmt.Sprintf("# invocation reduction: buckets %d -> %d (%.2f%%), invocations %d -> %d (%.2f%%)\n", s.Buckets, s.ReducedBuckets, percentage(s.ReducedBuckets, s.Buckets), s.Invocations, s.ReducedInvocations, percentage(s.ReducedInvocations, s.Invocations))
+}
+
+func percentage(part, total int) float64 {
+	if total == 0 {
+		return 100
+	}
+	return float64(part) / float64(total) * 100
+}
+
+// transformerReports collects the reports of the transformers of both groups
+type transformerReports struct {
+	l sync.Mutex
+	// lines per group (1 and 2)
+	lines [2][]string
+}
+
+func (tr *transformerReports) reporter(group int) bench.Reporter {
+	return func(b *bench.B, report fmt.Stringer) {
+		tr.l.Lock()
+		defer tr.l.Unlock()
+		tr.lines[group-1] = append(tr.lines[group-1], fmt.Sprintf("# transformer %d: %s: %s", group, b, report))
+	}
+}
+
+func (tr *transformerReports) print(w io.Writer) {
+	tr.l.Lock()
+	defer tr.l.Unlock()
+	for _, lines := range tr.lines {
+		for _, l := range lines {
+			fmt.Fprintln(w, l)
 		}
 	}
-	return &t, nil
 }
 
 func printMemStats(print bool) {
@@ -0,0 +1,102 @@
+package bench
+
+import "context"
+
+// AlignedExecutions holds the executions of the same benchmark of multiple versions, where Execs[i] is the execution of version i or nil if version i does not have the benchmark.
+// If a version sent an error, Err is set and Execs is nil.
+type AlignedExecutions struct {
+	Execs []*Execution
+	Err   error
+}
+
+// Benchmark returns the benchmark of the aligned executions
+func (ae AlignedExecutions) Benchmark() *B {
+	for _, e := range ae.Execs {
+		if e != nil {
+			return e.Benchmark
+		}
+	}
+	return nil
+}
+
+func AlignChans(cs ...Chan) <-chan AlignedExecutions {
+	return AlignChansContext(context.Background(), cs...)
+}
+
+// AlignChansContext aligns the executions of the same benchmarks of cs (one per version), which are all ordered by benchmark, i.e., it generalizes PairChansContext to multiple versions.
+// If a channel is truncated (i.e., closed after its start but before its end), the remaining benchmarks cannot be aligned and aligning stops.
+// If ctx is done, aligning stops and the returned channel is closed.
+func AlignChansContext(ctx context.Context, cs ...Chan) <-chan AlignedExecutions {
+	out := make(chan AlignedExecutions)
+
+	go func() {
+		defer close(out)
+		emit := func(ae AlignedExecutions) bool {
+			select {
+			case out <- ae:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		heads := make([]*Execution, len(cs))
+		done := make([]bool, len(cs))
+		started := make([]bool, len(cs))
+		for {
+			// fill the heads of all channels that are not done
+			for i, c := range cs {
+				for heads[i] == nil && !done[i] {
+					ev, ok := receive(ctx, c)
+					if ctx.Err() != nil {
+						return
+					}
+					if !ok {
+						if started[i] {
+							// truncated
+							return
+						}
+						done[i] = true
+						break
+					}
+					switch ev.Type {
+					case ExecStart:
+						started[i] = true
+					case ExecEnd:
+						done[i] = true
+					case ExecError:
+						if !emit(AlignedExecutions{Err: ev.Err}) {
+							return
+						}
+					case ExecNext:
+						heads[i] = ev.Exec
+					}
+				}
+			}
+
+			// the smallest benchmark among the heads
+			var min *B
+			for _, h := range heads {
+				if h != nil && (min == nil || h.Benchmark.Compare(min) < 0) {
+					min = h.Benchmark
+				}
+			}
+			if min == nil {
+				return
+			}
+
+			execs := make([]*Execution, len(cs))
+			for i, h := range heads {
+				if h != nil && h.Benchmark.Compare(min) == 0 {
+					execs[i] = h
+					heads[i] = nil
+				}
+			}
+			if !emit(AlignedExecutions{Execs: execs}) {
+				return
+			}
+		}
+	}()
+
+	return out
+}
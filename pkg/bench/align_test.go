@@ -0,0 +1,59 @@
+package bench_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/chrstphlbr/pa/pkg/bench"
+)
+
+func alignedString(ae bench.AlignedExecutions) string {
+	if ae.Err != nil {
+		return "err"
+	}
+	names := make([]string, len(ae.Execs))
+	for i, e := range ae.Execs {
+		names[i] = "-"
+		if e != nil {
+			names[i] = e.Benchmark.Name
+		}
+	}
+	return strings.Join(names, "/")
+}
+
+func TestAlignChans(t *testing.T) {
+	for _, c := range []struct {
+		cs       []bench.Chan
+		expected string
+	}{
+		{
+			[]bench.Chan{pairInput(true, true, "a", "b"), pairInput(true, true, "a", "b"), pairInput(true, true, "a", "b")},
+			"a/a/a,b/b/b",
+		},
+		{
+			[]bench.Chan{pairInput(true, true, "a", "c"), pairInput(true, true, "b", "c"), pairInput(true, true, "a", "d")},
+			"a/-/a,-/b/-,c/c/-,-/-/d",
+		},
+		{
+			[]bench.Chan{pairInput(true, true, "a", errors.New("e"), "b"), pairInput(true, true, "a", "b"), pairInput(true, true)},
+			"a/a/-,err,b/b/-",
+		},
+		// truncated second channel
+		{
+			[]bench.Chan{pairInput(true, true, "a", "b"), pairInput(true, false, "a"), pairInput(true, true, "a", "b")},
+			"a/a/a",
+		},
+	} {
+		var as []string
+		for ae := range bench.AlignChans(c.cs...) {
+			as = append(as, alignedString(ae))
+			if ae.Err == nil && ae.Benchmark() == nil {
+				t.Fatalf("Expected benchmark of aligned executions")
+			}
+		}
+		if s := strings.Join(as, ","); s != c.expected {
+			t.Fatalf("Unexpected aligned executions: was %s, expected %s", s, c.expected)
+		}
+	}
+}
@@ -50,6 +50,13 @@ func (b *B) Copy() *B {
 	return nb
 }
 
+// WithoutPerfParam returns a copy of the benchmark without the performance parameter param
+func (b *B) WithoutPerfParam(param string) *B {
+	nb := b.Copy()
+	nb.PerfParams.remove(param)
+	return nb
+}
+
 func (b *B) Equals(other *B) bool {
 	return b.Compare(other) == 0
 }
@@ -176,6 +183,22 @@ func (pp *PerfParams) Add(param, value string) {
 	}
 }
 
+func (pp *PerfParams) remove(param string) {
+	pp.l.Lock()
+	defer pp.l.Unlock()
+	if _, ok := pp.params[param]; !ok {
+		return
+	}
+	delete(pp.params, param)
+	keys := pp.keys[:0]
+	for _, k := range pp.keys {
+		if k != param {
+			keys = append(keys, k)
+		}
+	}
+	pp.keys = keys
+}
+
 func (pp *PerfParams) Get() map[string]string {
 	pp.l.RLock()
 	defer pp.l.RUnlock()
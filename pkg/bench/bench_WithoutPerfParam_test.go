@@ -0,0 +1,27 @@
+package bench_test
+
+import (
+	"testing"
+)
+
+func TestBWithoutPerfParam(t *testing.T) {
+	b := benchWithParams("bench1", "size=1", "mode=fast")
+	nb := b.WithoutPerfParam("size")
+
+	if s := nb.String(); s != "bench1(){mode=fast}" {
+		t.Fatalf("Unexpected benchmark without parameter: %s", s)
+	}
+	// original is unaltered
+	if s := b.String(); s != "bench1(){mode=fast,size=1}" {
+		t.Fatalf("Original benchmark altered: %s", s)
+	}
+
+	other := benchWithParams("bench1", "size=2", "mode=fast")
+	if !nb.Equals(other.WithoutPerfParam("size")) {
+		t.Fatalf("Expected benchmarks without the differing parameter to be equal")
+	}
+
+	if !b.WithoutPerfParam("threads").Equals(b) {
+		t.Fatalf("Expected benchmark without a non-existing parameter to be equal")
+	}
+}
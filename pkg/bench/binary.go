@@ -0,0 +1,520 @@
+package bench
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// The binary format consists of the magic bytes BinaryMagic, the format version (one byte), and a sequence of records, each starting with a tag byte:
+//   - name: a benchmark name (string), added to the name dictionary
+//   - params: the number of performance parameters (uvarint) followed by their keys and values (strings), added to the parameter dictionary
+//   - instance: an instance name (string), added to the instance dictionary
+//   - project: a project (string), added to the project dictionary
+//   - commit: a commit (string), added to the commit dictionary
+//   - execution: the benchmark's name and parameters, the project, and the commit (dictionary indices as uvarint), the number of function parameters (uvarint) followed by them (strings), and the number of rows (uvarint) followed by the rows
+//   - end: the end of the executions, which is missing if the data is truncated
+//
+// A row is an invocation with its instance (dictionary index as uvarint), trial, fork, and iteration (varint), count (uvarint), and value (float64, little endian).
+// A string is its length in bytes (uvarint) followed by the bytes.
+// Dictionary indices refer to the order in which the entries were added, starting at 0.
+const (
+	BinaryMagic   = "PAEX"
+	BinaryVersion = 2
+)
+
+const (
+	tagEnd byte = iota
+	tagName
+	tagParams
+	tagInstance
+	tagExecution
+	tagProject
+	tagCommit
+)
+
+// limits of lengths read from the binary format, which bound the memory allocated for malformed inputs
+const (
+	maxBinaryString = 1 << 20
+	maxBinaryParams = 1 << 16
+	// maxBinaryCount is the maximum invocation count of a row, which is converted to int and summed up per iteration
+	maxBinaryCount = math.MaxInt32
+)
+
+// WriteBinary writes the executions of c in the binary format (see BinaryMagic) to w and returns the number of written executions.
+// It fails if c sends an error or is truncated, in which case the written data has no end record.
+func WriteBinary(ctx context.Context, w io.Writer, c Chan) (int, error) {
+	bw := &binaryWriter{
+		w:         bufio.NewWriter(w),
+		names:     make(map[string]uint64),
+		params:    make(map[string]uint64),
+		instances: make(map[string]uint64),
+		projects:  make(map[string]uint64),
+		commits:   make(map[string]uint64),
+	}
+	bw.w.WriteString(BinaryMagic)
+	bw.w.WriteByte(BinaryVersion)
+
+	var n int
+	for {
+		ev, ok := receive(ctx, c)
+		if ctx.Err() != nil {
+			return n, ctx.Err()
+		}
+		if !ok {
+			return n, fmt.Errorf("input ended before all executions were read")
+		}
+
+		switch ev.Type {
+		case ExecStart:
+			continue
+		case ExecError:
+			return n, ev.Err
+		case ExecEnd:
+			bw.w.WriteByte(tagEnd)
+			return n, bw.w.Flush()
+		}
+
+		err := bw.execution(ev.Exec)
+		if err != nil {
+			return n, err
+		}
+		n++
+	}
+}
+
+type binaryWriter struct {
+	w         *bufio.Writer
+	buf       [binary.MaxVarintLen64]byte
+	names     map[string]uint64
+	params    map[string]uint64
+	instances map[string]uint64
+	projects  map[string]uint64
+	commits   map[string]uint64
+}
+
+func (bw *binaryWriter) uvarint(v uint64) {
+	n := binary.PutUvarint(bw.buf[:], v)
+	bw.w.Write(bw.buf[:n])
+}
+
+func (bw *binaryWriter) varint(v int64) {
+	n := binary.PutVarint(bw.buf[:], v)
+	bw.w.Write(bw.buf[:n])
+}
+
+func (bw *binaryWriter) string(s string) {
+	bw.uvarint(uint64(len(s)))
+	bw.w.WriteString(s)
+}
+
+// index returns the dictionary index of key, where add writes the record of a new entry
+func (bw *binaryWriter) index(dict map[string]uint64, key string, add func()) uint64 {
+	i, ok := dict[key]
+	if !ok {
+		i = uint64(len(dict))
+		dict[key] = i
+		add()
+	}
+	return i
+}
+
+func (bw *binaryWriter) execution(e *Execution) error {
+	b := e.Benchmark
+	nameIdx := bw.index(bw.names, b.Name, func() {
+		bw.w.WriteByte(tagName)
+		bw.string(b.Name)
+	})
+
+	keys := b.PerfParams.Keys()
+	values := b.PerfParams.Get()
+	var pk strings.Builder
+	for _, k := range keys {
+		pk.WriteString(k)
+		pk.WriteByte(0)
+		pk.WriteString(values[k])
+		pk.WriteByte(0)
+	}
+	paramsIdx := bw.index(bw.params, pk.String(), func() {
+		bw.w.WriteByte(tagParams)
+		bw.uvarint(uint64(len(keys)))
+		for _, k := range keys {
+			bw.string(k)
+			bw.string(values[k])
+		}
+	})
+
+	projectIdx := bw.index(bw.projects, e.Project, func() {
+		bw.w.WriteByte(tagProject)
+		bw.string(e.Project)
+	})
+	commitIdx := bw.index(bw.commits, e.Commit, func() {
+		bw.w.WriteByte(tagCommit)
+		bw.string(e.Commit)
+	})
+
+	// instances of the rows
+	instanceIdxs := make([]uint64, len(e.InstanceIDs))
+	var rows uint64
+	for i, iid := range e.InstanceIDs {
+		instanceIdxs[i] = bw.index(bw.instances, iid, func() {
+			bw.w.WriteByte(tagInstance)
+			bw.string(iid)
+		})
+		ins := e.Instances[iid]
+		for _, t := range ins.Trials {
+			for _, f := range t.Forks {
+				for _, it := range f.Iterations {
+					rows += uint64(len(it.Invocations))
+				}
+			}
+		}
+	}
+
+	bw.w.WriteByte(tagExecution)
+	bw.uvarint(nameIdx)
+	bw.uvarint(paramsIdx)
+	bw.uvarint(projectIdx)
+	bw.uvarint(commitIdx)
+	bw.uvarint(uint64(len(b.FunctionParams)))
+	for _, fp := range b.FunctionParams {
+		bw.string(fp)
+	}
+	bw.uvarint(rows)
+
+	var value [8]byte
+	for i, iid := range e.InstanceIDs {
+		ins := e.Instances[iid]
+		for _, tid := range ins.TrialIDs {
+			t := ins.Trials[tid]
+			for _, fid := range t.ForkIDs {
+				f := t.Forks[fid]
+				for _, itid := range f.IterationIDs {
+					for _, inv := range f.Iterations[itid].Invocations {
+						bw.uvarint(instanceIdxs[i])
+						bw.varint(int64(tid))
+						bw.varint(int64(fid))
+						bw.varint(int64(itid))
+						bw.uvarint(uint64(inv.Count))
+						binary.LittleEndian.PutUint64(value[:], math.Float64bits(inv.Value))
+						_, err := bw.w.Write(value[:])
+						if err != nil {
+							return err
+						}
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func FromBinary(ctx context.Context, r io.Reader) (Chan, error) {
+	return FromBinaryWithReduction(ctx, r, Reduction{})
+}
+
+// FromBinaryWithReduction reads executions in the binary format (see BinaryMagic), like FromCSVWithReduction, i.e., consecutive executions of the same benchmark (e.g., of different commits) are merged.
+// A malformed or truncated input is sent as error, after which the channel is closed without ExecEnd.
+func FromBinaryWithReduction(ctx context.Context, r io.Reader, reduction Reduction) (Chan, error) {
+	return fromBinary(ctx, r, reduction, false)
+}
+
+// FromBinaryPerCommit reads executions in the binary format like FromCSVPerCommit, i.e., with one execution per benchmark and commit
+func FromBinaryPerCommit(ctx context.Context, r io.Reader, reduction Reduction) (Chan, error) {
+	return fromBinary(ctx, r, reduction, true)
+}
+
+func fromBinary(ctx context.Context, r io.Reader, reduction Reduction, perCommit bool) (Chan, error) {
+	br := &binaryReader{
+		r:         bufio.NewReader(r),
+		reduction: reduction,
+		perCommit: perCommit,
+	}
+
+	head := make([]byte, len(BinaryMagic)+1)
+	_, err := io.ReadFull(br.r, head)
+	if err != nil || string(head[:len(BinaryMagic)]) != BinaryMagic {
+		return nil, fmt.Errorf("input is not in the binary format")
+	}
+	if v := head[len(BinaryMagic)]; v != BinaryVersion {
+		return nil, fmt.Errorf("unsupported binary format version %d (supported: %d)", v, BinaryVersion)
+	}
+
+	c := make(Chan)
+	go func() {
+		defer close(c)
+
+		ev := ExecutionValue{Type: ExecStart}
+		for {
+			if !send(ctx, c, ev) {
+				return
+			}
+			if ev.Type == ExecEnd || ev.Type == ExecError {
+				return
+			}
+			ev = br.next()
+		}
+	}()
+
+	return c, nil
+}
+
+type binaryReader struct {
+	r         *bufio.Reader
+	reduction Reduction
+	perCommit bool
+	names     []string
+	params    [][]string
+	instances []string
+	projects  []string
+	commits   []string
+	// pending is the execution record that was read but belongs to the next execution
+	pending *binaryExecution
+	ended   bool
+}
+
+// binaryExecution is the head of an execution record, which is followed by its rows
+type binaryExecution struct {
+	benchmark       *B
+	project, commit string
+	rows            uint64
+}
+
+// next reads the execution records up to the next execution or the end and merges consecutive records of the same benchmark (and commit if perCommit)
+func (br *binaryReader) next() ExecutionValue {
+	if br.ended {
+		return ExecutionValue{Type: ExecEnd}
+	}
+
+	var eb executionBuilder
+	var project, commit string
+	for {
+		be := br.pending
+		br.pending = nil
+		if be == nil {
+			var err error
+			be, err = br.record()
+			if err != nil {
+				return br.error(err)
+			}
+		}
+
+		if be == nil {
+			// end record
+			br.ended = true
+			if eb == nil {
+				return ExecutionValue{Type: ExecEnd}
+			}
+			return executionValue(eb, project, commit)
+		}
+
+		if eb == nil {
+			eb = newExecutionBuilder(be.benchmark, br.reduction)
+			if br.perCommit {
+				project, commit = be.project, be.commit
+			}
+		} else if !be.benchmark.Equals(eb.benchmark()) || (br.perCommit && (be.project != project || be.commit != commit)) {
+			br.pending = be
+			return executionValue(eb, project, commit)
+		}
+
+		err := br.rows(eb, be)
+		if err != nil {
+			return br.error(err)
+		}
+	}
+}
+
+// record reads the records up to the next execution record and returns its head, or nil at the end record
+func (br *binaryReader) record() (*binaryExecution, error) {
+	for {
+		tag, err := br.r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+
+		switch tag {
+		case tagEnd:
+			return nil, nil
+		case tagName:
+			err = br.entry(&br.names)
+		case tagParams:
+			var n uint64
+			n, err = binary.ReadUvarint(br.r)
+			if err != nil {
+				return nil, err
+			}
+			if n > maxBinaryParams {
+				return nil, fmt.Errorf("number of parameters %d exceeds limit of %d", n, maxBinaryParams)
+			}
+			kvs := make([]string, 0, 2*n)
+			for i := uint64(0); i < 2*n; i++ {
+				s, err := br.string()
+				if err != nil {
+					return nil, err
+				}
+				kvs = append(kvs, s)
+			}
+			br.params = append(br.params, kvs)
+		case tagInstance:
+			err = br.entry(&br.instances)
+		case tagProject:
+			err = br.entry(&br.projects)
+		case tagCommit:
+			err = br.entry(&br.commits)
+		case tagExecution:
+			return br.execution()
+		default:
+			return nil, fmt.Errorf("invalid record tag %d", tag)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// entry reads a string and adds it to the dictionary
+func (br *binaryReader) entry(dict *[]string) error {
+	s, err := br.string()
+	if err != nil {
+		return err
+	}
+	*dict = append(*dict, s)
+	return nil
+}
+
+func (br *binaryReader) error(err error) ExecutionValue {
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return ExecutionValue{
+		Type: ExecError,
+		Err:  fmt.Errorf("could not read binary executions: %w", err),
+	}
+}
+
+func (br *binaryReader) string() (string, error) {
+	n, err := binary.ReadUvarint(br.r)
+	if err != nil {
+		return "", err
+	}
+	if n > maxBinaryString {
+		return "", fmt.Errorf("string length %d exceeds limit of %d bytes", n, maxBinaryString)
+	}
+	b := make([]byte, n)
+	_, err = io.ReadFull(br.r, b)
+	return string(b), err
+}
+
+// dictionary returns the entry idx of a dictionary of length l
+func dictionary(r io.ByteReader, l int, name string) (int, error) {
+	idx, err := binary.ReadUvarint(r)
+	if err != nil {
+		return 0, err
+	}
+	if idx >= uint64(l) {
+		return 0, fmt.Errorf("%s index %d out of range (%d entries)", name, idx, l)
+	}
+	return int(idx), nil
+}
+
+// execution reads the head of an execution record
+func (br *binaryReader) execution() (*binaryExecution, error) {
+	var idxs [4]int
+	for i, d := range []struct {
+		l    int
+		name string
+	}{
+		{len(br.names), "name"},
+		{len(br.params), "params"},
+		{len(br.projects), "project"},
+		{len(br.commits), "commit"},
+	} {
+		var err error
+		idxs[i], err = dictionary(br.r, d.l, d.name)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	b := New(br.names[idxs[0]])
+	kvs := br.params[idxs[1]]
+	for i := 0; i < len(kvs); i += 2 {
+		b.PerfParams.Add(kvs[i], kvs[i+1])
+	}
+	nfps, err := binary.ReadUvarint(br.r)
+	if err != nil {
+		return nil, err
+	}
+	if nfps > maxBinaryParams {
+		return nil, fmt.Errorf("number of function parameters %d exceeds limit of %d", nfps, maxBinaryParams)
+	}
+	for i := uint64(0); i < nfps; i++ {
+		fp, err := br.string()
+		if err != nil {
+			return nil, err
+		}
+		b.FunctionParams = append(b.FunctionParams, fp)
+	}
+
+	// the rows are read one at a time (see rows), i.e., their number does not bound any allocation
+	rows, err := binary.ReadUvarint(br.r)
+	if err != nil {
+		return nil, err
+	}
+	return &binaryExecution{
+		benchmark: b,
+		project:   br.projects[idxs[2]],
+		commit:    br.commits[idxs[3]],
+		rows:      rows,
+	}, nil
+}
+
+// rows reads the rows of the execution record be into eb
+func (br *binaryReader) rows(eb executionBuilder, be *binaryExecution) error {
+	var value [8]byte
+	for i := uint64(0); i < be.rows; i++ {
+		insIdx, err := dictionary(br.r, len(br.instances), "instance")
+		if err != nil {
+			return err
+		}
+		var ids [3]int64
+		for j := range ids {
+			ids[j], err = binary.ReadVarint(br.r)
+			if err != nil {
+				return err
+			}
+		}
+		count, err := binary.ReadUvarint(br.r)
+		if err != nil {
+			return err
+		}
+		if count > maxBinaryCount {
+			return fmt.Errorf("invocation count %d exceeds limit of %d", count, maxBinaryCount)
+		}
+		_, err = io.ReadFull(br.r, value[:])
+		if err != nil {
+			return err
+		}
+
+		err = eb.add(InvocationsFlat{
+			Benchmark: eb.benchmark(),
+			Instance:  br.instances[insIdx],
+			Trial:     int(ids[0]),
+			Fork:      int(ids[1]),
+			Iteration: int(ids[2]),
+			Invocations: Invocations{
+				Count: int(count),
+				Value: math.Float64frombits(binary.LittleEndian.Uint64(value[:])),
+			},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
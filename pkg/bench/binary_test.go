@@ -0,0 +1,268 @@
+package bench_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/chrstphlbr/pa/pkg/bench"
+)
+
+// binaryCSV returns CSV with multiple benchmarks, performance parameters, instances, and hierarchy levels
+func binaryCSV(t *testing.T) string {
+	w, sb := header(t)
+	for _, b := range []struct {
+		name   string
+		params string
+	}{
+		{"b1", ""},
+		{"b1", "size=1"},
+		{"b1", "size=2"},
+		{"b2", "size=1"},
+		{"b2", "size=1,threads=4"},
+	} {
+		for ins := 1; ins <= 2; ins++ {
+			for tr := 1; tr <= 2; tr++ {
+				for f := 1; f <= 3; f++ {
+					for it := 1; it <= 4; it++ {
+						for inv := 1; inv <= 3; inv++ {
+							v := float64(ins*1000+tr*100+f*10+it) + 1/float64(inv)
+							err := w.Write([]string{"p", "c", b.name, b.params, fmt.Sprintf("i%d", ins), strconv.Itoa(tr), strconv.Itoa(f), strconv.Itoa(it), "avgt", "ns/op", strconv.Itoa(inv), strconv.FormatFloat(v, 'g', -1, 64)})
+							if err != nil {
+								t.Fatalf("Could not write to CSV: %v", err)
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	w.Flush()
+	return sb.String()
+}
+
+func toBinary(t *testing.T, in string) []byte {
+	c, err := bench.FromCSV(context.Background(), strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("Could not read CSV: %v", err)
+	}
+	var buf bytes.Buffer
+	n, err := bench.WriteBinary(context.Background(), &buf, c)
+	if err != nil {
+		t.Fatalf("Could not write binary: %v", err)
+	}
+	if n != 5 {
+		t.Fatalf("Expected 5 written executions, was %d", n)
+	}
+	return buf.Bytes()
+}
+
+func TestBinaryRoundTrip(t *testing.T) {
+	in := binaryCSV(t)
+	expected, err := fromCSVHelper(t, strings.NewReader(in), 5, false)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	bin := toBinary(t, in)
+	if l := len(bin); l >= len(in)/2 {
+		t.Fatalf("Expected binary (%d bytes) to be less than half of the CSV (%d bytes)", l, len(in))
+	}
+
+	c, err := bench.FromBinary(context.Background(), bytes.NewReader(bin))
+	if err != nil {
+		t.Fatalf("Could not read binary: %v", err)
+	}
+	var got []*bench.Execution
+	var started, ended bool
+	for ev := range c {
+		switch ev.Type {
+		case bench.ExecStart:
+			started = true
+		case bench.ExecEnd:
+			ended = true
+		case bench.ExecError:
+			t.Fatalf("Unexpected error: %v", ev.Err)
+		case bench.ExecNext:
+			got = append(got, ev.Exec)
+		}
+	}
+	if !started || !ended {
+		t.Fatalf("started = %t, ended = %t", started, ended)
+	}
+
+	if len(got) != len(expected) {
+		t.Fatalf("Expected %d executions, was %d", len(expected), len(got))
+	}
+	for i := range got {
+		if !got[i].Benchmark.Equals(expected[i].Benchmark) {
+			t.Fatalf("Expected benchmark %v, was %v", expected[i].Benchmark, got[i].Benchmark)
+		}
+		if got[i].ElementCount() != expected[i].ElementCount() {
+			t.Fatalf("Expected %d elements of %v, was %d", expected[i].ElementCount(), got[i].Benchmark, got[i].ElementCount())
+		}
+		equalInstances(t, got[i], expected[i], true)
+	}
+}
+
+func TestBinaryReduction(t *testing.T) {
+	in := binaryCSV(t)
+	reduction := bench.Reduction{Mode: bench.HistogramReduction, Cap: 2}
+
+	c, err := bench.FromCSVWithReduction(context.Background(), strings.NewReader(in), reduction)
+	if err != nil {
+		t.Fatalf("Could not read CSV: %v", err)
+	}
+	var expected []*bench.Execution
+	for ev := range c {
+		if ev.Type == bench.ExecNext {
+			expected = append(expected, ev.Exec)
+		}
+	}
+
+	c, err = bench.FromBinaryWithReduction(context.Background(), bytes.NewReader(toBinary(t, in)), reduction)
+	if err != nil {
+		t.Fatalf("Could not read binary: %v", err)
+	}
+	var i int
+	for ev := range c {
+		if ev.Type == bench.ExecError {
+			t.Fatalf("Unexpected error: %v", ev.Err)
+		}
+		if ev.Type != bench.ExecNext {
+			continue
+		}
+		if l, el := len(ev.Exec.FlatSlice(bench.AllInvocations)), len(expected[i].FlatSlice(bench.AllInvocations)); l != el {
+			t.Fatalf("Expected %d reduced invocations of %v, was %d", el, ev.Exec.Benchmark, l)
+		}
+		i++
+	}
+	if i != len(expected) {
+		t.Fatalf("Expected %d executions, was %d", len(expected), i)
+	}
+}
+
+func TestBinaryPerCommit(t *testing.T) {
+	c, err := bench.FromCSVPerCommit(context.Background(), strings.NewReader(perCommitCSV(t)), bench.Reduction{})
+	if err != nil {
+		t.Fatalf("Could not read CSV: %v", err)
+	}
+	var buf bytes.Buffer
+	_, err = bench.WriteBinary(context.Background(), &buf, c)
+	if err != nil {
+		t.Fatalf("Could not write binary: %v", err)
+	}
+
+	for _, tc := range []struct {
+		perCommit bool
+		expected  string
+	}{
+		{true, "b1@p/c1:2,b1@p/c2:1,b2@p/c2:1"},
+		// consecutive executions of a benchmark are merged
+		{false, "b1@/:3,b2@/:1"},
+	} {
+		read := bench.FromBinaryWithReduction
+		if tc.perCommit {
+			read = bench.FromBinaryPerCommit
+		}
+		c, err := read(context.Background(), bytes.NewReader(buf.Bytes()), bench.Reduction{})
+		if err != nil {
+			t.Fatalf("Could not read binary: %v", err)
+		}
+		if s := commitExecutions(t, c); s != tc.expected {
+			t.Fatalf("Unexpected executions (per commit %t): was %s, expected %s", tc.perCommit, s, tc.expected)
+		}
+	}
+}
+
+func TestBinaryInvalid(t *testing.T) {
+	bin := toBinary(t, binaryCSV(t))
+
+	for _, in := range [][]byte{
+		nil,
+		[]byte("PAE"),
+		[]byte("CSV;header"),
+		append([]byte(bench.BinaryMagic), bench.BinaryVersion+1),
+	} {
+		_, err := bench.FromBinary(context.Background(), bytes.NewReader(in))
+		if err == nil {
+			t.Fatalf("Expected error for input %q", in)
+		}
+	}
+
+	// truncated
+	for _, l := range []int{len(bench.BinaryMagic) + 1, len(bin) / 2, len(bin) - 1} {
+		c, err := bench.FromBinary(context.Background(), bytes.NewReader(bin[:l]))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		errs, ended := binaryErrors(c)
+		if errs != 1 || ended {
+			t.Fatalf("Expected one error and no end for truncated input of %d bytes, was %d errors and ended %t", l, errs, ended)
+		}
+	}
+
+	// huge lengths
+	huge := []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01}
+	for _, tag := range []byte{1, 2} {
+		in := append([]byte(bench.BinaryMagic), bench.BinaryVersion, tag)
+		in = append(in, huge...)
+		c, err := bench.FromBinary(context.Background(), bytes.NewReader(in))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		errs, ended := binaryErrors(c)
+		if errs != 1 || ended {
+			t.Fatalf("Expected one error and no end for huge length of tag %d, was %d errors and ended %t", tag, errs, ended)
+		}
+	}
+
+	// huge counts of an execution record: benchmark "b" without parameters of project "" and commit "" with instance "i"
+	head := append([]byte(bench.BinaryMagic), bench.BinaryVersion, 1, 1, 'b', 2, 0, 5, 0, 6, 0, 3, 1, 'i', 4, 0, 0, 0, 0)
+	for _, c := range []struct {
+		name string
+		in   []byte
+	}{
+		{"function parameters", append(append([]byte{}, head...), huge...)},
+		// no function parameters, one row of instance 0 with trial, fork, and iteration 1
+		{"invocation count", append(append(append([]byte{}, head...), 0, 1, 0, 2, 2, 2), huge...)},
+	} {
+		in := append(append(c.in, make([]byte, 8)...), 0)
+		ch, err := bench.FromBinary(context.Background(), bytes.NewReader(in))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		errs, ended := binaryErrors(ch)
+		if errs != 1 || ended {
+			t.Fatalf("Expected one error and no end for huge %s, was %d errors and ended %t", c.name, errs, ended)
+		}
+	}
+}
+
+// binaryErrors returns the number of errors of c and whether it ended
+func binaryErrors(c bench.Chan) (errs int, ended bool) {
+	for ev := range c {
+		switch ev.Type {
+		case bench.ExecError:
+			errs++
+		case bench.ExecEnd:
+			ended = true
+		}
+	}
+	return errs, ended
+}
+
+func TestWriteBinaryError(t *testing.T) {
+	var buf bytes.Buffer
+	_, err := bench.WriteBinary(context.Background(), &buf, pairInput(true, true, "a", fmt.Errorf("e")))
+	if err == nil {
+		t.Fatalf("Expected error of input")
+	}
+	_, err = bench.WriteBinary(context.Background(), &buf, pairInput(true, false, "a"))
+	if err == nil {
+		t.Fatalf("Expected error of truncated input")
+	}
+}
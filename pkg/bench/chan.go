@@ -1,6 +1,7 @@
 package bench
 
 import (
+	"context"
 	"fmt"
 	"sort"
 )
@@ -23,6 +24,12 @@ const (
 )
 
 func MergeChans(cs ...Chan) Chan {
+	return MergeChansContext(context.Background(), cs...)
+}
+
+// MergeChansContext merges the executions of the same benchmarks of cs.
+// If ctx is done, merging stops and the returned channel is closed without sending ExecEnd.
+func MergeChansContext(ctx context.Context, cs ...Chan) Chan {
 	out := make(Chan)
 
 	go func() {
@@ -31,14 +38,20 @@ func MergeChans(cs ...Chan) Chan {
 		active := len(cs)
 		crs := make(Executions, 0, len(cs))
 
-		out <- ExecutionValue{
-			Type: ExecStart,
+		if !send(ctx, out, ExecutionValue{Type: ExecStart}) {
+			return
 		}
 
 		for active != 0 {
 		ChanLoop:
 			for _, c := range cs {
-				ev, ok := <-c
+				var ev ExecutionValue
+				var ok bool
+				select {
+				case ev, ok = <-c:
+				case <-ctx.Done():
+					return
+				}
 				if !ok {
 					// remove channel from array of channels
 					continue
@@ -51,29 +64,41 @@ func MergeChans(cs ...Chan) Chan {
 					active--
 					continue ChanLoop
 				case ExecError:
-					out <- ev
+					if !send(ctx, out, ev) {
+						return
+					}
 				case ExecNext:
 					crs = append(crs, ev.Exec)
 				}
 			}
 
-			mergeExecutionValues(crs, out)
+			if !mergeExecutionValues(ctx, crs, out) {
+				return
+			}
 
 			// reset crs
 			crs = make(Executions, 0, len(cs))
 		}
 
-		out <- ExecutionValue{
-			Type: ExecEnd,
-		}
+		send(ctx, out, ExecutionValue{Type: ExecEnd})
 	}()
 
 	return out
 }
 
-func mergeExecutionValues(evs Executions, out Chan) {
+// send sends ev on c and returns true, unless ctx is done
+func send(ctx context.Context, c Chan, ev ExecutionValue) bool {
+	select {
+	case c <- ev:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func mergeExecutionValues(ctx context.Context, evs Executions, out Chan) bool {
 	if len(evs) == 0 {
-		return
+		return true
 	}
 	// sort evs
 	sort.Sort(evs)
@@ -91,26 +116,40 @@ func mergeExecutionValues(evs Executions, out Chan) {
 				continue
 			} else {
 				// send unmerged
-				out <- ExecutionValue{
-					Type: ExecNext,
-					Exec: prev,
+				if !send(ctx, out, ExecutionValue{Type: ExecNext, Exec: prev}) {
+					return false
 				}
 			}
 		}
 		prev = ev
 	}
 
-	out <- ExecutionValue{
-		Type: ExecNext,
-		Exec: prev,
-	}
+	return send(ctx, out, ExecutionValue{Type: ExecNext, Exec: prev})
 }
 
 func TransformChan(transformer ExecutionTransformer, c Chan) Chan {
+	return TransformChanContext(context.Background(), transformer, c)
+}
+
+// TransformChanContext transforms the executions of c.
+// If ctx is done, transformation stops and the returned channel is closed.
+func TransformChanContext(ctx context.Context, transformer ExecutionTransformer, c Chan) Chan {
 	out := make(Chan)
 
 	go func() {
-		for ev := range c {
+		defer close(out)
+		for {
+			var ev ExecutionValue
+			var ok bool
+			select {
+			case ev, ok = <-c:
+			case <-ctx.Done():
+				return
+			}
+			if !ok {
+				return
+			}
+
 			nev := ExecutionValue{
 				Type: ev.Type,
 				Err:  ev.Err,
@@ -122,9 +161,10 @@ func TransformChan(transformer ExecutionTransformer, c Chan) Chan {
 				nev.Exec = ev.Exec
 			}
 
-			out <- nev
+			if !send(ctx, out, nev) {
+				return
+			}
 		}
-		close(out)
 	}()
 
 	return out
@@ -0,0 +1,89 @@
+package bench_test
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chrstphlbr/pa/pkg/bench"
+)
+
+func csvChan(ctx context.Context, t *testing.T, nrBenchs int) bench.Chan {
+	w, sb := header(t)
+	for b := 1; b <= nrBenchs; b++ {
+		writeInvocations(t, w, bench.New(fmt.Sprintf("b%d", b)), "i1", 1, 1, 1, 5)
+	}
+
+	c, err := bench.FromCSV(ctx, strings.NewReader(sb.String()))
+	if err != nil {
+		t.Fatalf("Could not get Benchmark channel: %v", err)
+	}
+	return c
+}
+
+// checkNoGoroutineLeak waits until the number of goroutines dropped to at most before
+func checkNoGoroutineLeak(t *testing.T, before int) {
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		now := runtime.NumGoroutine()
+		if now <= before {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Goroutines leaked: %d before, %d after cancellation", before, now)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func cancelAfter(t *testing.T, c bench.Chan, cancel context.CancelFunc, values int) {
+	for i := 0; i < values; i++ {
+		if _, ok := <-c; !ok {
+			t.Fatalf("Channel closed after %d values", i)
+		}
+	}
+	// consumer stops reading
+	cancel()
+}
+
+func TestMergeChansContextCancel(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	mc := bench.MergeChansContext(ctx, csvChan(ctx, t, 50), csvChan(ctx, t, 50))
+	cancelAfter(t, mc, cancel, 3)
+
+	checkNoGoroutineLeak(t, before)
+}
+
+func TestTransformChanContextCancel(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	tc := bench.TransformChanContext(ctx, bench.ExecutionTransformerFunc(bench.IdentityExecutionTransformerFunc), csvChan(ctx, t, 50))
+	cancelAfter(t, tc, cancel, 3)
+
+	checkNoGoroutineLeak(t, before)
+}
+
+func TestMergeChansContextCancelledStages(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c := bench.TransformChanContext(ctx, bench.ExecutionTransformerFunc(bench.IdentityExecutionTransformerFunc), bench.MergeChansContext(ctx, csvChan(ctx, t, 50)))
+	cancel()
+
+	// the channel is closed eventually without reading all values
+	var cnt int
+	for range c {
+		cnt++
+	}
+	if cnt >= 52 {
+		t.Fatalf("Expected cancelled channel, but received all %d values", cnt)
+	}
+
+	checkNoGoroutineLeak(t, before)
+}
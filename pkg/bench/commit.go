@@ -0,0 +1,136 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+)
+
+// CommitExecutions holds the executions of a benchmark per commit, in commit order.
+// If the input sent an error, Err is set and Execs is nil.
+type CommitExecutions struct {
+	Benchmark *B
+	Execs     []*Execution
+	Err       error
+}
+
+// Commits returns the commits of the executions
+func (ce CommitExecutions) Commits() []string {
+	cs := make([]string, len(ce.Execs))
+	for i, e := range ce.Execs {
+		cs[i] = e.Commit
+	}
+	return cs
+}
+
+// Merge returns an execution of the commits from index start (inclusive) to end (exclusive), e.g., of a segment between change points, where the instances of every commit are separate instances (with IDs prefixed by the commit).
+// The returned execution shares the invocations with the executions of the commits.
+func (ce CommitExecutions) Merge(start, end int) (*Execution, error) {
+	if start < 0 || end > len(ce.Execs) || start >= end {
+		return nil, fmt.Errorf("invalid commits [%d, %d) of %d commits", start, end, len(ce.Execs))
+	}
+	if end-start == 1 {
+		return ce.Execs[start], nil
+	}
+
+	m := NewExecution(ce.Benchmark)
+	for _, e := range ce.Execs[start:end] {
+		for _, iid := range e.InstanceIDs {
+			i, ok := e.Instances[iid]
+			if !ok {
+				panic(fmt.Sprintf("Invalid state: InstanceIDs and Instances out of sync for %s", iid))
+			}
+			id := e.Commit + "/" + iid
+			if _, ok := m.Instances[id]; ok {
+				return nil, fmt.Errorf("%v: instance '%s' of commit '%s' is not unique", ce.Benchmark, iid, e.Commit)
+			}
+			m.InstanceIDs = append(m.InstanceIDs, id)
+			m.Instances[id] = &Instance{
+				ID:       id,
+				TrialIDs: i.TrialIDs,
+				Trials:   i.Trials,
+			}
+		}
+		m.Reduction.Add(e.Reduction)
+		m.addLen(e.ElementCount())
+	}
+	return m, nil
+}
+
+func GroupCommits(c Chan) <-chan CommitExecutions {
+	return GroupCommitsContext(context.Background(), c)
+}
+
+// GroupCommitsContext groups the consecutive executions of the same benchmark of c, which has one execution per benchmark and commit (see FromCSVPerCommit).
+// A benchmark with the same commit in multiple executions (i.e., its rows of a commit are not consecutive) is sent as error.
+// If c is truncated (i.e., closed after its start but before its end), the last benchmark might miss commits and is not sent.
+// If ctx is done, grouping stops and the returned channel is closed.
+func GroupCommitsContext(ctx context.Context, c Chan) <-chan CommitExecutions {
+	out := make(chan CommitExecutions)
+
+	go func() {
+		defer close(out)
+		emit := func(ce CommitExecutions) bool {
+			select {
+			case out <- ce:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		var cur CommitExecutions
+		seen := make(map[string]struct{})
+		flush := func() bool {
+			if cur.Benchmark == nil {
+				return true
+			}
+			ce := cur
+			cur = CommitExecutions{}
+			seen = make(map[string]struct{})
+			return emit(ce)
+		}
+
+		for {
+			ev, ok := receive(ctx, c)
+			if ctx.Err() != nil || !ok {
+				return
+			}
+
+			switch ev.Type {
+			case ExecStart:
+				continue
+			case ExecEnd:
+				flush()
+				return
+			case ExecError:
+				if !flush() || !emit(CommitExecutions{Err: ev.Err}) {
+					return
+				}
+				continue
+			}
+
+			e := ev.Exec
+			if cur.Benchmark != nil && !cur.Benchmark.Equals(e.Benchmark) {
+				if !flush() {
+					return
+				}
+			}
+			if cur.Err != nil {
+				// skip the remaining executions of an erroneous benchmark
+				continue
+			}
+			if _, ok := seen[e.Commit]; ok {
+				cur = CommitExecutions{
+					Benchmark: e.Benchmark,
+					Err:       fmt.Errorf("%v: rows of commit '%s' are not consecutive", e.Benchmark, e.Commit),
+				}
+				continue
+			}
+			seen[e.Commit] = struct{}{}
+			cur.Benchmark = e.Benchmark
+			cur.Execs = append(cur.Execs, e)
+		}
+	}()
+
+	return out
+}
@@ -0,0 +1,129 @@
+package bench_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/chrstphlbr/pa/pkg/bench"
+)
+
+// commitInput sends an execution per "<benchmark>@<commit>" value, or an error
+func commitInput(end bool, values ...interface{}) bench.Chan {
+	c := make(bench.Chan, len(values)+2)
+	c <- bench.ExecutionValue{Type: bench.ExecStart}
+	for _, v := range values {
+		switch v := v.(type) {
+		case string:
+			bc := strings.SplitN(v, "@", 2)
+			e := bench.NewExecution(bench.New(bc[0]))
+			e.Commit = bc[1]
+			c <- bench.ExecutionValue{Type: bench.ExecNext, Exec: e}
+		case error:
+			c <- bench.ExecutionValue{Type: bench.ExecError, Err: v}
+		}
+	}
+	if end {
+		c <- bench.ExecutionValue{Type: bench.ExecEnd}
+	}
+	close(c)
+	return c
+}
+
+func TestGroupCommits(t *testing.T) {
+	for _, c := range []struct {
+		c        bench.Chan
+		expected string
+	}{
+		{
+			commitInput(true, "a@1", "a@2", "a@3", "b@1", "c@2", "c@3"),
+			"a:1/2/3,b:1,c:2/3",
+		},
+		{
+			commitInput(true),
+			"",
+		},
+		{
+			commitInput(true, "a@1", errors.New("e"), "b@1", "b@2"),
+			"a:1,err,b:1/2",
+		},
+		// non-consecutive commit
+		{
+			commitInput(true, "a@1", "a@2", "a@1", "a@3", "b@1"),
+			"a:err,b:1",
+		},
+		// truncated
+		{
+			commitInput(false, "a@1", "a@2", "b@1"),
+			"a:1/2",
+		},
+	} {
+		var gs []string
+		for ce := range bench.GroupCommits(c.c) {
+			var s string
+			if ce.Benchmark != nil {
+				s = ce.Benchmark.Name + ":"
+			}
+			if ce.Err != nil {
+				s += "err"
+			} else {
+				s += strings.Join(ce.Commits(), "/")
+			}
+			gs = append(gs, s)
+		}
+		if s := strings.Join(gs, ","); s != c.expected {
+			t.Fatalf("Unexpected commit groups: was %s, expected %s", s, c.expected)
+		}
+	}
+}
+
+func TestCommitExecutionsMerge(t *testing.T) {
+	b := bench.New("a")
+	ce := bench.CommitExecutions{Benchmark: b}
+	for i, commit := range []string{"1", "2", "3"} {
+		e := bench.NewExecution(b)
+		e.Commit = commit
+		for it := 1; it <= i+1; it++ {
+			err := e.AddInvocations(bench.InvocationsFlat{Benchmark: b, Instance: "i1", Trial: 1, Fork: 1, Iteration: it, Invocations: bench.Invocations{Count: 1, Value: float64(i)}})
+			if err != nil {
+				t.Fatalf("Could not add invocations: %v", err)
+			}
+		}
+		ce.Execs = append(ce.Execs, e)
+	}
+
+	for _, c := range []struct {
+		start, end int
+		instances  string
+		values     int
+	}{
+		{0, 1, "i1", 1},
+		{1, 3, "2/i1,3/i1", 5},
+		{0, 3, "1/i1,2/i1,3/i1", 6},
+	} {
+		m, err := ce.Merge(c.start, c.end)
+		if err != nil {
+			t.Fatalf("Could not merge [%d, %d): %v", c.start, c.end, err)
+		}
+		if s := strings.Join(m.InstanceIDs, ","); s != c.instances {
+			t.Fatalf("Unexpected instances of [%d, %d): was %s, expected %s", c.start, c.end, s, c.instances)
+		}
+		if l := len(m.FlatSlice(bench.AllInvocations)); l != c.values {
+			t.Fatalf("Expected %d values of [%d, %d), was %d", c.values, c.start, c.end, l)
+		}
+		var count int
+		for _, e := range ce.Execs[c.start:c.end] {
+			count += e.ElementCount()
+		}
+		if m.ElementCount() != count {
+			t.Fatalf("Expected element count %d of [%d, %d), was %d", count, c.start, c.end, m.ElementCount())
+		}
+	}
+
+	for _, r := range [][2]int{{-1, 1}, {1, 1}, {2, 4}} {
+		_, err := ce.Merge(r[0], r[1])
+		if err == nil {
+			t.Fatalf("Expected error for commits [%d, %d)", r[0], r[1])
+		}
+	}
+}
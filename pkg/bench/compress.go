@@ -0,0 +1,105 @@
+package bench
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+type Compression int
+
+const (
+	NoCompression Compression = iota
+	Gzip
+	Zstd
+	Xz
+	Bzip2
+)
+
+func (c Compression) String() string {
+	switch c {
+	case NoCompression:
+		return "none"
+	case Gzip:
+		return "gzip"
+	case Zstd:
+		return "zstd"
+	case Xz:
+		return "xz"
+	case Bzip2:
+		return "bzip2"
+	default:
+		return fmt.Sprintf("Compression(%d)", int(c))
+	}
+}
+
+// magic bytes of the compression formats
+var compressionMagics = []struct {
+	c     Compression
+	magic []byte
+}{
+	{c: Gzip, magic: []byte{0x1f, 0x8b}},
+	{c: Zstd, magic: []byte{0x28, 0xb5, 0x2f, 0xfd}},
+	{c: Xz, magic: []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}},
+	{c: Bzip2, magic: []byte{'B', 'Z', 'h'}},
+}
+
+// Decompress returns a reader of the decompressed r, where the compression (gzip, zstd, xz, or bzip2) is detected by the magic bytes at the start of r.
+// If r is not compressed, its data is returned as is.
+// Closing the returned reader releases the decompressor but does not close r.
+func Decompress(r io.Reader) (io.ReadCloser, Compression, error) {
+	br := bufio.NewReader(r)
+	c := detectCompression(br)
+
+	switch c {
+	case Gzip:
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, c, fmt.Errorf("could not read gzip input: %w", err)
+		}
+		return zr, c, nil
+	case Zstd:
+		zr, err := zstd.NewReader(br)
+		if err != nil {
+			return nil, c, fmt.Errorf("could not read zstd input: %w", err)
+		}
+		return zstdReadCloser{zr}, c, nil
+	case Xz:
+		xr, err := xz.NewReader(br)
+		if err != nil {
+			return nil, c, fmt.Errorf("could not read xz input: %w", err)
+		}
+		return ioutil.NopCloser(xr), c, nil
+	case Bzip2:
+		return ioutil.NopCloser(bzip2.NewReader(br)), c, nil
+	default:
+		return ioutil.NopCloser(br), c, nil
+	}
+}
+
+func detectCompression(r *bufio.Reader) Compression {
+	for _, cm := range compressionMagics {
+		magic, _ := r.Peek(len(cm.magic))
+		if bytes.Equal(magic, cm.magic) {
+			return cm.c
+		}
+	}
+	return NoCompression
+}
+
+// zstdReadCloser closes a zstd.Decoder, whose Close does not return an error
+type zstdReadCloser struct {
+	*zstd.Decoder
+}
+
+func (z zstdReadCloser) Close() error {
+	z.Decoder.Close()
+	return nil
+}
@@ -0,0 +1,139 @@
+package bench_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/chrstphlbr/pa/pkg/bench"
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// bzip2Data is 'bzip2 compressed\n' compressed with bzip2, for which the standard library has no writer
+var bzip2Data = []byte{
+	0x42, 0x5a, 0x68, 0x39, 0x31, 0x41, 0x59, 0x26, 0x53, 0x59, 0xad, 0x89,
+	0xf3, 0x10, 0x00, 0x00, 0x01, 0xd9, 0x80, 0x00, 0x10, 0x40, 0x00, 0x10,
+	0x00, 0x1e, 0x22, 0xd8, 0x10, 0x20, 0x00, 0x22, 0x98, 0x09, 0x8c, 0xa1,
+	0x00, 0x00, 0x21, 0xb1, 0x9e, 0x80, 0x27, 0x41, 0xca, 0xf1, 0xf8, 0xbb,
+	0x92, 0x29, 0xc2, 0x84, 0x85, 0x6c, 0x4f, 0x98, 0x80,
+}
+
+func compressed(t *testing.T, c bench.Compression, data []byte) []byte {
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	var err error
+	switch c {
+	case bench.NoCompression:
+		return data
+	case bench.Gzip:
+		w = gzip.NewWriter(&buf)
+	case bench.Zstd:
+		w, err = zstd.NewWriter(&buf)
+	case bench.Xz:
+		w, err = xz.NewWriter(&buf)
+	default:
+		t.Fatalf("No writer for %v", c)
+	}
+	if err != nil {
+		t.Fatalf("Could not create %v writer: %v", c, err)
+	}
+	_, err = w.Write(data)
+	if err != nil {
+		t.Fatalf("Could not write %v: %v", c, err)
+	}
+	err = w.Close()
+	if err != nil {
+		t.Fatalf("Could not close %v writer: %v", c, err)
+	}
+	return buf.Bytes()
+}
+
+func decompressed(t *testing.T, in []byte, expectedCompression bench.Compression) []byte {
+	r, c, err := bench.Decompress(bytes.NewReader(in))
+	if err != nil {
+		t.Fatalf("Could not decompress: %v", err)
+	}
+	defer r.Close()
+	if c != expectedCompression {
+		t.Fatalf("Expected compression %v, was %v", expectedCompression, c)
+	}
+	out, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatalf("Could not read %v: %v", c, err)
+	}
+	return out
+}
+
+func TestDecompress(t *testing.T) {
+	csv := []byte(binaryCSV(t))
+	bin := toBinary(t, binaryCSV(t))
+
+	for _, c := range []bench.Compression{bench.NoCompression, bench.Gzip, bench.Zstd, bench.Xz} {
+		for _, data := range [][]byte{csv, bin} {
+			out := decompressed(t, compressed(t, c, data), c)
+			if !bytes.Equal(out, data) {
+				t.Fatalf("%v: expected %d decompressed bytes equal to the input, were %d bytes", c, len(data), len(out))
+			}
+		}
+	}
+}
+
+func TestDecompressBzip2(t *testing.T) {
+	out := decompressed(t, bzip2Data, bench.Bzip2)
+	if s := string(out); s != "bzip2 compressed\n" {
+		t.Fatalf("Unexpected decompressed data: '%s'", s)
+	}
+}
+
+func TestDecompressCSV(t *testing.T) {
+	in := binaryCSV(t)
+	r, _, err := bench.Decompress(bytes.NewReader(compressed(t, bench.Zstd, []byte(in))))
+	if err != nil {
+		t.Fatalf("Could not decompress: %v", err)
+	}
+	defer r.Close()
+
+	c, err := bench.FromCSV(context.Background(), r)
+	if err != nil {
+		t.Fatalf("Could not read CSV: %v", err)
+	}
+	var execs int
+	for ev := range c {
+		switch ev.Type {
+		case bench.ExecError:
+			t.Fatalf("Unexpected error: %v", ev.Err)
+		case bench.ExecNext:
+			execs++
+		}
+	}
+	if execs != 5 {
+		t.Fatalf("Expected 5 executions, was %d", execs)
+	}
+}
+
+func TestDecompressInvalid(t *testing.T) {
+	// gzip magic bytes with an invalid header
+	_, c, err := bench.Decompress(bytes.NewReader([]byte{0x1f, 0x8b, 0x00, 0x00}))
+	if err == nil {
+		t.Fatalf("Expected error for invalid gzip header")
+	}
+	if c != bench.Gzip {
+		t.Fatalf("Expected compression gzip, was %v", c)
+	}
+
+	// truncated data
+	data := compressed(t, bench.Xz, []byte(binaryCSV(t)))
+	r, _, err := bench.Decompress(bytes.NewReader(data[:len(data)/2]))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer r.Close()
+	_, err = ioutil.ReadAll(r)
+	if err == nil {
+		t.Fatalf("Expected error for truncated xz data")
+	}
+}
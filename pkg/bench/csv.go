@@ -1,6 +1,7 @@
 package bench
 
 import (
+	"bufio"
 	"context"
 	"encoding/csv"
 	"fmt"
@@ -9,31 +10,87 @@ import (
 	"strings"
 )
 
+// CSVColumns are the columns of CSV input in their default order
+var CSVColumns = []string{"project", "commit", "benchmark", "params", "instance", "trial", "fork", "iteration", "mode", "unit", "value_count", "value"}
+
+// optionalCSVColumns are the columns that are empty if they are not in the header and have no default
+var optionalCSVColumns = map[string]bool{
+	"project": true,
+	"commit":  true,
+	"mode":    true,
+	"unit":    true,
+}
+
+// CSVFormat is the dialect and the columns of CSV input
+type CSVFormat struct {
+	// Delimiter separates the fields, ';' if 0
+	Delimiter rune
+	// Columns maps columns (see CSVColumns) to their names in the header, if they differ
+	Columns map[string]string
+	// Defaults are the values of columns that are not in the header
+	Defaults map[string]string
+}
+
 func FromCSV(ctx context.Context, r io.Reader) (Chan, error) {
+	return FromCSVWithReduction(ctx, r, Reduction{})
+}
+
+// FromCSVWithReduction reads executions from CSV, where the invocations of every iteration are reduced according to `reduction` while reading, which bounds the memory required per iteration
+func FromCSVWithReduction(ctx context.Context, r io.Reader, reduction Reduction) (Chan, error) {
+	return fromCSV(ctx, r, reduction, CSVFormat{}, false)
+}
+
+// FromCSVWithFormat reads executions from CSV like FromCSVWithReduction, where the columns are mapped by their names in the header (see CSVFormat).
+// Columns of the header that are not in CSVColumns are ignored.
+// If the header has no column 'benchmark' (or its mapped name) and the format maps no columns, the header is ignored and the columns are in the order of CSVColumns.
+func FromCSVWithFormat(ctx context.Context, r io.Reader, reduction Reduction, format CSVFormat) (Chan, error) {
+	return fromCSV(ctx, r, reduction, format, false)
+}
+
+// FromCSVPerCommit reads executions from CSV like FromCSVWithReduction, but with one execution per benchmark and commit (columns 'project' and 'commit'), i.e., consecutive executions have the same benchmark if it was executed on multiple commits.
+// Every execution has its Project and Commit set, and the commits are in the order of the rows of the benchmark.
+func FromCSVPerCommit(ctx context.Context, r io.Reader, reduction Reduction) (Chan, error) {
+	return fromCSV(ctx, r, reduction, CSVFormat{}, true)
+}
+
+// FromCSVPerCommitWithFormat reads executions from CSV like FromCSVPerCommit with the columns of format (see FromCSVWithFormat)
+func FromCSVPerCommitWithFormat(ctx context.Context, r io.Reader, reduction Reduction, format CSVFormat) (Chan, error) {
+	return fromCSV(ctx, r, reduction, format, true)
+}
+
+func fromCSV(ctx context.Context, r io.Reader, reduction Reduction, format CSVFormat, perCommit bool) (Chan, error) {
 	cr := csv.NewReader(r)
 	if cr == nil {
 		return nil, fmt.Errorf("Could not create reader")
 	}
 
 	cr.Comma = ';'
-	cr.FieldsPerRecord = 12
+	if format.Delimiter != 0 {
+		cr.Comma = format.Delimiter
+	}
+	// every record has the number of fields of the header
+	cr.FieldsPerRecord = 0
 	cr.ReuseRecord = true
 
 	c := make(Chan)
 
-	// remove header
-	_, err := cr.Read()
+	header, err := cr.Read()
 	if err != nil {
 		if err == io.EOF {
 			go func() {
 				defer close(c)
-				c <- ExecutionValue{Type: ExecStart}
-				c <- ExecutionValue{Type: ExecEnd}
+				if send(ctx, c, ExecutionValue{Type: ExecStart}) {
+					send(ctx, c, ExecutionValue{Type: ExecEnd})
+				}
 			}()
 			return c, nil
 		}
 		return nil, err
 	}
+	cols, err := newCSVColumns(header, format)
+	if err != nil {
+		return nil, err
+	}
 
 	go func() {
 		defer close(c)
@@ -45,9 +102,9 @@ func FromCSV(ctx context.Context, r io.Reader) (Chan, error) {
 		for {
 			select {
 			case c <- ev:
-				ev, first = parseExecution(cr, first)
+				ev, first = parseExecution(cr, cols, first, reduction, perCommit)
 				if ev.Type == ExecEnd {
-					c <- ev
+					send(ctx, c, ev)
 					break Loop
 				}
 			case <-ctx.Done():
@@ -60,15 +117,18 @@ func FromCSV(ctx context.Context, r io.Reader) (Chan, error) {
 	return c, nil
 }
 
-func parseExecution(cr *csv.Reader, first *InvocationsFlat) (ExecutionValue, *InvocationsFlat) {
-	res := ExecutionValue{
-		Type: ExecNext,
-	}
+func parseExecution(cr *csv.Reader, cols *csvColumns, first *InvocationsFlat, reduction Reduction, perCommit bool) (ExecutionValue, *InvocationsFlat) {
+	var eb executionBuilder
+	// project and commit of the execution if perCommit
+	var project, commit string
 
 	// add first invocations from current benchmark
 	if first != nil {
-		res.Exec = NewExecution(first.Benchmark)
-		err := res.Exec.AddInvocations(*first)
+		eb = newExecutionBuilder(first.Benchmark, reduction)
+		if perCommit {
+			project, commit = first.Project, first.Commit
+		}
+		err := eb.add(*first)
 		if err != nil {
 			return ExecutionValue{
 				Type: ExecError,
@@ -84,8 +144,8 @@ func parseExecution(cr *csv.Reader, first *InvocationsFlat) (ExecutionValue, *In
 			// handle end of file
 			if err == io.EOF {
 				// handle EOF if there is a last element
-				if res.Exec != nil {
-					return res, nil
+				if eb != nil {
+					return executionValue(eb, project, commit), nil
 				}
 				return ExecutionValue{Type: ExecEnd}, nil
 			}
@@ -96,7 +156,7 @@ func parseExecution(cr *csv.Reader, first *InvocationsFlat) (ExecutionValue, *In
 			}, nil
 		}
 
-		cr, err := csvBenchExec(rec)
+		cr, err := csvBenchExec(cols.record(rec))
 		if err != nil {
 			// send error over channel
 			return ExecutionValue{
@@ -106,18 +166,21 @@ func parseExecution(cr *csv.Reader, first *InvocationsFlat) (ExecutionValue, *In
 		}
 
 		// check benchmark; handle first benchmark of all benchmarks in result
-		if res.Exec == nil {
+		if eb == nil {
 			// first line
-			res.Exec = NewExecution(cr.Benchmark)
+			eb = newExecutionBuilder(cr.Benchmark, reduction)
+			if perCommit {
+				project, commit = cr.Project, cr.Commit
+			}
 		}
 
-		// new benchmark
-		if !cr.Benchmark.Equals(res.Exec.Benchmark) {
-			return res, cr
+		// new benchmark (or new commit)
+		if !cr.Benchmark.Equals(eb.benchmark()) || (perCommit && (cr.Commit != commit || cr.Project != project)) {
+			return executionValue(eb, project, commit), cr
 		}
 
 		// still same benchmark -> append to existing results
-		err = res.Exec.AddInvocations(*cr)
+		err = eb.add(*cr)
 		if err != nil {
 			return ExecutionValue{
 				Type: ExecError,
@@ -127,31 +190,24 @@ func parseExecution(cr *csv.Reader, first *InvocationsFlat) (ExecutionValue, *In
 	}
 }
 
-func csvBenchExec(rec []string) (*InvocationsFlat, error) {
-	b := New(rec[2])
-	b.FunctionParams = make(FunctionParams, 0)
-
-	// params
-	if rawps := rec[3]; rawps != "" {
-		rawpsSplitted := strings.Split(rawps, "=")
-		var prevKey string
-		for i := 0; i < len(rawpsSplitted); i++ {
-			el := rawpsSplitted[i]
-			if i == 0 {
-				// first element -> only key
-				prevKey = el
-			} else if i == len(rawpsSplitted)-1 {
-				// last element -> value only
-				b.PerfParams.Add(prevKey, el)
-			} else {
-				// any middle element -> value,key
-				lastComma := strings.LastIndex(el, ",")
-				v := el[:lastComma]
-				b.PerfParams.Add(prevKey, v)
-				prevKey = el[lastComma+1:]
-			}
+func executionValue(eb executionBuilder, project, commit string) ExecutionValue {
+	e, err := eb.execution()
+	if err != nil {
+		return ExecutionValue{
+			Type: ExecError,
+			Err:  err,
 		}
 	}
+	e.Project = project
+	e.Commit = commit
+	return ExecutionValue{
+		Type: ExecNext,
+		Exec: e,
+	}
+}
+
+func csvBenchExec(rec []string) (*InvocationsFlat, error) {
+	b := csvBenchmark(rec[2], rec[3])
 
 	// project;commit;benchmark;params;instance;trial;fork;iteration;mode;unit;value_count;value
 
@@ -187,6 +243,8 @@ func csvBenchExec(rec []string) (*InvocationsFlat, error) {
 
 	return &InvocationsFlat{
 		Benchmark: b,
+		Project:   rec[0],
+		Commit:    rec[1],
 		Instance:  rec[4],
 		Trial:     t,
 		Fork:      f,
@@ -197,3 +255,153 @@ func csvBenchExec(rec []string) (*InvocationsFlat, error) {
 		},
 	}, nil
 }
+
+// csvBenchmark creates the benchmark of name with its performance parameters in the form of the column 'params', i.e., 'key1=value1,key2=value2'
+func csvBenchmark(name, rawps string) *B {
+	b := New(name)
+	b.FunctionParams = make(FunctionParams, 0)
+
+	if rawps != "" {
+		rawpsSplitted := strings.Split(rawps, "=")
+		var prevKey string
+		for i := 0; i < len(rawpsSplitted); i++ {
+			el := rawpsSplitted[i]
+			if i == 0 {
+				// first element -> only key
+				prevKey = el
+			} else if i == len(rawpsSplitted)-1 {
+				// last element -> value only
+				b.PerfParams.Add(prevKey, el)
+			} else {
+				// any middle element -> value,key
+				lastComma := strings.LastIndex(el, ",")
+				v := el[:lastComma]
+				b.PerfParams.Add(prevKey, v)
+				prevKey = el[lastComma+1:]
+			}
+		}
+	}
+	return b
+}
+
+// csvColumns maps the fields of records to the columns of CSVColumns
+type csvColumns struct {
+	// fields are the field indices of the columns, or -1 for their default
+	fields   []int
+	defaults []string
+	rec      []string
+}
+
+func newCSVColumns(header []string, format CSVFormat) (*csvColumns, error) {
+	cc := &csvColumns{
+		fields:   make([]int, len(CSVColumns)),
+		defaults: make([]string, len(CSVColumns)),
+		rec:      make([]string, len(CSVColumns)),
+	}
+
+	names := make(map[string]int, len(header))
+	for i := len(header) - 1; i >= 0; i-- {
+		// the first of duplicate names
+		names[strings.TrimSpace(strings.TrimPrefix(header[i], "\ufeff"))] = i
+	}
+
+	name := func(col string) string {
+		if n, ok := format.Columns[col]; ok {
+			return n
+		}
+		return col
+	}
+
+	if _, ok := names[name("benchmark")]; !ok && len(format.Columns) == 0 {
+		// header without names -> default order
+		if len(header) != len(CSVColumns) {
+			return nil, fmt.Errorf("CSV header has neither a column '%s' nor the %d columns in default order: %w", name("benchmark"), len(CSVColumns), &csv.ParseError{StartLine: 1, Line: 1, Column: 1, Err: csv.ErrFieldCount})
+		}
+		for i := range cc.fields {
+			cc.fields[i] = i
+		}
+		return cc, nil
+	}
+
+	var missing []string
+	for i, col := range CSVColumns {
+		if f, ok := names[name(col)]; ok && name(col) != "" {
+			cc.fields[i] = f
+			continue
+		}
+		cc.fields[i] = -1
+		if d, ok := format.Defaults[col]; ok {
+			cc.defaults[i] = d
+		} else if !optionalCSVColumns[col] {
+			missing = append(missing, name(col))
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("CSV header has no columns %s", strings.Join(missing, ", "))
+	}
+	return cc, nil
+}
+
+// record returns the fields of rec in the order of CSVColumns, where the returned slice is reused by the next call
+func (cc *csvColumns) record(rec []string) []string {
+	for i, f := range cc.fields {
+		if f == -1 {
+			cc.rec[i] = cc.defaults[i]
+		} else {
+			cc.rec[i] = rec[f]
+		}
+	}
+	return cc.rec
+}
+
+// CSVColumnsSeparator separates the fields of a line of a columns file (see ParseCSVColumns)
+const CSVColumnsSeparator = ";"
+
+// ParseCSVColumns reads the columns of a CSV format (see CSVFormat) with one '<column>;<name>[;<default>]' per line, where column is one of CSVColumns, name is its name in the header (empty if the column is missing), and default is its value if the header has no column name, e.g., 'benchmark;name' or 'instance;;local'.
+// Empty lines and lines starting with '#' are ignored. The delimiter of the returned format is not set.
+func ParseCSVColumns(r io.Reader) (CSVFormat, error) {
+	known := make(map[string]bool, len(CSVColumns))
+	for _, col := range CSVColumns {
+		known[col] = true
+	}
+
+	f := CSVFormat{
+		Columns:  make(map[string]string),
+		Defaults: make(map[string]string),
+	}
+
+	s := bufio.NewScanner(r)
+	nr := 0
+	for s.Scan() {
+		nr++
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.SplitN(line, CSVColumnsSeparator, 3)
+		if len(fields) < 2 {
+			return CSVFormat{}, fmt.Errorf("line %d: expected '<column>%s<name>[%s<default>]', was '%s'", nr, CSVColumnsSeparator, CSVColumnsSeparator, line)
+		}
+		col := strings.TrimSpace(fields[0])
+		if !known[col] {
+			return CSVFormat{}, fmt.Errorf("line %d: unknown column '%s', expected one of %s", nr, col, strings.Join(CSVColumns, ", "))
+		}
+		if _, ok := f.Columns[col]; ok {
+			return CSVFormat{}, fmt.Errorf("line %d: duplicate column '%s'", nr, col)
+		}
+
+		name := strings.TrimSpace(fields[1])
+		if name == "" && len(fields) < 3 {
+			return CSVFormat{}, fmt.Errorf("line %d: column '%s' has neither a name nor a default", nr, col)
+		}
+		f.Columns[col] = name
+		if len(fields) == 3 {
+			f.Defaults[col] = strings.TrimSpace(fields[2])
+		}
+	}
+	if err := s.Err(); err != nil {
+		return CSVFormat{}, err
+	}
+	return f, nil
+}
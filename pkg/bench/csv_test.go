@@ -361,3 +361,249 @@ func TestFromCSVMultiInvsBenchs(t *testing.T) {
 func TestFromCSVMultiInvsAll(t *testing.T) {
 	fromCSVMultiInvs(t, 5, 5, 5, 5, 5, 5, 20)
 }
+
+// perCommitCSV returns CSV with benchmarks executed on multiple commits
+func perCommitCSV(t *testing.T) string {
+	w, sb := header(t)
+
+	//[]string{"project", "commit", "benchmark", "params", "instance", "trial", "fork", "iteration", "mode", "unit", "value_count", "value"}
+	for _, rec := range [][]string{
+		{"p", "c1", "b1", "", "i1", "1", "1", "1", "", "", "1", "1.0"},
+		{"p", "c1", "b1", "", "i1", "1", "1", "2", "", "", "1", "1.0"},
+		{"p", "c2", "b1", "", "i1", "1", "1", "1", "", "", "1", "2.0"},
+		{"p", "c2", "b2", "", "i1", "1", "1", "1", "", "", "1", "3.0"},
+	} {
+		err := w.Write(rec)
+		if err != nil {
+			t.Fatalf("Could not write to CSV: %v", err)
+		}
+	}
+	w.Flush()
+	return sb.String()
+}
+
+// commitExecutions returns the executions of c as benchmark@project/commit:invocations
+func commitExecutions(t *testing.T, c bench.Chan) string {
+	var got []string
+	for ev := range c {
+		if ev.Type == bench.ExecError {
+			t.Fatalf("Unexpected error: %v", ev.Err)
+		}
+		if ev.Type == bench.ExecNext {
+			got = append(got, fmt.Sprintf("%s@%s/%s:%d", ev.Exec.Benchmark.Name, ev.Exec.Project, ev.Exec.Commit, len(ev.Exec.FlatSlice(bench.AllInvocations))))
+		}
+	}
+	return strings.Join(got, ",")
+}
+
+func TestFromCSVPerCommit(t *testing.T) {
+	c, err := bench.FromCSVPerCommit(context.TODO(), strings.NewReader(perCommitCSV(t)), bench.Reduction{})
+	if err != nil {
+		t.Fatalf("Could not get Benchmark channel: %v", err)
+	}
+
+	expected := "b1@p/c1:2,b1@p/c2:1,b2@p/c2:1"
+	if s := commitExecutions(t, c); s != expected {
+		t.Fatalf("Unexpected executions: was %s, expected %s", s, expected)
+	}
+}
+
+// rewriteCSV writes the records of the CSV in (with the columns of bench.CSVColumns) with the columns of header and delimiter delim, where a column of header that is not in bench.CSVColumns is 'x'
+func rewriteCSV(t *testing.T, in string, header []string, delim rune) string {
+	cr := csv.NewReader(strings.NewReader(in))
+	cr.Comma = ';'
+	recs, err := cr.ReadAll()
+	if err != nil {
+		t.Fatalf("Could not read CSV: %v", err)
+	}
+
+	idx := make(map[string]int)
+	for i, col := range bench.CSVColumns {
+		idx[col] = i
+	}
+
+	sb := &strings.Builder{}
+	w := csv.NewWriter(sb)
+	w.Comma = delim
+	err = w.Write(header)
+	if err != nil {
+		t.Fatalf("Could not write header: %v", err)
+	}
+	for _, rec := range recs[1:] {
+		out := make([]string, len(header))
+		for i, col := range header {
+			if j, ok := idx[col]; ok {
+				out[i] = rec[j]
+			} else {
+				out[i] = "x"
+			}
+		}
+		err := w.Write(out)
+		if err != nil {
+			t.Fatalf("Could not write to CSV: %v", err)
+		}
+	}
+	w.Flush()
+	return sb.String()
+}
+
+func fromCSVFormatHelper(t *testing.T, in string, format bench.CSVFormat) []*bench.Execution {
+	c, err := bench.FromCSVWithFormat(context.TODO(), strings.NewReader(in), bench.Reduction{}, format)
+	if err != nil {
+		t.Fatalf("Could not get Benchmark channel: %v", err)
+	}
+	var execs []*bench.Execution
+	for ev := range c {
+		switch ev.Type {
+		case bench.ExecError:
+			t.Fatalf("Unexpected error: %v", ev.Err)
+		case bench.ExecNext:
+			execs = append(execs, ev.Exec)
+		}
+	}
+	return execs
+}
+
+func equalExecutions(t *testing.T, got, expected []*bench.Execution) {
+	if len(got) != len(expected) {
+		t.Fatalf("Expected %d executions, was %d", len(expected), len(got))
+	}
+	for i := range got {
+		if !got[i].Benchmark.Equals(expected[i].Benchmark) {
+			t.Fatalf("Expected benchmark %v, was %v", expected[i].Benchmark, got[i].Benchmark)
+		}
+		equalInstances(t, got[i], expected[i], true)
+		equalValues(t, got[i], expected[i])
+	}
+}
+
+func TestFromCSVWithFormat(t *testing.T) {
+	in := binaryCSV(t)
+	expected, err := fromCSVHelper(t, strings.NewReader(in), 5, false)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	// different order, extra columns, and ',' as delimiter, i.e., the params are quoted
+	header := []string{"value", "extra", "benchmark", "instance", "params", "iteration", "fork", "trial", "value_count", "commit"}
+	rewritten := rewriteCSV(t, in, header, ',')
+	if !strings.Contains(rewritten, `"size=1,threads=4"`) {
+		t.Fatalf("Expected quoted params")
+	}
+	got := fromCSVFormatHelper(t, rewritten, bench.CSVFormat{Delimiter: ','})
+	equalExecutions(t, got, expected)
+
+	// tab as delimiter
+	got = fromCSVFormatHelper(t, rewriteCSV(t, in, bench.CSVColumns, '\t'), bench.CSVFormat{Delimiter: '\t'})
+	equalExecutions(t, got, expected)
+}
+
+func TestFromCSVWithFormatColumns(t *testing.T) {
+	in := binaryCSV(t)
+	expected, err := fromCSVHelper(t, strings.NewReader(in), 5, false)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	// renamed columns
+	rewritten := rewriteCSV(t, in, bench.CSVColumns, ';')
+	rewritten = strings.Replace(rewritten, "benchmark", "name", 1)
+	rewritten = strings.Replace(rewritten, "value_count", "count", 1)
+	got := fromCSVFormatHelper(t, rewritten, bench.CSVFormat{
+		Columns: map[string]string{
+			"benchmark":   "name",
+			"value_count": "count",
+		},
+	})
+	equalExecutions(t, got, expected)
+
+	// missing column with default
+	header := []string{"benchmark", "params", "trial", "fork", "iteration", "value_count", "value"}
+	got = fromCSVFormatHelper(t, rewriteCSV(t, in, header, ';'), bench.CSVFormat{
+		Defaults: map[string]string{
+			"instance": "local",
+		},
+	})
+	if len(got) != len(expected) {
+		t.Fatalf("Expected %d executions, was %d", len(expected), len(got))
+	}
+	for i, e := range got {
+		if len(e.InstanceIDs) != 1 || e.InstanceIDs[0] != "local" {
+			t.Fatalf("Expected instance 'local', was %v", e.InstanceIDs)
+		}
+		// both instances are merged into 'local'
+		equalValues(t, e, expected[i])
+	}
+}
+
+func TestFromCSVWithFormatMissingColumn(t *testing.T) {
+	header := []string{"benchmark", "params", "trial", "fork", "iteration", "value_count", "value"}
+	in := rewriteCSV(t, binaryCSV(t), header, ';')
+
+	_, err := bench.FromCSVWithFormat(context.TODO(), strings.NewReader(in), bench.Reduction{}, bench.CSVFormat{})
+	if err == nil {
+		t.Fatalf("Expected error for missing column 'instance'")
+	}
+	if !strings.Contains(err.Error(), "instance") {
+		t.Fatalf("Expected error to name the missing column, was: %v", err)
+	}
+
+	// mapped name not in header
+	_, err = bench.FromCSVWithFormat(context.TODO(), strings.NewReader(binaryCSV(t)), bench.Reduction{}, bench.CSVFormat{
+		Columns: map[string]string{"benchmark": "name"},
+	})
+	if err == nil {
+		t.Fatalf("Expected error for missing column 'name'")
+	}
+}
+
+func TestFromCSVUnnamedHeader(t *testing.T) {
+	in := binaryCSV(t)
+	expected, err := fromCSVHelper(t, strings.NewReader(in), 5, false)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	// header without the column names -> default order
+	unnamed := "a;b;c;d;e;f;g;h;i;j;k;l" + in[strings.Index(in, "\n"):]
+	got, err := fromCSVHelper(t, strings.NewReader(unnamed), 5, false)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	equalExecutions(t, got, expected)
+}
+
+func TestParseCSVColumns(t *testing.T) {
+	f, err := bench.ParseCSVColumns(strings.NewReader(`
+# harness columns
+benchmark;name
+instance;;local
+value_count ; count ; 1
+`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if f.Delimiter != 0 {
+		t.Fatalf("Expected no delimiter, was %q", f.Delimiter)
+	}
+	for col, name := range map[string]string{"benchmark": "name", "instance": "", "value_count": "count"} {
+		if n, ok := f.Columns[col]; !ok || n != name {
+			t.Fatalf("Expected column '%s' named '%s', was '%s' (%t)", col, name, n, ok)
+		}
+	}
+	if len(f.Defaults) != 2 || f.Defaults["instance"] != "local" || f.Defaults["value_count"] != "1" {
+		t.Fatalf("Unexpected defaults: %v", f.Defaults)
+	}
+
+	for _, in := range []string{
+		"benchmark",
+		"bench;name",
+		"instance;",
+		"benchmark;name\nbenchmark;b",
+	} {
+		_, err := bench.ParseCSVColumns(strings.NewReader(in))
+		if err == nil {
+			t.Fatalf("Expected error for '%s'", in)
+		}
+	}
+}
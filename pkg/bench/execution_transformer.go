@@ -22,6 +22,16 @@ func (f ExecutionTransformerFunc) transform(e *Execution) *Execution {
 	return f(e)
 }
 
+// ChainExecutionTransformer applies the transformers ts one after the other (from left to right)
+func ChainExecutionTransformer(ts ...ExecutionTransformer) ExecutionTransformerFunc {
+	return func(e *Execution) *Execution {
+		for _, t := range ts {
+			e = t.transform(e)
+		}
+		return e
+	}
+}
+
 func IdentityExecutionTransformerFunc(e *Execution) *Execution {
 	return e
 }
@@ -31,6 +31,8 @@ type ArraySizes struct {
 
 type InvocationsFlat struct {
 	Benchmark   *B
+	Project     string
+	Commit      string
 	Instance    string
 	Trial       int
 	Fork        int
@@ -41,6 +43,7 @@ type InvocationsFlat struct {
 type ExecutionSlice interface {
 	Slice(InvocationSampler) [][][][][]float64
 	FlatSlice(InvocationSampler) []float64
+	Flat() *FlatExecution
 	// returns the length of all leaf elements
 	// ElementCount() int
 }
@@ -162,9 +165,14 @@ type Execution struct {
 	Benchmark   *B
 	InstanceIDs []string
 	Instances   map[string]*Instance
-	arraySizes  ArraySizes
-	lenLock     sync.RWMutex
-	len         int
+	// Project and Commit are the project and version the execution belongs to, if it was read per commit (see FromCSVPerCommit)
+	Project string
+	Commit  string
+	// Reduction reports how much data was reduced when the execution was read (see Reduction)
+	Reduction  ReductionStats
+	arraySizes ArraySizes
+	lenLock    sync.RWMutex
+	len        int
 }
 
 func NewExecution(b *B) *Execution {
@@ -331,6 +339,9 @@ func (e *Execution) AddInvocations(is InvocationsFlat) error {
 func (e *Execution) Copy() *Execution {
 	ne := NewExecution(e.Benchmark.Copy())
 	ne.len = e.len
+	ne.Project = e.Project
+	ne.Commit = e.Commit
+	ne.Reduction = e.Reduction
 
 	iids := make([]string, len(e.InstanceIDs))
 	copy(iids, e.InstanceIDs)
@@ -411,6 +422,8 @@ func (e *Execution) Merge(other *Execution) error {
 		}
 	}
 
+	e.Reduction.Add(other.Reduction)
+
 	// update length
 	other.lenLock.RLock()
 	defer other.lenLock.RUnlock()
@@ -419,6 +432,39 @@ func (e *Execution) Merge(other *Execution) error {
 	return nil
 }
 
+// executionBuilder creates an Execution by adding invocations
+type executionBuilder interface {
+	benchmark() *B
+	add(InvocationsFlat) error
+	execution() (*Execution, error)
+}
+
+func newExecutionBuilder(b *B, r Reduction) executionBuilder {
+	if r.enabled() {
+		return newReducingExecutionBuilder(b, r)
+	}
+	return &plainExecutionBuilder{
+		e: NewExecution(b),
+	}
+}
+
+// plainExecutionBuilder adds all invocations to the Execution
+type plainExecutionBuilder struct {
+	e *Execution
+}
+
+func (pb *plainExecutionBuilder) benchmark() *B {
+	return pb.e.Benchmark
+}
+
+func (pb *plainExecutionBuilder) add(ivf InvocationsFlat) error {
+	return pb.e.AddInvocations(ivf)
+}
+
+func (pb *plainExecutionBuilder) execution() (*Execution, error) {
+	return pb.e, nil
+}
+
 type Executions []*Execution
 
 func (e Executions) Len() int {
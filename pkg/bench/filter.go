@@ -0,0 +1,134 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Filter decides whether a benchmark is kept
+type Filter func(b *B) bool
+
+var perfParamPredicate = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_.\-]*)\s*(!=|<=|>=|=|<|>)\s*(.*)$`)
+
+// ParseFilter parses a filter expression, which is either a predicate over a performance parameter of the form `<param><op><value>` with op one of `=`, `!=`, `<`, `<=`, `>`, or `>=` (e.g., `size=1024` or `threads>=4`),
+// or otherwise a regular expression matching (a part of) the benchmark name
+func ParseFilter(expr string) (Filter, error) {
+	if m := perfParamPredicate.FindStringSubmatch(expr); m != nil {
+		return PerfParamFilter(m[1], m[2], strings.TrimSpace(m[3]))
+	}
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter '%s': %w", expr, err)
+	}
+	return NameFilter(re), nil
+}
+
+// NameFilter keeps the benchmarks whose names match re
+func NameFilter(re *regexp.Regexp) Filter {
+	return func(b *B) bool {
+		return re.MatchString(b.Name)
+	}
+}
+
+// PerfParamFilter keeps the benchmarks with a performance parameter param whose value compares to value as defined by op (`=`, `!=`, `<`, `<=`, `>`, or `>=`).
+// Values are compared numerically if both are numbers and lexically otherwise. Benchmarks without the parameter are not kept.
+func PerfParamFilter(param, op, value string) (Filter, error) {
+	var cmp func(c int) bool
+	switch op {
+	case "=":
+		cmp = func(c int) bool { return c == 0 }
+	case "!=":
+		cmp = func(c int) bool { return c != 0 }
+	case "<":
+		cmp = func(c int) bool { return c < 0 }
+	case "<=":
+		cmp = func(c int) bool { return c <= 0 }
+	case ">":
+		cmp = func(c int) bool { return c > 0 }
+	case ">=":
+		cmp = func(c int) bool { return c >= 0 }
+	default:
+		return nil, fmt.Errorf("invalid operator '%s' (available: =, !=, <, <=, >, >=)", op)
+	}
+
+	return func(b *B) bool {
+		pv, ok := b.PerfParams.Get()[param]
+		if !ok {
+			return false
+		}
+		return cmp(ComparePerfParamValues(pv, value))
+	}, nil
+}
+
+// ComparePerfParamValues compares the performance-parameter values a and b numerically if both are numbers and lexically otherwise, and returns -1 (a is smaller), 0 (they are equal), or 1 (b is smaller)
+func ComparePerfParamValues(a, b string) int {
+	fa, erra := strconv.ParseFloat(a, 64)
+	fb, errb := strconv.ParseFloat(b, 64)
+	if erra == nil && errb == nil {
+		switch {
+		case fa < fb:
+			return -1
+		case fa > fb:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(a, b)
+}
+
+// IncludeExcludeFilter keeps the benchmarks that match all include filters and none of the exclude filters
+func IncludeExcludeFilter(include, exclude []Filter) Filter {
+	return func(b *B) bool {
+		for _, f := range include {
+			if !f(b) {
+				return false
+			}
+		}
+		for _, f := range exclude {
+			if f(b) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+func FilterChan(f Filter, c Chan) Chan {
+	return FilterChanContext(context.Background(), f, c)
+}
+
+// FilterChanContext forwards all values of c except the executions whose benchmarks are not kept by f.
+// If ctx is done, filtering stops and the returned channel is closed.
+func FilterChanContext(ctx context.Context, f Filter, c Chan) Chan {
+	out := make(Chan)
+
+	go func() {
+		defer close(out)
+		for {
+			var ev ExecutionValue
+			var ok bool
+			select {
+			case ev, ok = <-c:
+			case <-ctx.Done():
+				return
+			}
+			if !ok {
+				return
+			}
+
+			if ev.Type == ExecNext && !f(ev.Exec.Benchmark) {
+				continue
+			}
+
+			if !send(ctx, out, ev) {
+				return
+			}
+		}
+	}()
+
+	return out
+}
@@ -0,0 +1,123 @@
+package bench_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/chrstphlbr/pa/pkg/bench"
+)
+
+func benchWithParams(name string, params ...string) *bench.B {
+	b := bench.New(name)
+	for _, p := range params {
+		kv := strings.SplitN(p, "=", 2)
+		b.PerfParams.Add(kv[0], kv[1])
+	}
+	return b
+}
+
+func TestParseFilter(t *testing.T) {
+	bs := []*bench.B{
+		benchWithParams("pkg.SortBench", "size=1024", "threads=4"),
+		benchWithParams("pkg.SortBench", "size=64", "threads=1"),
+		benchWithParams("pkg.MapBench", "size=1024", "mode=fast"),
+	}
+
+	for _, c := range []struct {
+		expr     string
+		expected []bool
+	}{
+		{"Sort", []bool{true, true, false}},
+		{"^pkg\\.Map", []bool{false, false, true}},
+		{"size=1024", []bool{true, false, true}},
+		{"size = 1024.0", []bool{true, false, true}},
+		{"size!=1024", []bool{false, true, false}},
+		{"threads>=4", []bool{true, false, false}},
+		{"threads<4", []bool{false, true, false}},
+		{"size>100", []bool{true, false, true}},
+		{"size<=64", []bool{false, true, false}},
+		{"mode=fast", []bool{false, false, true}},
+		{"mode>f", []bool{false, false, true}},
+	} {
+		f, err := bench.ParseFilter(c.expr)
+		if err != nil {
+			t.Fatalf("Could not parse '%s': %v", c.expr, err)
+		}
+		for i, b := range bs {
+			if m := f(b); m != c.expected[i] {
+				t.Fatalf("Unexpected match of '%s' for %v: was %t, expected %t", c.expr, b, m, c.expected[i])
+			}
+		}
+	}
+}
+
+func TestParseFilterInvalid(t *testing.T) {
+	_, err := bench.ParseFilter("Sort(")
+	if err == nil {
+		t.Fatalf("Expected error for invalid regular expression")
+	}
+}
+
+func TestIncludeExcludeFilter(t *testing.T) {
+	parse := func(exprs ...string) []bench.Filter {
+		var fs []bench.Filter
+		for _, e := range exprs {
+			f, err := bench.ParseFilter(e)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fs = append(fs, f)
+		}
+		return fs
+	}
+
+	f := bench.IncludeExcludeFilter(parse("Sort", "size>=64"), parse("threads=1"))
+	for _, c := range []struct {
+		b        *bench.B
+		expected bool
+	}{
+		{benchWithParams("SortBench", "size=1024", "threads=4"), true},
+		{benchWithParams("SortBench", "size=1024", "threads=1"), false},
+		{benchWithParams("SortBench", "size=32", "threads=4"), false},
+		{benchWithParams("MapBench", "size=1024", "threads=4"), false},
+	} {
+		if m := f(c.b); m != c.expected {
+			t.Fatalf("Unexpected match for %v: was %t, expected %t", c.b, m, c.expected)
+		}
+	}
+
+	if !bench.IncludeExcludeFilter(nil, nil)(bench.New("b")) {
+		t.Fatalf("Expected empty filter to keep all benchmarks")
+	}
+}
+
+func TestFilterChan(t *testing.T) {
+	c := make(bench.Chan, 5)
+	c <- bench.ExecutionValue{Type: bench.ExecStart}
+	for _, name := range []string{"a1", "b1", "a2"} {
+		c <- bench.ExecutionValue{Type: bench.ExecNext, Exec: bench.NewExecution(bench.New(name))}
+	}
+	c <- bench.ExecutionValue{Type: bench.ExecEnd}
+	close(c)
+
+	f, err := bench.ParseFilter("^a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var types []bench.ExecutionType
+	var names []string
+	for ev := range bench.FilterChan(f, c) {
+		types = append(types, ev.Type)
+		if ev.Type == bench.ExecNext {
+			names = append(names, ev.Exec.Benchmark.Name)
+		}
+	}
+
+	if len(types) != 4 || types[0] != bench.ExecStart || types[3] != bench.ExecEnd {
+		t.Fatalf("Unexpected execution types: %v", types)
+	}
+	if strings.Join(names, ",") != "a1,a2" {
+		t.Fatalf("Unexpected benchmarks: %v", names)
+	}
+}
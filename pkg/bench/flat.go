@@ -0,0 +1,75 @@
+package bench
+
+import "fmt"
+
+// FlatExecution is a flattened representation of an Execution, where all hierarchy levels are stored in contiguous arrays.
+// Every level contains offsets into the next lower level, i.e., the trials of instance i are Trials[Instances[i]:Instances[i+1]],
+// the forks of trial t are Forks[Trials[t]:Trials[t+1]], the iterations of fork f are Iterations[Forks[f]:Forks[f+1]],
+// and the invocations of iteration it are Invocations[Iterations[it]:Iterations[it+1]].
+// Hence, every offset array has one element more than there are elements on its level.
+type FlatExecution struct {
+	Instances   []int
+	Trials      []int
+	Forks       []int
+	Iterations  []int
+	Invocations []Invocations
+}
+
+// NrInstances returns the number of instances
+func (fe *FlatExecution) NrInstances() int {
+	return len(fe.Instances) - 1
+}
+
+// NrIterations returns the number of iterations across all instances, trials, and forks
+func (fe *FlatExecution) NrIterations() int {
+	return len(fe.Iterations) - 1
+}
+
+// IterationInvocations returns the invocations of the iteration at (global) position it
+func (fe *FlatExecution) IterationInvocations(it int) []Invocations {
+	return fe.Invocations[fe.Iterations[it]:fe.Iterations[it+1]]
+}
+
+func (e *Execution) Flat() *FlatExecution {
+	fe := &FlatExecution{
+		Instances:  append(make([]int, 0, len(e.InstanceIDs)+1), 0),
+		Trials:     []int{0},
+		Forks:      []int{0},
+		Iterations: []int{0},
+	}
+
+	for _, iid := range e.InstanceIDs {
+		i, ok := e.Instances[iid]
+		if !ok {
+			panic(fmt.Sprintf("Invalid state: InstanceIDs and Instances out of sync for %s", iid))
+		}
+
+		for _, tid := range i.TrialIDs {
+			t, ok := i.Trials[tid]
+			if !ok {
+				panic(fmt.Sprintf("Invalid state: TrialIDs and Trials out of sync for %d", tid))
+			}
+
+			for _, fid := range t.ForkIDs {
+				f, ok := t.Forks[fid]
+				if !ok {
+					panic(fmt.Sprintf("Invalid state: ForkIDs and Forks out of sync for %d", fid))
+				}
+
+				for _, itid := range f.IterationIDs {
+					it, ok := f.Iterations[itid]
+					if !ok {
+						panic(fmt.Sprintf("Invalid state: IterationIDs and Iterations out of sync for %d", itid))
+					}
+					fe.Invocations = append(fe.Invocations, it.Invocations...)
+					fe.Iterations = append(fe.Iterations, len(fe.Invocations))
+				}
+				fe.Forks = append(fe.Forks, len(fe.Iterations)-1)
+			}
+			fe.Trials = append(fe.Trials, len(fe.Forks)-1)
+		}
+		fe.Instances = append(fe.Instances, len(fe.Trials)-1)
+	}
+
+	return fe
+}
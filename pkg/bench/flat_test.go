@@ -0,0 +1,69 @@
+package bench_test
+
+import (
+	"testing"
+
+	"github.com/chrstphlbr/pa/pkg/bench"
+)
+
+func TestFlatEmpty(t *testing.T) {
+	fe := bench.NewExecution(b).Flat()
+
+	if nr := fe.NrInstances(); nr != 0 {
+		t.Fatalf("Expected 0 instances, was %d", nr)
+	}
+	if nr := fe.NrIterations(); nr != 0 {
+		t.Fatalf("Expected 0 iterations, was %d", nr)
+	}
+	if l := len(fe.Invocations); l != 0 {
+		t.Fatalf("Expected 0 invocations, was %d", l)
+	}
+}
+
+func TestFlatComplex(t *testing.T) {
+	e := complexExecution(t)
+	fe := e.Flat()
+
+	if nr := fe.NrInstances(); nr != len(e.InstanceIDs) {
+		t.Fatalf("Expected %d instances, was %d", len(e.InstanceIDs), nr)
+	}
+
+	// walk the nested execution and check that the flat execution has the same structure and values
+	var tpos, fpos, itpos, invs int
+	for ipos, iid := range e.InstanceIDs {
+		instance := e.Instances[iid]
+		if ts, te := fe.Instances[ipos], fe.Instances[ipos+1]; te-ts != len(instance.TrialIDs) || ts != tpos {
+			t.Fatalf("Unexpected trial offsets for instance %s: [%d,%d)", iid, ts, te)
+		}
+
+		for _, tid := range instance.TrialIDs {
+			trial := instance.Trials[tid]
+			if fs, fe := fe.Trials[tpos], fe.Trials[tpos+1]; fe-fs != len(trial.ForkIDs) || fs != fpos {
+				t.Fatalf("Unexpected fork offsets for trial %d: [%d,%d)", tid, fs, fe)
+			}
+			tpos++
+
+			for _, fid := range trial.ForkIDs {
+				fork := trial.Forks[fid]
+				if its, ite := fe.Forks[fpos], fe.Forks[fpos+1]; ite-its != len(fork.IterationIDs) || its != itpos {
+					t.Fatalf("Unexpected iteration offsets for fork %d: [%d,%d)", fid, its, ite)
+				}
+				fpos++
+
+				for _, itid := range fork.IterationIDs {
+					ivs := fork.Iterations[itid].Invocations
+					equalInvocations(t, ivs, fe.IterationInvocations(itpos))
+					invs += len(ivs)
+					itpos++
+				}
+			}
+		}
+	}
+
+	if nr := fe.NrIterations(); nr != itpos {
+		t.Fatalf("Expected %d iterations, was %d", itpos, nr)
+	}
+	if l := len(fe.Invocations); l != invs {
+		t.Fatalf("Expected %d invocations, was %d", invs, l)
+	}
+}
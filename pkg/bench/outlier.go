@@ -0,0 +1,253 @@
+package bench
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// OutlierLevel defines on which values outliers are detected
+type OutlierLevel int
+
+const (
+	// InvocationLevel detects outliers among the invocations of a fork
+	InvocationLevel OutlierLevel = iota
+	// IterationLevel detects outliers among the iteration means of a fork
+	IterationLevel
+)
+
+func (l OutlierLevel) String() string {
+	switch l {
+	case InvocationLevel:
+		return "invocations"
+	case IterationLevel:
+		return "iterations"
+	}
+	return "INVALID_OUTLIER_LEVEL"
+}
+
+// madScale scales the median absolute deviation to a consistent estimator of the standard deviation for normal data
+const madScale = 1.4826
+
+// OutlierReport reports how many values (invocations or iterations) an outlier transformer removed or clamped for a benchmark
+type OutlierReport struct {
+	Method  string
+	Level   OutlierLevel
+	Values  int
+	Removed int
+	Clamped int
+}
+
+func (r OutlierReport) String() string {
+	return fmt.Sprintf("%s: removed %d and clamped %d of %d %s", r.Method, r.Removed, r.Clamped, r.Values, r.Level)
+}
+
+// fences computes the lower and upper bound of non-outlier values from the values of a fork
+type fences func(ivs []Invocations) (lower, upper float64)
+
+// TukeyExecutionTransformerFunc removes the values outside of Tukey's fences [Q1 - k*IQR, Q3 + k*IQR] of every fork (k = 1.5 is the common choice).
+// If the IQR of a fork is 0, none of its values are removed.
+// Invocations are weighted by their count; iterations that lose all their invocations are removed.
+func TukeyExecutionTransformerFunc(level OutlierLevel, k float64, report Reporter) ExecutionTransformerFunc {
+	return outlierExecutionTransformerFunc(level, fmt.Sprintf("Tukey(%g)", k), false, report, func(ivs []Invocations) (float64, float64) {
+		q1, q3 := quantile(ivs, 0.25), quantile(ivs, 0.75)
+		iqr := q3 - q1
+		if iqr == 0 {
+			return math.Inf(-1), math.Inf(1)
+		}
+		return q1 - k*iqr, q3 + k*iqr
+	})
+}
+
+// MADExecutionTransformerFunc removes the values that deviate more than k scaled median absolute deviations (MAD * 1.4826) from the median of every fork (k = 3 is a common choice).
+// If the MAD of a fork is 0 (e.g., due to timer resolution), none of its values are removed.
+// Invocations are weighted by their count; iterations that lose all their invocations are removed.
+func MADExecutionTransformerFunc(level OutlierLevel, k float64, report Reporter) ExecutionTransformerFunc {
+	return outlierExecutionTransformerFunc(level, fmt.Sprintf("MAD(%g)", k), false, report, func(ivs []Invocations) (float64, float64) {
+		median := quantile(ivs, 0.5)
+		deviations := make([]Invocations, len(ivs))
+		for i, iv := range ivs {
+			deviations[i] = Invocations{Count: iv.Count, Value: math.Abs(iv.Value - median)}
+		}
+		mad := quantile(deviations, 0.5) * madScale
+		if mad == 0 {
+			return math.Inf(-1), math.Inf(1)
+		}
+		return median - k*mad, median + k*mad
+	})
+}
+
+// WinsorizeExecutionTransformerFunc clamps the values of every fork below the lower percentile to the lower percentile and the ones above the upper percentile to the upper percentile
+// (e.g., 0.01 and 0.99).
+// On the iteration level, the invocations of a clamped iteration are scaled such that the iteration's mean is the clamped value.
+func WinsorizeExecutionTransformerFunc(level OutlierLevel, lower, upper float64, report Reporter) ExecutionTransformerFunc {
+	return outlierExecutionTransformerFunc(level, fmt.Sprintf("Winsorize(%g,%g)", lower, upper), true, report, func(ivs []Invocations) (float64, float64) {
+		return quantile(ivs, lower), quantile(ivs, upper)
+	})
+}
+
+func outlierExecutionTransformerFunc(level OutlierLevel, method string, clamp bool, report Reporter, f fences) ExecutionTransformerFunc {
+	return func(e *Execution) *Execution {
+		ne := e.Copy()
+
+		r := OutlierReport{
+			Method: method,
+			Level:  level,
+		}
+		for _, instance := range ne.Instances {
+			for _, trial := range instance.Trials {
+				for _, fork := range trial.Forks {
+					var removed int
+					switch level {
+					case InvocationLevel:
+						removed = fork.invocationOutliers(f, clamp, &r)
+					case IterationLevel:
+						removed = fork.iterationOutliers(f, clamp, &r)
+					default:
+						panic(fmt.Sprintf("Invalid outlier level: %d", level))
+					}
+					ne.addLen(-removed)
+				}
+			}
+		}
+		ne.removeEmpty()
+
+		if report != nil {
+			report(ne.Benchmark, r)
+		}
+		return ne
+	}
+}
+
+// invocationOutliers removes or clamps the outlier invocations of a fork, counts them in r, and returns the number of removed invocations
+func (f *Fork) invocationOutliers(fs fences, clamp bool, r *OutlierReport) int {
+	var all []Invocations
+	for _, itid := range f.IterationIDs {
+		all = append(all, f.Iterations[itid].Invocations...)
+	}
+	for _, iv := range all {
+		r.Values += iv.Count
+	}
+	if len(all) == 0 {
+		return 0
+	}
+	lower, upper := fs(all)
+
+	var removed int
+	itids := f.IterationIDs[:0]
+	for _, itid := range f.IterationIDs {
+		it := f.Iterations[itid]
+		ivs := it.Invocations[:0]
+		for _, iv := range it.Invocations {
+			if iv.Value >= lower && iv.Value <= upper {
+				ivs = append(ivs, iv)
+				continue
+			}
+			if clamp {
+				iv.Value = math.Max(lower, math.Min(upper, iv.Value))
+				r.Clamped += iv.Count
+				ivs = append(ivs, iv)
+				continue
+			}
+			r.Removed += iv.Count
+			removed += iv.Count
+		}
+		it.Invocations = ivs
+
+		if len(it.Invocations) == 0 {
+			delete(f.Iterations, itid)
+			continue
+		}
+		itids = append(itids, itid)
+	}
+	f.IterationIDs = itids
+	return removed
+}
+
+// iterationOutliers removes or clamps the outlier iterations (based on their means) of a fork, counts them in r, and returns the number of removed invocations
+func (f *Fork) iterationOutliers(fs fences, clamp bool, r *OutlierReport) int {
+	if len(f.IterationIDs) == 0 {
+		return 0
+	}
+	means := make([]Invocations, len(f.IterationIDs))
+	for i, itid := range f.IterationIDs {
+		means[i] = Invocations{Count: 1, Value: MeanInvocations(f.Iterations[itid].Invocations)[0]}
+	}
+	r.Values += len(means)
+	lower, upper := fs(means)
+
+	var removed int
+	itids := f.IterationIDs[:0]
+	for i, itid := range f.IterationIDs {
+		it := f.Iterations[itid]
+		mean := means[i].Value
+		if mean >= lower && mean <= upper {
+			itids = append(itids, itid)
+			continue
+		}
+		if clamp {
+			if mean != 0 {
+				factor := math.Max(lower, math.Min(upper, mean)) / mean
+				for j := range it.Invocations {
+					it.Invocations[j].Value *= factor
+				}
+			}
+			r.Clamped++
+			itids = append(itids, itid)
+			continue
+		}
+		r.Removed++
+		for _, iv := range it.Invocations {
+			removed += iv.Count
+		}
+		delete(f.Iterations, itid)
+	}
+	f.IterationIDs = itids
+	return removed
+}
+
+// quantile returns the p-quantile of the invocations, where every invocation value is weighted by its count.
+// It interpolates linearly between the closest ranks (like the default of R and NumPy).
+func quantile(ivs []Invocations, p float64) float64 {
+	sorted := make([]Invocations, 0, len(ivs))
+	var n int
+	for _, iv := range ivs {
+		if iv.Count <= 0 {
+			continue
+		}
+		sorted = append(sorted, iv)
+		n += iv.Count
+	}
+	if n == 0 {
+		return 0
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Value < sorted[j].Value
+	})
+
+	// value at rank j (0-based) of the expanded invocations
+	at := func(j int) float64 {
+		var cum int
+		for _, iv := range sorted {
+			cum += iv.Count
+			if j < cum {
+				return iv.Value
+			}
+		}
+		return sorted[len(sorted)-1].Value
+	}
+
+	if p <= 0 {
+		return sorted[0].Value
+	} else if p >= 1 {
+		return sorted[len(sorted)-1].Value
+	}
+
+	h := p * float64(n-1)
+	lower := math.Floor(h)
+	lv := at(int(lower))
+	if h == lower {
+		return lv
+	}
+	return lv + (h-lower)*(at(int(lower)+1)-lv)
+}
@@ -0,0 +1,196 @@
+package bench_test
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/chrstphlbr/pa/pkg/bench"
+)
+
+// forkExecution creates an execution of a single fork with the invocations per iteration
+func forkExecution(t *testing.T, iterations [][]bench.Invocations) *bench.Execution {
+	b := bench.New("b1")
+	e := bench.NewExecution(b)
+	for i, ivs := range iterations {
+		for _, iv := range ivs {
+			addInvocationsHelper(t, e, []bench.InvocationsFlat{{
+				Benchmark:   b,
+				Instance:    "i1",
+				Trial:       1,
+				Fork:        1,
+				Iteration:   i + 1,
+				Invocations: iv,
+			}})
+		}
+	}
+	return e
+}
+
+func fork(e *bench.Execution) *bench.Fork {
+	return e.Instances["i1"].Trials[1].Forks[1]
+}
+
+// spikedIterations creates 10 iterations with 10 invocations around 100 each, where the last invocation of iteration 5 is a spike of 1000
+func spikedIterations() [][]bench.Invocations {
+	var its [][]bench.Invocations
+	for i := 0; i < 10; i++ {
+		var ivs []bench.Invocations
+		for j := 0; j < 10; j++ {
+			ivs = append(ivs, bench.Invocations{Count: 1, Value: float64(98 + (i+j)%5)})
+		}
+		its = append(its, ivs)
+	}
+	its[4][9].Value = 1000
+	return its
+}
+
+func reportCollector(reports *[]fmt.Stringer) bench.Reporter {
+	return func(b *bench.B, r fmt.Stringer) {
+		*reports = append(*reports, r)
+	}
+}
+
+func TestTukeyExecutionTransformerFunc(t *testing.T) {
+	e := forkExecution(t, spikedIterations())
+
+	var reports []fmt.Stringer
+	te := bench.TukeyExecutionTransformerFunc(bench.InvocationLevel, 1.5, reportCollector(&reports))(e)
+
+	if l := len(fork(te).Iterations[5].Invocations); l != 9 {
+		t.Fatalf("Expected spike to be removed, iteration has %d invocations", l)
+	}
+	for _, itid := range fork(te).IterationIDs {
+		for _, iv := range fork(te).Iterations[itid].Invocations {
+			if iv.Value == 1000 {
+				t.Fatalf("Spike not removed from iteration %d", itid)
+			}
+		}
+	}
+	// original execution is unaltered
+	if l := len(fork(e).Iterations[5].Invocations); l != 10 {
+		t.Fatalf("Original execution altered, iteration has %d invocations", l)
+	}
+
+	expected := bench.OutlierReport{Method: "Tukey(1.5)", Level: bench.InvocationLevel, Values: 100, Removed: 1}
+	if len(reports) != 1 || !reflect.DeepEqual(reports[0], expected) {
+		t.Fatalf("Unexpected reports: was %v, expected [%v]", reports, expected)
+	}
+}
+
+func TestTukeyExecutionTransformerFuncCounts(t *testing.T) {
+	// counts weigh invocations: 100 invocations of 10 and a single one of 20
+	e := forkExecution(t, [][]bench.Invocations{
+		{{Count: 50, Value: 10}, {Count: 1, Value: 20}},
+		{{Count: 25, Value: 9}, {Count: 25, Value: 11}},
+	})
+
+	var reports []fmt.Stringer
+	te := bench.TukeyExecutionTransformerFunc(bench.InvocationLevel, 1.5, reportCollector(&reports))(e)
+
+	if ivs := fork(te).Iterations[1].Invocations; !reflect.DeepEqual(ivs, []bench.Invocations{{Count: 50, Value: 10}}) {
+		t.Fatalf("Unexpected invocations of iteration 1: %v", ivs)
+	}
+	expected := bench.OutlierReport{Method: "Tukey(1.5)", Level: bench.InvocationLevel, Values: 101, Removed: 1}
+	if !reflect.DeepEqual(reports[0], expected) {
+		t.Fatalf("Unexpected report: was %v, expected %v", reports[0], expected)
+	}
+}
+
+func TestMADExecutionTransformerFuncIterations(t *testing.T) {
+	its := spikedIterations()
+	for i := range its {
+		for j := range its[i] {
+			// iteration means vary between 100 and 102
+			its[i][j].Value += float64(i % 3)
+			// iteration 8 is slow overall
+			if i == 7 {
+				its[i][j].Value *= 3
+			}
+		}
+	}
+	e := forkExecution(t, its)
+
+	var reports []fmt.Stringer
+	te := bench.MADExecutionTransformerFunc(bench.IterationLevel, 3, reportCollector(&reports))(e)
+
+	// iteration 5 (mean 191) and 8 (mean 303) are removed
+	checkIterationIDs(t, te, []int{1, 2, 3, 4, 6, 7, 9, 10})
+	if nr := te.Flat().NrIterations(); nr != 8 {
+		t.Fatalf("Unexpected number of iterations: %d", nr)
+	}
+
+	expected := bench.OutlierReport{Method: "MAD(3)", Level: bench.IterationLevel, Values: 10, Removed: 2}
+	if !reflect.DeepEqual(reports[0], expected) {
+		t.Fatalf("Unexpected report: was %v, expected %v", reports[0], expected)
+	}
+}
+
+func TestMADExecutionTransformerFuncNoDeviation(t *testing.T) {
+	// more than half of the invocations are equal, i.e., MAD is 0
+	e := forkExecution(t, [][]bench.Invocations{
+		{{Count: 10, Value: 5}, {Count: 1, Value: 6}, {Count: 1, Value: 100}},
+	})
+	te := bench.MADExecutionTransformerFunc(bench.InvocationLevel, 3, nil)(e)
+
+	equalInstances(t, e, te, true)
+}
+
+func TestWinsorizeExecutionTransformerFunc(t *testing.T) {
+	var ivs []bench.Invocations
+	for i := 1; i <= 100; i++ {
+		ivs = append(ivs, bench.Invocations{Count: 1, Value: float64(i)})
+	}
+	e := forkExecution(t, [][]bench.Invocations{ivs})
+
+	var reports []fmt.Stringer
+	te := bench.WinsorizeExecutionTransformerFunc(bench.InvocationLevel, 0.05, 0.95, reportCollector(&reports))(e)
+
+	tivs := fork(te).Iterations[1].Invocations
+	if len(tivs) != 100 {
+		t.Fatalf("Expected no invocation to be removed, has %d", len(tivs))
+	}
+	// quantiles interpolate between ranks: 1 + 0.05*99 and 1 + 0.95*99
+	lower, upper := 5.95, 95.05
+	for i, iv := range tivs {
+		expected := math.Max(lower, math.Min(upper, float64(i+1)))
+		if math.Abs(iv.Value-expected) > 1e-9 {
+			t.Fatalf("Unexpected value at %d: was %f, expected %f", i, iv.Value, expected)
+		}
+	}
+
+	expected := bench.OutlierReport{Method: "Winsorize(0.05,0.95)", Level: bench.InvocationLevel, Values: 100, Clamped: 10}
+	if !reflect.DeepEqual(reports[0], expected) {
+		t.Fatalf("Unexpected report: was %v, expected %v", reports[0], expected)
+	}
+}
+
+func TestWinsorizeExecutionTransformerFuncIterations(t *testing.T) {
+	var its [][]bench.Invocations
+	for i := 1; i <= 11; i++ {
+		its = append(its, []bench.Invocations{{Count: 1, Value: float64(i)}, {Count: 1, Value: float64(3 * i)}})
+	}
+	e := forkExecution(t, its)
+
+	var reports []fmt.Stringer
+	te := bench.WinsorizeExecutionTransformerFunc(bench.IterationLevel, 0.1, 0.9, reportCollector(&reports))(e)
+
+	// iteration means are 2*i, clamped to [4, 20]
+	f := fork(te)
+	checkIterationIDs(t, te, []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11})
+	for itid, expected := range map[int][]bench.Invocations{
+		1:  {{Count: 1, Value: 2}, {Count: 1, Value: 6}},
+		6:  {{Count: 1, Value: 6}, {Count: 1, Value: 18}},
+		11: {{Count: 1, Value: 10}, {Count: 1, Value: 30}},
+	} {
+		if ivs := f.Iterations[itid].Invocations; !reflect.DeepEqual(ivs, expected) {
+			t.Fatalf("Unexpected invocations of iteration %d: was %v, expected %v", itid, ivs, expected)
+		}
+	}
+
+	expected := bench.OutlierReport{Method: "Winsorize(0.1,0.9)", Level: bench.IterationLevel, Values: 11, Clamped: 2}
+	if !reflect.DeepEqual(reports[0], expected) {
+		t.Fatalf("Unexpected report: was %v, expected %v", reports[0], expected)
+	}
+}
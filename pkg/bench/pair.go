@@ -0,0 +1,172 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+)
+
+// ExecutionPair holds the executions of the same benchmark of two versions A and B.
+// If only one version has the benchmark, the other execution is nil.
+// If a version sent an error, Err is set and both executions are nil.
+type ExecutionPair struct {
+	A   *Execution
+	B   *Execution
+	Err error
+}
+
+func PairChans(c1, c2 Chan) <-chan ExecutionPair {
+	return PairChansContext(context.Background(), c1, c2)
+}
+
+// PairChansContext pairs the executions of the same benchmarks of c1 (version A) and c2 (version B), which are both ordered by benchmark.
+// If a channel is closed after its start but before its end (i.e., truncated, e.g., because its input was cancelled), the remaining benchmarks of the other channel cannot be paired and pairing stops.
+// If ctx is done, pairing stops and the returned channel is closed.
+func PairChansContext(ctx context.Context, c1, c2 Chan) <-chan ExecutionPair {
+	out := make(chan ExecutionPair)
+
+	go func() {
+		defer close(out)
+		emit := func(p ExecutionPair) bool {
+			select {
+			case out <- p:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		// a value read from one channel that is paired with the next value of the other channel (needed if the channels do not have the same benchmarks)
+		var leftOver *ExecutionValue
+		var leftOverA bool
+		var started1, started2, ended1, ended2 bool
+		for {
+			var ev1, ev2 ExecutionValue
+			var ok1, ok2 bool
+
+			if leftOver != nil {
+				if leftOverA {
+					ev1, ok1 = *leftOver, true
+					ev2, ok2 = receive(ctx, c2)
+				} else {
+					ev2, ok2 = *leftOver, true
+					ev1, ok1 = receive(ctx, c1)
+				}
+				leftOver = nil
+			} else {
+				ev1, ok1 = receive(ctx, c1)
+				ev2, ok2 = receive(ctx, c2)
+			}
+
+			if ctx.Err() != nil {
+				return
+			}
+
+			started1, ended1 = streamState(ev1, ok1, started1, ended1)
+			started2, ended2 = streamState(ev2, ok2, started2, ended2)
+			if (!ok1 && started1 && !ended1) || (!ok2 && started2 && !ended2) {
+				return
+			}
+
+			var ok bool
+			switch {
+			case ok1 && ok2:
+				leftOver, leftOverA, ok = pairTwo(emit, ev1, ev2)
+			case ok1:
+				ok = pairSingle(emit, ev1, true)
+			case ok2:
+				ok = pairSingle(emit, ev2, false)
+			default:
+				return
+			}
+			if !ok {
+				return
+			}
+		}
+	}()
+
+	return out
+}
+
+// receive receives the next value from c, unless ctx is done or c is closed
+func receive(ctx context.Context, c Chan) (ExecutionValue, bool) {
+	select {
+	case ev, ok := <-c:
+		return ev, ok
+	case <-ctx.Done():
+		return ExecutionValue{}, false
+	}
+}
+
+// streamState updates whether a channel has started and ended with a received value
+func streamState(ev ExecutionValue, ok, started, ended bool) (bool, bool) {
+	if !ok {
+		return started, ended
+	}
+	switch ev.Type {
+	case ExecStart:
+		started = true
+	case ExecEnd:
+		ended = true
+	}
+	return started, ended
+}
+
+// pairSingle emits a value of only one version (A if a is true)
+func pairSingle(emit func(ExecutionPair) bool, ev ExecutionValue, a bool) bool {
+	switch ev.Type {
+	case ExecError:
+		return emit(ExecutionPair{
+			Err: ev.Err,
+		})
+	case ExecNext:
+		if a {
+			return emit(ExecutionPair{
+				A: ev.Exec,
+			})
+		}
+		return emit(ExecutionPair{
+			B: ev.Exec,
+		})
+	}
+	return true
+}
+
+// pairTwo emits the values of both versions, either paired or, if their benchmarks differ, the smaller one alone, in which case the other one is returned as left over
+func pairTwo(emit func(ExecutionPair) bool, ev1, ev2 ExecutionValue) (leftOver *ExecutionValue, leftOverA bool, ok bool) {
+	switch {
+	case ev1.Type == ExecError:
+		if !emit(ExecutionPair{Err: ev1.Err}) {
+			return nil, false, false
+		}
+		return nil, false, pairSingle(emit, ev2, false)
+	case ev2.Type == ExecError:
+		if !emit(ExecutionPair{Err: ev2.Err}) {
+			return nil, false, false
+		}
+		return nil, false, pairSingle(emit, ev1, true)
+	case ev1.Type != ExecNext:
+		// channel 1 started or ended
+		return nil, false, pairSingle(emit, ev2, false)
+	case ev2.Type != ExecNext:
+		// channel 2 started or ended
+		return nil, false, pairSingle(emit, ev1, true)
+	}
+
+	switch cmp := ev1.Exec.Benchmark.Compare(ev2.Exec.Benchmark); cmp {
+	case 0:
+		return nil, false, emit(ExecutionPair{
+			A: ev1.Exec,
+			B: ev2.Exec,
+		})
+	case -1:
+		return &ev2, false, emit(ExecutionPair{
+			A: ev1.Exec,
+		})
+	case 1:
+		return &ev1, true, emit(ExecutionPair{
+			B: ev2.Exec,
+		})
+	default:
+		panic(fmt.Sprintf("Invalid result from Compare: %d", cmp))
+	}
+}
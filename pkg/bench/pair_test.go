@@ -0,0 +1,65 @@
+package bench_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/chrstphlbr/pa/pkg/bench"
+)
+
+func pairInput(start, end bool, values ...interface{}) bench.Chan {
+	c := make(bench.Chan, len(values)+2)
+	if start {
+		c <- bench.ExecutionValue{Type: bench.ExecStart}
+	}
+	for _, v := range values {
+		switch v := v.(type) {
+		case string:
+			c <- bench.ExecutionValue{Type: bench.ExecNext, Exec: bench.NewExecution(bench.New(v))}
+		case error:
+			c <- bench.ExecutionValue{Type: bench.ExecError, Err: v}
+		}
+	}
+	if end {
+		c <- bench.ExecutionValue{Type: bench.ExecEnd}
+	}
+	close(c)
+	return c
+}
+
+func pairString(p bench.ExecutionPair) string {
+	if p.Err != nil {
+		return "err"
+	}
+	a, b := "-", "-"
+	if p.A != nil {
+		a = p.A.Benchmark.Name
+	}
+	if p.B != nil {
+		b = p.B.Benchmark.Name
+	}
+	return a + "/" + b
+}
+
+func TestPairChans(t *testing.T) {
+	for _, c := range []struct {
+		c1, c2   bench.Chan
+		expected string
+	}{
+		{pairInput(true, true, "a", "b", "c"), pairInput(true, true, "a", "b", "c"), "a/a,b/b,c/c"},
+		{pairInput(true, true, "a", "c", "d"), pairInput(true, true, "b", "c"), "a/-,-/b,c/c,d/-"},
+		{pairInput(true, true), pairInput(true, true, "a"), "-/a"},
+		{pairInput(true, true, "a", errors.New("e"), "c"), pairInput(true, true, "a", "b", "c"), "a/a,err,-/b,c/c"},
+		// truncated c1: remaining benchmarks of c2 cannot be paired
+		{pairInput(true, false, "a"), pairInput(true, true, "a", "b"), "a/a"},
+	} {
+		var ps []string
+		for p := range bench.PairChans(c.c1, c.c2) {
+			ps = append(ps, pairString(p))
+		}
+		if s := strings.Join(ps, ","); s != c.expected {
+			t.Fatalf("Unexpected pairs: was %s, expected %s", s, c.expected)
+		}
+	}
+}
@@ -0,0 +1,482 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/xitongsys/parquet-go/common"
+	"github.com/xitongsys/parquet-go/reader"
+	"github.com/xitongsys/parquet-go/source"
+)
+
+// ParquetMagic are the first (and last) bytes of a Parquet file
+const ParquetMagic = "PAR1"
+
+// parquetColumns are the columns read from Parquet files, i.e., the columns of the CSV format (see FromCSV) without 'mode' and 'unit', where 'project' and 'commit' are optional (see parquetOptionalColumns) and only read per commit
+var parquetColumns = []string{"benchmark", "params", "instance", "trial", "fork", "iteration", "value_count", "value", "project", "commit"}
+
+// parquetOptionalColumns are the columns of parquetColumns that are empty if the file does not have them
+var parquetOptionalColumns = map[string]bool{
+	"project": true,
+	"commit":  true,
+}
+
+const (
+	pqBenchmark = iota
+	pqParams
+	pqInstance
+	pqTrial
+	pqFork
+	pqIteration
+	pqValueCount
+	pqValue
+	pqProject
+	pqCommit
+)
+
+// FromParquet reads executions from a Parquet file of the given size without reducing invocations (see FromParquetWithReduction)
+func FromParquet(ctx context.Context, r io.ReaderAt, size int64) (Chan, error) {
+	return FromParquetWithReduction(ctx, r, size, Reduction{})
+}
+
+// FromParquetWithReduction reads executions from a Parquet file of size bytes with the columns of the CSV format (see FromCSV), where other columns are ignored.
+// The file is read column-wise per row group, and, in contrast to CSV, the rows do not have to be sorted:
+// if the rows of every benchmark are consecutive and the benchmarks in benchmark order (see B.Compare), every execution is sent as soon as its last row is read;
+// otherwise, the rows are grouped by benchmark and sent in benchmark order after all row groups are read, i.e., all executions are held in memory.
+// Whether the rows are sorted is checked by reading the columns 'benchmark' and 'params' before the executions.
+// The invocations of every iteration are reduced according to `reduction` while reading, which bounds the memory required for every execution.
+// An invalid file (e.g., missing columns) returns an error, and invalid rows are sent as error, after which the channel is closed without ExecEnd.
+func FromParquetWithReduction(ctx context.Context, r io.ReaderAt, size int64, reduction Reduction) (Chan, error) {
+	return fromParquet(ctx, r, size, reduction, false)
+}
+
+// FromParquetPerCommit reads executions from a Parquet file like FromParquetWithReduction, but with one execution per benchmark and commit (columns 'project' and 'commit', see FromCSVPerCommit), where the commits of a benchmark are in the order of their first rows
+func FromParquetPerCommit(ctx context.Context, r io.ReaderAt, size int64, reduction Reduction) (Chan, error) {
+	return fromParquet(ctx, r, size, reduction, true)
+}
+
+func fromParquet(ctx context.Context, r io.ReaderAt, size int64, reduction Reduction, perCommit bool) (Chan, error) {
+	pr, cols, err := newParquetReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	c := make(Chan)
+	go func() {
+		defer close(c)
+
+		if !send(ctx, c, ExecutionValue{Type: ExecStart}) {
+			return
+		}
+
+		sorted, err := parquetSorted(ctx, r, size, perCommit)
+		if err == nil {
+			if sorted {
+				err = streamParquet(ctx, c, pr, cols, reduction, perCommit)
+			} else {
+				err = bufferParquet(ctx, c, pr, cols, reduction, perCommit)
+			}
+		}
+		if err != nil {
+			send(ctx, c, ExecutionValue{
+				Type: ExecError,
+				Err:  fmt.Errorf("could not read Parquet executions: %w", err),
+			})
+			return
+		}
+		send(ctx, c, ExecutionValue{Type: ExecEnd})
+	}()
+
+	return c, nil
+}
+
+// newParquetReader reads the footer of the Parquet file and returns the column indices of parquetColumns, which are -1 for missing optional columns
+func newParquetReader(r io.ReaderAt, size int64) (pr *reader.ParquetReader, cols []int64, err error) {
+	// the reader panics on some malformed files
+	defer func() {
+		if rec := recover(); rec != nil {
+			pr, cols, err = nil, nil, fmt.Errorf("invalid Parquet file: %v", rec)
+		}
+	}()
+
+	pr, err = reader.NewParquetColumnReader(newParquetFile(r, size), 1)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid Parquet file: %w", err)
+	}
+
+	sh := pr.SchemaHandler
+	indices := make(map[string]int64)
+	for i, in := range sh.ValueColumns {
+		path := common.StrToPath(sh.InPathToExPath[in])
+		// only top-level columns, i.e., root and column
+		if len(path) == 2 {
+			indices[strings.ToLower(path[1])] = int64(i)
+		}
+	}
+
+	cols = make([]int64, len(parquetColumns))
+	var missing []string
+	for i, name := range parquetColumns {
+		idx, ok := indices[name]
+		if !ok {
+			if parquetOptionalColumns[name] {
+				idx = -1
+			} else {
+				missing = append(missing, name)
+			}
+		}
+		cols[i] = idx
+	}
+	if len(missing) > 0 {
+		return nil, nil, fmt.Errorf("Parquet file has no columns %s", strings.Join(missing, ", "))
+	}
+	return pr, cols, nil
+}
+
+// readParquetRows calls row for every row of the file, where values holds the values of the row group per column of parquetColumns and only the columns for which read is true are read (others are nil)
+func readParquetRows(ctx context.Context, pr *reader.ParquetReader, cols []int64, read func(col int) bool, row func(values [][]interface{}, i int64) error) (err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("invalid Parquet file: %v", rec)
+		}
+	}()
+
+	var start int64
+	for _, rg := range pr.Footer.GetRowGroups() {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		rows := rg.GetNumRows()
+		values := make([][]interface{}, len(cols))
+		for i, idx := range cols {
+			if idx == -1 || !read(i) {
+				continue
+			}
+			vs, _, _, err := pr.ReadColumnByIndex(idx, rows)
+			if err != nil {
+				return err
+			}
+			if int64(len(vs)) != rows {
+				return fmt.Errorf("column '%s' has %d of %d values in row group starting at row %d", parquetColumns[i], len(vs), rows, start)
+			}
+			values[i] = vs
+		}
+
+		for j := int64(0); j < rows; j++ {
+			err := row(values, j)
+			if err != nil {
+				return fmt.Errorf("row %d: %w", start+j, err)
+			}
+		}
+		start += rows
+	}
+	return nil
+}
+
+// parquetKeyColumns returns whether a column identifies the execution of a row, i.e., its benchmark and, if per commit, its project and commit
+func parquetKeyColumns(perCommit bool) func(col int) bool {
+	return func(col int) bool {
+		return col == pqBenchmark || col == pqParams || (perCommit && (col == pqProject || col == pqCommit))
+	}
+}
+
+// parquetSorted returns whether the rows of every execution are consecutive and the executions in benchmark order, i.e., whether every execution can be sent as soon as its last row is read
+func parquetSorted(ctx context.Context, r io.ReaderAt, size int64, perCommit bool) (bool, error) {
+	pr, cols, err := newParquetReader(r, size)
+	if err != nil {
+		return false, err
+	}
+
+	pbs := make(parquetBenchmarks)
+	// keys of the previous executions
+	seen := make(map[string]bool)
+	var prev *parquetBenchmark
+	var prevKey string
+	sorted := true
+	err = readParquetRows(ctx, pr, cols, parquetKeyColumns(perCommit), func(values [][]interface{}, i int64) error {
+		strs, err := parquetStrings(values, i)
+		if err != nil {
+			return err
+		}
+		pb := pbs.get(strs[pqBenchmark], strs[pqParams])
+		key := pb.executionKey(strs, perCommit)
+		if prev != nil && key == prevKey {
+			return nil
+		}
+		if seen[key] || (prev != nil && pb.b.Compare(prev.b) < 0) {
+			sorted = false
+			return errParquetUnsorted
+		}
+		seen[key] = true
+		prev, prevKey = pb, key
+		return nil
+	})
+	if !sorted {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// errParquetUnsorted stops reading the rows in parquetSorted
+var errParquetUnsorted = fmt.Errorf("rows are not sorted by benchmark")
+
+// parquetBenchmarks caches the benchmarks of the rows by their columns 'benchmark' and 'params'
+type parquetBenchmarks map[[2]string]*parquetBenchmark
+
+// parquetBenchmark is a benchmark and its key, which is the same for all params strings of the benchmark, e.g., 'a=1,b=2' and 'b=2,a=1'
+type parquetBenchmark struct {
+	b   *B
+	key string
+}
+
+func (pbs parquetBenchmarks) get(name, params string) *parquetBenchmark {
+	raw := [2]string{name, params}
+	pb, ok := pbs[raw]
+	if !ok {
+		b := csvBenchmark(name, params)
+		pb = &parquetBenchmark{
+			b:   b,
+			key: b.String(),
+		}
+		pbs[raw] = pb
+	}
+	return pb
+}
+
+// executionKey returns the key of the execution of a row with the strings strs (see parquetStrings), which includes the project and commit if per commit
+func (pb *parquetBenchmark) executionKey(strs []string, perCommit bool) string {
+	if !perCommit {
+		return pb.key
+	}
+	return pb.key + "\x00" + strs[pqProject] + "\x00" + strs[pqCommit]
+}
+
+// parquetExecution builds the execution of a benchmark (and commit if per commit)
+type parquetExecution struct {
+	eb              executionBuilder
+	project, commit string
+}
+
+func newParquetExecution(ivf InvocationsFlat, reduction Reduction, perCommit bool) *parquetExecution {
+	pe := &parquetExecution{
+		// the benchmark is shared by all rows with the same columns 'benchmark' and 'params'
+		eb: newExecutionBuilder(ivf.Benchmark.Copy(), reduction),
+	}
+	if perCommit {
+		pe.project, pe.commit = ivf.Project, ivf.Commit
+	}
+	return pe
+}
+
+func (pe *parquetExecution) execution() (*Execution, error) {
+	e, err := pe.eb.execution()
+	if err != nil {
+		return nil, err
+	}
+	e.Project = pe.project
+	e.Commit = pe.commit
+	return e, nil
+}
+
+// streamParquet sends every execution as soon as the row of another execution is read, which requires sorted rows (see parquetSorted)
+func streamParquet(ctx context.Context, c Chan, pr *reader.ParquetReader, cols []int64, reduction Reduction, perCommit bool) error {
+	pbs := make(parquetBenchmarks)
+	var pe *parquetExecution
+	var key string
+	sendExecution := func() error {
+		e, err := pe.execution()
+		if err != nil {
+			return err
+		}
+		if !send(ctx, c, ExecutionValue{Type: ExecNext, Exec: e}) {
+			return ctx.Err()
+		}
+		return nil
+	}
+
+	err := readParquetRows(ctx, pr, cols, parquetReadColumns(perCommit), func(values [][]interface{}, i int64) error {
+		ivf, k, err := parquetInvocations(values, i, pbs, perCommit)
+		if err != nil {
+			return err
+		}
+		if pe != nil && k != key {
+			err := sendExecution()
+			if err != nil {
+				return err
+			}
+			pe = nil
+		}
+		if pe == nil {
+			pe = newParquetExecution(ivf, reduction, perCommit)
+			key = k
+		}
+		return pe.eb.add(ivf)
+	})
+	if err != nil {
+		return err
+	}
+	if pe != nil {
+		return sendExecution()
+	}
+	return nil
+}
+
+// bufferParquet groups the rows of all row groups by execution and sends the executions in benchmark order, where the executions of a benchmark are in the order of their first rows
+func bufferParquet(ctx context.Context, c Chan, pr *reader.ParquetReader, cols []int64, reduction Reduction, perCommit bool) error {
+	pbs := make(parquetBenchmarks)
+	pes := make(map[string]*parquetExecution)
+	// keys of pes in the order of their first rows
+	var keys []string
+	err := readParquetRows(ctx, pr, cols, parquetReadColumns(perCommit), func(values [][]interface{}, i int64) error {
+		ivf, key, err := parquetInvocations(values, i, pbs, perCommit)
+		if err != nil {
+			return err
+		}
+		pe, ok := pes[key]
+		if !ok {
+			pe = newParquetExecution(ivf, reduction, perCommit)
+			pes[key] = pe
+			keys = append(keys, key)
+		}
+		return pe.eb.add(ivf)
+	})
+	if err != nil {
+		return err
+	}
+
+	execs := make([]*Execution, 0, len(pes))
+	for _, key := range keys {
+		e, err := pes[key].execution()
+		if err != nil {
+			return err
+		}
+		execs = append(execs, e)
+	}
+	sort.SliceStable(execs, func(i, j int) bool {
+		return execs[i].Benchmark.Compare(execs[j].Benchmark) < 0
+	})
+
+	for _, e := range execs {
+		if !send(ctx, c, ExecutionValue{Type: ExecNext, Exec: e}) {
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// parquetReadColumns returns whether a column is read for the executions, i.e., all columns except 'project' and 'commit' if not per commit
+func parquetReadColumns(perCommit bool) func(col int) bool {
+	return func(col int) bool {
+		return perCommit || (col != pqProject && col != pqCommit)
+	}
+}
+
+// parquetStrings returns the string columns of row, which are empty for columns without values
+func parquetStrings(values [][]interface{}, row int64) ([]string, error) {
+	strs := make([]string, len(parquetColumns))
+	for _, i := range []int{pqBenchmark, pqParams, pqInstance, pqProject, pqCommit} {
+		if values[i] == nil {
+			continue
+		}
+		switch v := values[i][row].(type) {
+		case string:
+			strs[i] = v
+		case []byte:
+			strs[i] = string(v)
+		case nil:
+			// no params, project, or commit
+			if i == pqBenchmark || i == pqInstance {
+				return nil, fmt.Errorf("'%s' is null", parquetColumns[i])
+			}
+		default:
+			return nil, fmt.Errorf("'%s' is not a string but %T", parquetColumns[i], v)
+		}
+	}
+	return strs, nil
+}
+
+// parquetInvocations returns the invocations of row and the key of their execution (see parquetBenchmark.executionKey)
+func parquetInvocations(values [][]interface{}, row int64, pbs parquetBenchmarks, perCommit bool) (InvocationsFlat, string, error) {
+	strs, err := parquetStrings(values, row)
+	if err != nil {
+		return InvocationsFlat{}, "", err
+	}
+
+	ints := make([]int64, len(parquetColumns))
+	for col := pqTrial; col <= pqValueCount; col++ {
+		switch v := values[col][row].(type) {
+		case int32:
+			ints[col] = int64(v)
+		case int64:
+			ints[col] = v
+		default:
+			return InvocationsFlat{}, "", fmt.Errorf("'%s' is not an integer but %T", parquetColumns[col], v)
+		}
+	}
+
+	var value float64
+	switch v := values[pqValue][row].(type) {
+	case float64:
+		value = v
+	case float32:
+		value = float64(v)
+	case int32:
+		value = float64(v)
+	case int64:
+		value = float64(v)
+	default:
+		return InvocationsFlat{}, "", fmt.Errorf("'value' is not a number but %T", v)
+	}
+
+	pb := pbs.get(strs[pqBenchmark], strs[pqParams])
+	return InvocationsFlat{
+		Benchmark: pb.b,
+		Project:   strs[pqProject],
+		Commit:    strs[pqCommit],
+		Instance:  strs[pqInstance],
+		Trial:     int(ints[pqTrial]),
+		Fork:      int(ints[pqFork]),
+		Iteration: int(ints[pqIteration]),
+		Invocations: Invocations{
+			Count: int(ints[pqValueCount]),
+			Value: value,
+		},
+	}, pb.executionKey(strs, perCommit), nil
+}
+
+// parquetFile is a read-only source.ParquetFile of an io.ReaderAt, where every opened file has its own offset
+type parquetFile struct {
+	*io.SectionReader
+	r    io.ReaderAt
+	size int64
+}
+
+var _ source.ParquetFile = &parquetFile{}
+
+func newParquetFile(r io.ReaderAt, size int64) *parquetFile {
+	return &parquetFile{
+		SectionReader: io.NewSectionReader(r, 0, size),
+		r:             r,
+		size:          size,
+	}
+}
+
+func (f *parquetFile) Open(name string) (source.ParquetFile, error) {
+	return newParquetFile(f.r, f.size), nil
+}
+
+func (f *parquetFile) Create(name string) (source.ParquetFile, error) {
+	return nil, fmt.Errorf("Parquet file is read-only")
+}
+
+func (f *parquetFile) Write(p []byte) (int, error) {
+	return 0, fmt.Errorf("Parquet file is read-only")
+}
+
+func (f *parquetFile) Close() error {
+	return nil
+}
@@ -0,0 +1,324 @@
+package bench_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/chrstphlbr/pa/pkg/bench"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+type parquetRow struct {
+	Project    string  `parquet:"name=project, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Commit     string  `parquet:"name=commit, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Benchmark  string  `parquet:"name=benchmark, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Params     string  `parquet:"name=params, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Instance   string  `parquet:"name=instance, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Trial      int32   `parquet:"name=trial, type=INT32"`
+	Fork       int32   `parquet:"name=fork, type=INT32"`
+	Iteration  int32   `parquet:"name=iteration, type=INT32"`
+	Mode       string  `parquet:"name=mode, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Unit       string  `parquet:"name=unit, type=BYTE_ARRAY, convertedtype=UTF8"`
+	ValueCount int64   `parquet:"name=value_count, type=INT64"`
+	Value      float64 `parquet:"name=value, type=DOUBLE"`
+}
+
+// parquetRowNoValue is a parquetRow without the column 'value'
+type parquetRowNoValue struct {
+	Benchmark  string `parquet:"name=benchmark, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Params     string `parquet:"name=params, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Instance   string `parquet:"name=instance, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Trial      int32  `parquet:"name=trial, type=INT32"`
+	Fork       int32  `parquet:"name=fork, type=INT32"`
+	Iteration  int32  `parquet:"name=iteration, type=INT32"`
+	ValueCount int64  `parquet:"name=value_count, type=INT64"`
+}
+
+// parquetRowNoCommit is a parquetRow without the columns 'project', 'commit', 'mode', and 'unit'
+type parquetRowNoCommit struct {
+	Benchmark  string  `parquet:"name=benchmark, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Params     string  `parquet:"name=params, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Instance   string  `parquet:"name=instance, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Trial      int32   `parquet:"name=trial, type=INT32"`
+	Fork       int32   `parquet:"name=fork, type=INT32"`
+	Iteration  int32   `parquet:"name=iteration, type=INT32"`
+	ValueCount int64   `parquet:"name=value_count, type=INT64"`
+	Value      float64 `parquet:"name=value, type=DOUBLE"`
+}
+
+// parquetRows returns the rows of the CSV, in random order if shuffle
+func parquetRows(t *testing.T, in string, shuffle bool) []interface{} {
+	cr := csv.NewReader(strings.NewReader(in))
+	cr.Comma = ';'
+	recs, err := cr.ReadAll()
+	if err != nil {
+		t.Fatalf("Could not read CSV: %v", err)
+	}
+
+	rows := make([]interface{}, 0, len(recs)-1)
+	for _, rec := range recs[1:] {
+		ints := make([]int, 4)
+		for i, col := range []int{5, 6, 7, 10} {
+			ints[i], err = strconv.Atoi(rec[col])
+			if err != nil {
+				t.Fatalf("Could not parse %s: %v", rec[col], err)
+			}
+		}
+		v, err := strconv.ParseFloat(rec[11], 64)
+		if err != nil {
+			t.Fatalf("Could not parse %s: %v", rec[11], err)
+		}
+		rows = append(rows, parquetRow{
+			Project:    rec[0],
+			Commit:     rec[1],
+			Benchmark:  rec[2],
+			Params:     rec[3],
+			Instance:   rec[4],
+			Trial:      int32(ints[0]),
+			Fork:       int32(ints[1]),
+			Iteration:  int32(ints[2]),
+			Mode:       rec[8],
+			Unit:       rec[9],
+			ValueCount: int64(ints[3]),
+			Value:      v,
+		})
+	}
+
+	if !shuffle {
+		return rows
+	}
+	r := rand.New(rand.NewSource(1))
+	r.Shuffle(len(rows), func(i, j int) {
+		rows[i], rows[j] = rows[j], rows[i]
+	})
+	return rows
+}
+
+// toParquet writes the rows to a Parquet file with row groups of groupRows rows
+func toParquet(t *testing.T, schema interface{}, rows []interface{}, groupRows int) []byte {
+	var buf bytes.Buffer
+	pw, err := writer.NewParquetWriterFromWriter(&buf, schema, 1)
+	if err != nil {
+		t.Fatalf("Could not create Parquet writer: %v", err)
+	}
+	for i, row := range rows {
+		err := pw.Write(row)
+		if err == nil && (i+1)%groupRows == 0 {
+			err = pw.Flush(true)
+		}
+		if err != nil {
+			t.Fatalf("Could not write Parquet row: %v", err)
+		}
+	}
+	err = pw.WriteStop()
+	if err != nil {
+		t.Fatalf("Could not write Parquet: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func fromParquetHelper(t *testing.T, data []byte, reduction bench.Reduction) []*bench.Execution {
+	c, err := bench.FromParquetWithReduction(context.Background(), bytes.NewReader(data), int64(len(data)), reduction)
+	if err != nil {
+		t.Fatalf("Could not read Parquet: %v", err)
+	}
+	var execs []*bench.Execution
+	var started, ended bool
+	for ev := range c {
+		switch ev.Type {
+		case bench.ExecStart:
+			started = true
+		case bench.ExecEnd:
+			ended = true
+		case bench.ExecError:
+			t.Fatalf("Unexpected error: %v", ev.Err)
+		case bench.ExecNext:
+			execs = append(execs, ev.Exec)
+		}
+	}
+	if !started || !ended {
+		t.Fatalf("started = %t, ended = %t", started, ended)
+	}
+	return execs
+}
+
+func TestFromParquet(t *testing.T) {
+	in := binaryCSV(t)
+	expected, err := fromCSVHelper(t, strings.NewReader(in), 5, false)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	data := toParquet(t, new(parquetRow), parquetRows(t, in, true), 100)
+
+	got := fromParquetHelper(t, data, bench.Reduction{})
+	if len(got) != len(expected) {
+		t.Fatalf("Expected %d executions, was %d", len(expected), len(got))
+	}
+	for i := range got {
+		if !got[i].Benchmark.Equals(expected[i].Benchmark) {
+			t.Fatalf("Expected benchmark %v at %d, was %v", expected[i].Benchmark, i, got[i].Benchmark)
+		}
+		if got[i].ElementCount() != expected[i].ElementCount() {
+			t.Fatalf("Expected %d elements of %v, was %d", expected[i].ElementCount(), got[i].Benchmark, got[i].ElementCount())
+		}
+		equalValues(t, got[i], expected[i])
+	}
+}
+
+func TestFromParquetSorted(t *testing.T) {
+	in := binaryCSV(t)
+	expected, err := fromCSVHelper(t, strings.NewReader(in), 5, false)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	// sorted rows with executions across row groups are sent while reading
+	data := toParquet(t, new(parquetRow), parquetRows(t, in, false), 7)
+
+	got := fromParquetHelper(t, data, bench.Reduction{})
+	if len(got) != len(expected) {
+		t.Fatalf("Expected %d executions, was %d", len(expected), len(got))
+	}
+	for i := range got {
+		if !got[i].Benchmark.Equals(expected[i].Benchmark) {
+			t.Fatalf("Expected benchmark %v at %d, was %v", expected[i].Benchmark, i, got[i].Benchmark)
+		}
+		equalValues(t, got[i], expected[i])
+	}
+}
+
+func TestFromParquetParamsOrder(t *testing.T) {
+	row := func(params string, it int32) parquetRowNoCommit {
+		return parquetRowNoCommit{Benchmark: "b1", Params: params, Instance: "i1", Trial: 1, Fork: 1, Iteration: it, ValueCount: 1, Value: 1}
+	}
+
+	for name, rows := range map[string][]interface{}{
+		"sorted":   {row("a=1,b=2", 1), row("b=2,a=1", 2), row("b=2,a=1", 3)},
+		"unsorted": {row("a=1,b=2", 1), row("a=2,b=2", 1), row("b=2,a=1", 2), row("b=2,a=1", 3)},
+	} {
+		got := fromParquetHelper(t, toParquet(t, new(parquetRowNoCommit), rows, 2), bench.Reduction{})
+		if l := len(got); l != len(rows)-2 {
+			t.Fatalf("%s: expected %d executions, was %d", name, len(rows)-2, l)
+		}
+		if s := got[0].Benchmark.String(); s != "b1(){a=1,b=2}" {
+			t.Fatalf("%s: unexpected benchmark %s", name, s)
+		}
+		if l := len(got[0].FlatSlice(bench.AllInvocations)); l != 3 {
+			t.Fatalf("%s: expected 3 invocations of %v, was %d", name, got[0].Benchmark, l)
+		}
+	}
+}
+
+// equalValues checks whether the executions have the same invocation values, in any order
+func equalValues(t *testing.T, got, expected *bench.Execution) {
+	gvs := got.FlatSlice(bench.AllInvocations)
+	evs := expected.FlatSlice(bench.AllInvocations)
+	if len(gvs) != len(evs) {
+		t.Fatalf("Expected %d values of %v, was %d", len(evs), got.Benchmark, len(gvs))
+	}
+	sort.Float64s(gvs)
+	sort.Float64s(evs)
+	for i := range gvs {
+		if gvs[i] != evs[i] {
+			t.Fatalf("Expected value %f of %v at %d, was %f", evs[i], got.Benchmark, i, gvs[i])
+		}
+	}
+}
+
+func TestFromParquetReduction(t *testing.T) {
+	in := binaryCSV(t)
+	reduction := bench.Reduction{Mode: bench.HistogramReduction, Cap: 2}
+
+	c, err := bench.FromCSVWithReduction(context.Background(), strings.NewReader(in), reduction)
+	if err != nil {
+		t.Fatalf("Could not read CSV: %v", err)
+	}
+	var expected []*bench.Execution
+	for ev := range c {
+		if ev.Type == bench.ExecNext {
+			expected = append(expected, ev.Exec)
+		}
+	}
+
+	got := fromParquetHelper(t, toParquet(t, new(parquetRow), parquetRows(t, in, true), 100), reduction)
+	if len(got) != len(expected) {
+		t.Fatalf("Expected %d executions, was %d", len(expected), len(got))
+	}
+	for i := range got {
+		if l, el := len(got[i].FlatSlice(bench.AllInvocations)), len(expected[i].FlatSlice(bench.AllInvocations)); l != el {
+			t.Fatalf("Expected %d reduced invocations of %v, was %d", el, got[i].Benchmark, l)
+		}
+	}
+}
+
+func TestFromParquetPerCommit(t *testing.T) {
+	data := toParquet(t, new(parquetRow), parquetRows(t, perCommitCSV(t), false), 2)
+
+	for _, tc := range []struct {
+		perCommit bool
+		expected  string
+	}{
+		{true, "b1@p/c1:2,b1@p/c2:1,b2@p/c2:1"},
+		{false, "b1@/:3,b2@/:1"},
+	} {
+		read := bench.FromParquetWithReduction
+		if tc.perCommit {
+			read = bench.FromParquetPerCommit
+		}
+		c, err := read(context.Background(), bytes.NewReader(data), int64(len(data)), bench.Reduction{})
+		if err != nil {
+			t.Fatalf("Could not read Parquet: %v", err)
+		}
+		if s := commitExecutions(t, c); s != tc.expected {
+			t.Fatalf("Unexpected executions (per commit %t): was %s, expected %s", tc.perCommit, s, tc.expected)
+		}
+	}
+
+	// without the columns 'project' and 'commit'
+	data = toParquet(t, new(parquetRowNoCommit), []interface{}{
+		parquetRowNoCommit{Benchmark: "b1", Instance: "i1", Trial: 1, Fork: 1, Iteration: 1, ValueCount: 1, Value: 1},
+	}, 100)
+	c, err := bench.FromParquetPerCommit(context.Background(), bytes.NewReader(data), int64(len(data)), bench.Reduction{})
+	if err != nil {
+		t.Fatalf("Could not read Parquet: %v", err)
+	}
+	if s := commitExecutions(t, c); s != "b1@/:1" {
+		t.Fatalf("Unexpected executions: was %s, expected b1@/:1", s)
+	}
+}
+
+func TestFromParquetMissingColumn(t *testing.T) {
+	data := toParquet(t, new(parquetRowNoValue), []interface{}{
+		parquetRowNoValue{Benchmark: "b1", Instance: "i1", Trial: 1, Fork: 1, Iteration: 1, ValueCount: 1},
+	}, 100)
+
+	_, err := bench.FromParquet(context.Background(), bytes.NewReader(data), int64(len(data)))
+	if err == nil {
+		t.Fatalf("Expected error for missing column")
+	}
+	if !strings.Contains(err.Error(), "value") {
+		t.Fatalf("Expected error to name the missing column, was: %v", err)
+	}
+}
+
+func TestFromParquetInvalid(t *testing.T) {
+	data := toParquet(t, new(parquetRow), parquetRows(t, binaryCSV(t), true), 100)
+
+	for name, in := range map[string][]byte{
+		"csv":       []byte(binaryCSV(t)),
+		"empty":     {},
+		"truncated": data[:len(data)/2],
+	} {
+		_, err := bench.FromParquet(context.Background(), bytes.NewReader(in), int64(len(in)))
+		if err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+	}
+}
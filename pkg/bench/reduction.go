@@ -0,0 +1,310 @@
+package bench
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/exp/rand"
+)
+
+type ReductionMode int
+
+const (
+	// NoReduction keeps all invocations
+	NoReduction ReductionMode = iota
+	// HistogramReduction merges the invocations of an iteration into at most Cap histogram buckets of (approximately) equal counts,
+	// where every bucket's value is the mean of the merged invocations (which preserves the iteration's mean)
+	HistogramReduction
+	// ReservoirReduction keeps a uniform random sample (reservoir) of at most Cap invocations per iteration
+	ReservoirReduction
+)
+
+func (m ReductionMode) String() string {
+	switch m {
+	case NoReduction:
+		return "None"
+	case HistogramReduction:
+		return "Histogram"
+	case ReservoirReduction:
+		return "Reservoir"
+	}
+	return "INVALID_REDUCTION_MODE"
+}
+
+// Reduction bounds the memory required per iteration while reading executions
+type Reduction struct {
+	Mode ReductionMode
+	Cap  int
+}
+
+func (r Reduction) String() string {
+	if r.Mode == NoReduction {
+		return r.Mode.String()
+	}
+	return fmt.Sprintf("%s(%d)", r.Mode, r.Cap)
+}
+
+func (r Reduction) enabled() bool {
+	return r.Mode != NoReduction && r.Cap > 0
+}
+
+// ReductionStats reports how much data was reduced
+type ReductionStats struct {
+	// Buckets and Invocations are the number of histogram buckets (Invocations) and the sum of their counts that were read
+	Buckets     int
+	Invocations int
+	// ReducedBuckets and ReducedInvocations are the number of histogram buckets and the sum of their counts that were kept
+	ReducedBuckets     int
+	ReducedInvocations int
+}
+
+func (rs *ReductionStats) Add(other ReductionStats) {
+	rs.Buckets += other.Buckets
+	rs.Invocations += other.Invocations
+	rs.ReducedBuckets += other.ReducedBuckets
+	rs.ReducedInvocations += other.ReducedInvocations
+}
+
+type invocationsReducer interface {
+	add(Invocations)
+	invocations() []Invocations
+}
+
+func newInvocationsReducer(r Reduction) invocationsReducer {
+	switch r.Mode {
+	case HistogramReduction:
+		return &histogramReducer{
+			cap: r.Cap,
+		}
+	case ReservoirReduction:
+		return newReservoirReducer(r.Cap)
+	}
+	panic(fmt.Sprintf("Invalid reduction mode %d", r.Mode))
+}
+
+// histogramReducer buffers up to 2*cap buckets before compacting them to cap buckets
+type histogramReducer struct {
+	cap int
+	ivs []Invocations
+}
+
+func (r *histogramReducer) add(iv Invocations) {
+	r.ivs = append(r.ivs, iv)
+	if len(r.ivs) >= 2*r.cap {
+		r.ivs = CompactInvocations(r.ivs, r.cap)
+	}
+}
+
+func (r *histogramReducer) invocations() []Invocations {
+	return CompactInvocations(r.ivs, r.cap)
+}
+
+// CompactInvocations merges invocations into at most maxBuckets buckets.
+// First, invocations with equal values are merged.
+// If there are still more than maxBuckets buckets, neighbouring values are merged into buckets of (approximately) equal counts,
+// where the value of a bucket is the count-weighted mean of the merged values.
+// Hence, the total count and the mean of the invocations are preserved.
+func CompactInvocations(ivs []Invocations, maxBuckets int) []Invocations {
+	if len(ivs) <= 1 {
+		return ivs
+	}
+
+	sort.Slice(ivs, func(i, j int) bool {
+		return ivs[i].Value < ivs[j].Value
+	})
+
+	// merge equal values
+	merged := ivs[:1]
+	var total int
+	for _, iv := range ivs {
+		total += iv.Count
+	}
+	for _, iv := range ivs[1:] {
+		last := &merged[len(merged)-1]
+		if last.Value == iv.Value {
+			last.Count += iv.Count
+		} else {
+			merged = append(merged, iv)
+		}
+	}
+
+	if len(merged) <= maxBuckets || total == 0 {
+		return merged
+	}
+
+	// merge neighbouring values into buckets of equal counts
+	out := make([]Invocations, 0, maxBuckets)
+	bucket := -1
+	var cum int
+	var sum float64
+	for _, iv := range merged {
+		b := int(int64(cum) * int64(maxBuckets) / int64(total))
+		if b != bucket {
+			if bucket != -1 {
+				out[len(out)-1].Value = sum / float64(out[len(out)-1].Count)
+			}
+			out = append(out, Invocations{})
+			bucket = b
+			sum = 0
+		}
+		last := &out[len(out)-1]
+		last.Count += iv.Count
+		sum += float64(iv.Count) * iv.Value
+		cum += iv.Count
+	}
+	out[len(out)-1].Value = sum / float64(out[len(out)-1].Count)
+
+	return out
+}
+
+// reservoirReducer samples cap invocations uniformly with Algorithm L (Li, 1994), where an Invocations with Count c corresponds to c invocations
+type reservoirReducer struct {
+	cap       int
+	rng       *rand.Rand
+	reservoir []float64
+	// seen is the number of invocations seen so far
+	seen int
+	// next is the (1-based) number of the next invocation to be put into the reservoir
+	next int
+	w    float64
+}
+
+var reservoirSeed uint64
+
+func newReservoirReducer(cap int) *reservoirReducer {
+	seed := uint64(time.Now().UnixNano()) + atomic.AddUint64(&reservoirSeed, 1)
+	return &reservoirReducer{
+		cap:       cap,
+		rng:       rand.New(rand.NewSource(seed)),
+		reservoir: make([]float64, 0, cap),
+	}
+}
+
+func (r *reservoirReducer) random() float64 {
+	// (0,1] to avoid log(0)
+	return 1 - r.rng.Float64()
+}
+
+func (r *reservoirReducer) skip() {
+	r.next += int(math.Floor(math.Log(r.random())/math.Log(1-r.w))) + 1
+}
+
+func (r *reservoirReducer) add(iv Invocations) {
+	count := iv.Count
+	// fill reservoir
+	for count > 0 && len(r.reservoir) < r.cap {
+		r.reservoir = append(r.reservoir, iv.Value)
+		r.seen++
+		count--
+		if len(r.reservoir) == r.cap {
+			r.w = math.Exp(math.Log(r.random()) / float64(r.cap))
+			r.next = r.seen
+			r.skip()
+		}
+	}
+
+	if len(r.reservoir) < r.cap {
+		return
+	}
+
+	// replace random elements of the reservoir
+	last := r.seen + count
+	for r.next <= last {
+		r.reservoir[r.rng.Intn(r.cap)] = iv.Value
+		r.w *= math.Exp(math.Log(r.random()) / float64(r.cap))
+		r.skip()
+	}
+	r.seen = last
+}
+
+func (r *reservoirReducer) invocations() []Invocations {
+	ivs := make([]Invocations, len(r.reservoir))
+	for i, v := range r.reservoir {
+		ivs[i] = Invocations{
+			Count: 1,
+			Value: v,
+		}
+	}
+	// merge equal values
+	return CompactInvocations(ivs, len(ivs))
+}
+
+type iterationKey struct {
+	instance  string
+	trial     int
+	fork      int
+	iteration int
+}
+
+// reducingExecutionBuilder collects the invocations of one benchmark, where every iteration's invocations are reduced while they are added
+type reducingExecutionBuilder struct {
+	reduction Reduction
+	b         *B
+	keys      []iterationKey
+	reducers  map[iterationKey]invocationsReducer
+	stats     ReductionStats
+}
+
+var _ executionBuilder = &reducingExecutionBuilder{}
+
+func newReducingExecutionBuilder(b *B, r Reduction) *reducingExecutionBuilder {
+	return &reducingExecutionBuilder{
+		reduction: r,
+		b:         b,
+		reducers:  make(map[iterationKey]invocationsReducer),
+	}
+}
+
+func (rb *reducingExecutionBuilder) benchmark() *B {
+	return rb.b
+}
+
+func (rb *reducingExecutionBuilder) add(ivf InvocationsFlat) error {
+	if !rb.b.Equals(ivf.Benchmark) {
+		return fmt.Errorf("Execution belongs to %v, not to %v", rb.b, ivf.Benchmark)
+	}
+
+	k := iterationKey{
+		instance:  ivf.Instance,
+		trial:     ivf.Trial,
+		fork:      ivf.Fork,
+		iteration: ivf.Iteration,
+	}
+	r, ok := rb.reducers[k]
+	if !ok {
+		r = newInvocationsReducer(rb.reduction)
+		rb.reducers[k] = r
+		rb.keys = append(rb.keys, k)
+	}
+	r.add(ivf.Invocations)
+
+	rb.stats.Buckets++
+	rb.stats.Invocations += ivf.Invocations.Count
+	return nil
+}
+
+func (rb *reducingExecutionBuilder) execution() (*Execution, error) {
+	e := NewExecution(rb.b)
+	for _, k := range rb.keys {
+		for _, iv := range rb.reducers[k].invocations() {
+			err := e.AddInvocations(InvocationsFlat{
+				Benchmark:   rb.b,
+				Instance:    k.instance,
+				Trial:       k.trial,
+				Fork:        k.fork,
+				Iteration:   k.iteration,
+				Invocations: iv,
+			})
+			if err != nil {
+				return nil, err
+			}
+			rb.stats.ReducedBuckets++
+			rb.stats.ReducedInvocations += iv.Count
+		}
+	}
+	e.Reduction = rb.stats
+	return e, nil
+}
@@ -0,0 +1,170 @@
+package bench_test
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/chrstphlbr/pa/pkg/bench"
+)
+
+func invocationsSummary(ivs []bench.Invocations) (count int, mean float64) {
+	var sum float64
+	for _, iv := range ivs {
+		count += iv.Count
+		sum += float64(iv.Count) * iv.Value
+	}
+	return count, sum / float64(count)
+}
+
+func TestCompactInvocationsEqualValues(t *testing.T) {
+	ivs := []bench.Invocations{
+		{Count: 1, Value: 3},
+		{Count: 2, Value: 1},
+		{Count: 3, Value: 3},
+		{Count: 4, Value: 1},
+	}
+
+	civs := bench.CompactInvocations(ivs, 10)
+
+	expected := []bench.Invocations{
+		{Count: 6, Value: 1},
+		{Count: 4, Value: 3},
+	}
+	equalInvocations(t, expected, civs)
+}
+
+func TestCompactInvocationsBuckets(t *testing.T) {
+	const maxBuckets = 7
+	ivs := make([]bench.Invocations, 0, 1000)
+	for i := 0; i < 1000; i++ {
+		ivs = append(ivs, bench.Invocations{Count: i%3 + 1, Value: float64(i)})
+	}
+	count, mean := invocationsSummary(ivs)
+
+	civs := bench.CompactInvocations(ivs, maxBuckets)
+
+	if l := len(civs); l > maxBuckets {
+		t.Fatalf("Expected at most %d buckets, got %d", maxBuckets, l)
+	}
+	ccount, cmean := invocationsSummary(civs)
+	if ccount != count {
+		t.Fatalf("Count not preserved: expected %d, was %d", count, ccount)
+	}
+	if math.Abs(cmean-mean) > 1e-9 {
+		t.Fatalf("Mean not preserved: expected %f, was %f", mean, cmean)
+	}
+	for i := 1; i < len(civs); i++ {
+		if civs[i-1].Value >= civs[i].Value {
+			t.Fatalf("Buckets not sorted by value")
+		}
+	}
+}
+
+func reductionCSV(t *testing.T, iterations, invocations int) string {
+	w, sb := header(t)
+	for it := 1; it <= iterations; it++ {
+		for inv := 0; inv < invocations; inv++ {
+			w.Write([]string{"", "", "b1", "", "i1", "1", "1", strconv.Itoa(it), "sample", "ms/op", "2", strconv.Itoa(inv)})
+		}
+	}
+	w.Flush()
+	return sb.String()
+}
+
+func reducedExecution(t *testing.T, r bench.Reduction, iterations, invocations int) *bench.Execution {
+	c, err := bench.FromCSVWithReduction(context.TODO(), strings.NewReader(reductionCSV(t, iterations, invocations)), r)
+	if err != nil {
+		t.Fatalf("Could not get Benchmark channel: %v", err)
+	}
+
+	var execs []*bench.Execution
+	for ev := range c {
+		switch ev.Type {
+		case bench.ExecError:
+			t.Fatalf("Unexpected error: %v", ev.Err)
+		case bench.ExecNext:
+			execs = append(execs, ev.Exec)
+		}
+	}
+
+	if l := len(execs); l != 1 {
+		t.Fatalf("Expected 1 execution, got %d", l)
+	}
+	return execs[0]
+}
+
+func checkReducedIterations(t *testing.T, e *bench.Execution, iterations, maxBuckets int) {
+	f := e.Instances["i1"].Trials[1].Forks[1]
+	if l := len(f.IterationIDs); l != iterations {
+		t.Fatalf("Expected %d iterations, got %d", iterations, l)
+	}
+	for _, it := range f.Iterations {
+		if l := len(it.Invocations); l > maxBuckets {
+			t.Fatalf("Expected at most %d buckets in iteration %d, got %d", maxBuckets, it.ID, l)
+		}
+	}
+}
+
+func TestFromCSVHistogramReduction(t *testing.T) {
+	const (
+		iterations  = 3
+		invocations = 1000
+		maxBuckets  = 10
+	)
+
+	e := reducedExecution(t, bench.Reduction{Mode: bench.HistogramReduction, Cap: maxBuckets}, iterations, invocations)
+	checkReducedIterations(t, e, iterations, maxBuckets)
+
+	for _, it := range e.Instances["i1"].Trials[1].Forks[1].Iterations {
+		count, mean := invocationsSummary(it.Invocations)
+		if count != 2*invocations {
+			t.Fatalf("Count not preserved: expected %d, was %d", 2*invocations, count)
+		}
+		if expected := float64(invocations-1) / 2; math.Abs(mean-expected) > 1e-9 {
+			t.Fatalf("Mean not preserved: expected %f, was %f", expected, mean)
+		}
+	}
+
+	rs := e.Reduction
+	if rs.Buckets != iterations*invocations || rs.Invocations != iterations*invocations*2 || rs.ReducedInvocations != iterations*invocations*2 {
+		t.Fatalf("Unexpected reduction stats: %+v", rs)
+	}
+	if rs.ReducedBuckets < iterations || rs.ReducedBuckets > iterations*maxBuckets {
+		t.Fatalf("Unexpected number of reduced buckets: %d", rs.ReducedBuckets)
+	}
+}
+
+func TestFromCSVReservoirReduction(t *testing.T) {
+	const (
+		iterations  = 3
+		invocations = 1000
+		maxSamples  = 50
+	)
+
+	e := reducedExecution(t, bench.Reduction{Mode: bench.ReservoirReduction, Cap: maxSamples}, iterations, invocations)
+	checkReducedIterations(t, e, iterations, maxSamples)
+
+	for _, it := range e.Instances["i1"].Trials[1].Forks[1].Iterations {
+		count, _ := invocationsSummary(it.Invocations)
+		if count != maxSamples {
+			t.Fatalf("Expected %d sampled invocations, was %d", maxSamples, count)
+		}
+		for _, iv := range it.Invocations {
+			if iv.Value < 0 || iv.Value >= invocations || iv.Value != math.Trunc(iv.Value) {
+				t.Fatalf("Unexpected sampled value %f", iv.Value)
+			}
+		}
+	}
+
+	if e.Reduction.Invocations != iterations*invocations*2 || e.Reduction.ReducedInvocations != iterations*maxSamples {
+		t.Fatalf("Unexpected reduction stats: %+v", e.Reduction)
+	}
+}
+
+func TestFromCSVNoReduction(t *testing.T) {
+	e := reducedExecution(t, bench.Reduction{Mode: bench.NoReduction, Cap: 10}, 2, 100)
+	checkReducedIterations(t, e, 2, 100)
+}
@@ -0,0 +1,364 @@
+package bench
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultRoundingPrecision is the number of decimal places the constant factor transformer rounds to
+const DefaultRoundingPrecision = 5
+
+const (
+	// DefaultTukeyFactor is the default factor of the IQR for Tukey's fences
+	DefaultTukeyFactor = 1.5
+	// DefaultMADFactor is the default factor of the scaled MAD for MAD-based outlier removal
+	DefaultMADFactor = 3.0
+)
+
+const (
+	// ChainSeparator separates the transformers of a chain
+	ChainSeparator = "|"
+	// ChainNameSeparator separates the transformer names of a chain
+	ChainNameSeparator = " | "
+)
+
+// TransformerFactory creates a transformer from its (possibly empty) arguments.
+// A transformer that does not change executions may return a NamedExecutionTransformer with a nil ExecutionTransformer.
+type TransformerFactory func(args []string, report Reporter) (NamedExecutionTransformer, error)
+
+type registeredTransformer struct {
+	name    string
+	aliases []string
+	usage   string
+	factory TransformerFactory
+}
+
+// TransformerRegistry maps transformer names (and aliases) to factories, which allows parsing transformer chains such as `warmup(5)|f0.001|winsorize(0.99)`
+type TransformerRegistry struct {
+	transformers []*registeredTransformer
+	names        map[string]*registeredTransformer
+}
+
+// NewTransformerRegistry creates an empty registry
+func NewTransformerRegistry() *TransformerRegistry {
+	return &TransformerRegistry{
+		names: map[string]*registeredTransformer{},
+	}
+}
+
+// Register adds a transformer with a name, aliases, and a usage description to the registry.
+// It panics if the name or an alias is already registered.
+func (r *TransformerRegistry) Register(name string, aliases []string, usage string, factory TransformerFactory) {
+	rt := &registeredTransformer{
+		name:    name,
+		aliases: aliases,
+		usage:   usage,
+		factory: factory,
+	}
+	for _, n := range append([]string{name}, aliases...) {
+		if _, ok := r.names[n]; ok {
+			panic(fmt.Sprintf("Transformer '%s' already registered", n))
+		}
+		r.names[n] = rt
+	}
+	r.transformers = append(r.transformers, rt)
+}
+
+// Usage describes all registered transformers (one per line)
+func (r *TransformerRegistry) Usage() string {
+	var sb strings.Builder
+	for i, rt := range r.transformers {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(rt.name)
+		if len(rt.aliases) > 0 {
+			sb.WriteString(fmt.Sprintf(" (alias %s)", strings.Join(rt.aliases, ", ")))
+		}
+		sb.WriteString(": ")
+		sb.WriteString(rt.usage)
+	}
+	return sb.String()
+}
+
+// Parse parses a chain of transformers separated by ChainSeparator, which are applied from left to right.
+// Every transformer is either of the form `name(arg1,arg2)`, `name`, or `<name><arg>` (e.g., `f0.5` or `wi5`), where name is a registered name or alias.
+// The name of the resulting transformer lists the names of all transformers in the chain.
+func (r *TransformerRegistry) Parse(chain string, report Reporter) (*NamedExecutionTransformer, error) {
+	var ts []ExecutionTransformer
+	var names []string
+	for _, spec := range strings.Split(chain, ChainSeparator) {
+		t, err := r.parse(strings.TrimSpace(spec), report)
+		if err != nil {
+			return nil, err
+		}
+		if t.ExecutionTransformer != nil {
+			ts = append(ts, t.ExecutionTransformer)
+		}
+		names = append(names, t.Name)
+	}
+
+	var t ExecutionTransformer
+	switch len(ts) {
+	case 0:
+		t = nil
+	case 1:
+		t = ts[0]
+	default:
+		t = ChainExecutionTransformer(ts...)
+	}
+
+	return &NamedExecutionTransformer{
+		ExecutionTransformer: t,
+		Name:                 strings.Join(names, ChainNameSeparator),
+	}, nil
+}
+
+func (r *TransformerRegistry) parse(spec string, report Reporter) (NamedExecutionTransformer, error) {
+	name, args, err := r.split(spec)
+	if err != nil {
+		return NamedExecutionTransformer{}, err
+	}
+	t, err := r.names[name].factory(args, report)
+	if err != nil {
+		return NamedExecutionTransformer{}, fmt.Errorf("invalid transformer '%s': %w", spec, err)
+	}
+	return t, nil
+}
+
+// split splits spec into a registered name and the arguments
+func (r *TransformerRegistry) split(spec string) (string, []string, error) {
+	if spec == "" {
+		return "", nil, fmt.Errorf("empty transformer")
+	}
+
+	// name(arg1,arg2)
+	if open := strings.Index(spec, "("); open != -1 {
+		if !strings.HasSuffix(spec, ")") {
+			return "", nil, fmt.Errorf("invalid transformer '%s': missing closing parenthesis", spec)
+		}
+		name := spec[:open]
+		if _, ok := r.names[name]; !ok {
+			return "", nil, r.unknown(name)
+		}
+		var args []string
+		if argStr := strings.TrimSpace(spec[open+1 : len(spec)-1]); argStr != "" {
+			for _, arg := range strings.Split(argStr, ",") {
+				args = append(args, strings.TrimSpace(arg))
+			}
+		}
+		return name, args, nil
+	}
+
+	// name or <name><arg>, with the longest matching name and an argument that is a number (optionally with the level suffix 'it')
+	var name string
+	for n := range r.names {
+		if strings.HasPrefix(spec, n) && len(n) > len(name) && shortArg(spec[len(n):]) {
+			name = n
+		}
+	}
+	if name == "" {
+		return "", nil, r.unknown(spec)
+	}
+	if arg := spec[len(name):]; arg != "" {
+		return name, []string{arg}, nil
+	}
+	return name, nil, nil
+}
+
+func shortArg(arg string) bool {
+	if arg == "" || arg == "it" {
+		return true
+	}
+	c := arg[0]
+	return (c >= '0' && c <= '9') || c == '.' || c == '-' || c == '+'
+}
+
+func (r *TransformerRegistry) unknown(name string) error {
+	names := make([]string, 0, len(r.transformers))
+	for _, rt := range r.transformers {
+		names = append(names, rt.name)
+	}
+	return fmt.Errorf("unknown transformer '%s' (available: %s)", name, strings.Join(names, ", "))
+}
+
+// DefaultTransformers is the registry of all transformers of this package
+var DefaultTransformers = defaultTransformers()
+
+func defaultTransformers() *TransformerRegistry {
+	r := NewTransformerRegistry()
+
+	r.Register("id", []string{"identity"}, "no transformation", func(args []string, _ Reporter) (NamedExecutionTransformer, error) {
+		if err := checkArgs(args, 0, 0); err != nil {
+			return NamedExecutionTransformer{}, err
+		}
+		return NamedExecutionTransformer{Name: "ID"}, nil
+	})
+
+	r.Register("factor", []string{"f"}, "multiplies all invocation values with a factor, e.g., 'factor(0.001)' or 'f0.001'", func(args []string, _ Reporter) (NamedExecutionTransformer, error) {
+		if err := checkArgs(args, 1, 1); err != nil {
+			return NamedExecutionTransformer{}, err
+		}
+		f, err := strconv.ParseFloat(args[0], 64)
+		if err != nil {
+			return NamedExecutionTransformer{}, fmt.Errorf("invalid factor '%s'", args[0])
+		}
+		return NamedExecutionTransformer{
+			ExecutionTransformer: ConstantFactorExecutionTransformerFunc(f, DefaultRoundingPrecision),
+			Name:                 fmt.Sprintf("ConstantFactor(%g)", f),
+		}, nil
+	})
+
+	r.Register("warmup", []string{"wi"}, "removes the first n (warm-up) iterations of every fork, e.g., 'warmup(5)' or 'wi5'", func(args []string, _ Reporter) (NamedExecutionTransformer, error) {
+		n, err := intArg(args, "number of iterations", 0)
+		if err != nil {
+			return NamedExecutionTransformer{}, err
+		}
+		return NamedExecutionTransformer{
+			ExecutionTransformer: WarmupIterationsExecutionTransformerFunc(n),
+			Name:                 fmt.Sprintf("WarmupIterations(%d)", n),
+		}, nil
+	})
+
+	r.Register("warmupforks", []string{"wf"}, "removes the first n (warm-up) forks of every trial, e.g., 'warmupforks(1)' or 'wf1'", func(args []string, _ Reporter) (NamedExecutionTransformer, error) {
+		n, err := intArg(args, "number of forks", 0)
+		if err != nil {
+			return NamedExecutionTransformer{}, err
+		}
+		return NamedExecutionTransformer{
+			ExecutionTransformer: WarmupForksExecutionTransformerFunc(n),
+			Name:                 fmt.Sprintf("WarmupForks(%d)", n),
+		}, nil
+	})
+
+	r.Register("steadystate", []string{"ss"}, fmt.Sprintf("removes the iterations of every fork before its automatically-detected steady state, with an optional minimum number of steady iterations (default %d), e.g., 'steadystate', 'steadystate(10)', or 'ss10'", DefaultSteadyStateMinIterations), func(args []string, report Reporter) (NamedExecutionTransformer, error) {
+		n := DefaultSteadyStateMinIterations
+		if len(args) > 0 {
+			var err error
+			n, err = intArg(args, "minimum number of iterations", 1)
+			if err != nil {
+				return NamedExecutionTransformer{}, err
+			}
+		}
+		return NamedExecutionTransformer{
+			ExecutionTransformer: SteadyStateExecutionTransformerFunc(n, report),
+			Name:                 fmt.Sprintf("SteadyState(%d)", n),
+		}, nil
+	})
+
+	r.Register("tukey", nil, fmt.Sprintf("removes the values of every fork outside of Tukey's fences, with an optional IQR factor (default %g) and level ('invocations' (default) or 'iterations'), e.g., 'tukey(3, iterations)' or 'tukey3'", DefaultTukeyFactor), func(args []string, report Reporter) (NamedExecutionTransformer, error) {
+		k, level, err := outlierArgs(args, DefaultTukeyFactor)
+		if err != nil {
+			return NamedExecutionTransformer{}, err
+		}
+		return NamedExecutionTransformer{
+			ExecutionTransformer: TukeyExecutionTransformerFunc(level, k, report),
+			Name:                 fmt.Sprintf("Tukey(%g, %s)", k, level),
+		}, nil
+	})
+
+	r.Register("mad", nil, fmt.Sprintf("removes the values of every fork deviating more than a factor (default %g) of the scaled median absolute deviation from the median, with an optional level ('invocations' (default) or 'iterations'), e.g., 'mad(2.5, iterations)' or 'mad2.5'", DefaultMADFactor), func(args []string, report Reporter) (NamedExecutionTransformer, error) {
+		k, level, err := outlierArgs(args, DefaultMADFactor)
+		if err != nil {
+			return NamedExecutionTransformer{}, err
+		}
+		return NamedExecutionTransformer{
+			ExecutionTransformer: MADExecutionTransformerFunc(level, k, report),
+			Name:                 fmt.Sprintf("MAD(%g, %s)", k, level),
+		}, nil
+	})
+
+	r.Register("winsorize", []string{"win"}, "clamps the values of every fork outside of a percentile and its lower counterpart (or between a lower and an upper percentile), with an optional level ('invocations' (default) or 'iterations'), e.g., 'winsorize(0.99)', 'winsorize(0.05, 0.99, iterations)', or 'win0.99'", func(args []string, report Reporter) (NamedExecutionTransformer, error) {
+		args, level, err := levelArg(args)
+		if err != nil {
+			return NamedExecutionTransformer{}, err
+		}
+		if err := checkArgs(args, 1, 2); err != nil {
+			return NamedExecutionTransformer{}, err
+		}
+		var ps []float64
+		for _, arg := range args {
+			p, err := strconv.ParseFloat(arg, 64)
+			if err != nil || p <= 0 || p >= 1 {
+				return NamedExecutionTransformer{}, fmt.Errorf("invalid percentile '%s'", arg)
+			}
+			ps = append(ps, p)
+		}
+		if len(ps) == 1 {
+			// round the complement to avoid floating-point noise (e.g., 1-0.99)
+			ps = append(ps, math.Round((1-ps[0])*1e9)/1e9)
+		}
+		sort.Float64s(ps)
+		return NamedExecutionTransformer{
+			ExecutionTransformer: WinsorizeExecutionTransformerFunc(level, ps[0], ps[1], report),
+			Name:                 fmt.Sprintf("Winsorize(%g, %g, %s)", ps[0], ps[1], level),
+		}, nil
+	})
+
+	return r
+}
+
+func checkArgs(args []string, min, max int) error {
+	if len(args) < min || len(args) > max {
+		if min == max {
+			return fmt.Errorf("expected %d argument(s), got %d", min, len(args))
+		}
+		return fmt.Errorf("expected %d to %d arguments, got %d", min, max, len(args))
+	}
+	return nil
+}
+
+func intArg(args []string, desc string, min int) (int, error) {
+	if err := checkArgs(args, 1, 1); err != nil {
+		return 0, err
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n < min {
+		return 0, fmt.Errorf("invalid %s '%s'", desc, args[0])
+	}
+	return n, nil
+}
+
+// outlierArgs parses the optional factor (with default def) and level of an outlier transformer
+func outlierArgs(args []string, def float64) (float64, OutlierLevel, error) {
+	args, level, err := levelArg(args)
+	if err != nil {
+		return 0, level, err
+	}
+	if err := checkArgs(args, 0, 1); err != nil {
+		return 0, level, err
+	}
+	if len(args) == 0 {
+		return def, level, nil
+	}
+	k, err := strconv.ParseFloat(args[0], 64)
+	if err != nil || k <= 0 {
+		return 0, level, fmt.Errorf("invalid factor '%s'", args[0])
+	}
+	return k, level, nil
+}
+
+// levelArg extracts the outlier level, which is either the last argument ('invocations' or 'iterations') or, for the short form (e.g., 'tukey3it' or 'madit'), the suffix 'it' of the only argument.
+// It returns the remaining arguments.
+func levelArg(args []string) ([]string, OutlierLevel, error) {
+	if len(args) == 0 {
+		return args, InvocationLevel, nil
+	}
+	last := args[len(args)-1]
+	switch last {
+	case InvocationLevel.String():
+		return args[:len(args)-1], InvocationLevel, nil
+	case IterationLevel.String(), "it":
+		return args[:len(args)-1], IterationLevel, nil
+	}
+	if len(args) == 1 && strings.HasSuffix(last, "it") {
+		return []string{strings.TrimSuffix(last, "it")}, IterationLevel, nil
+	}
+	if _, err := strconv.ParseFloat(last, 64); err != nil && len(args) > 1 {
+		return args, InvocationLevel, fmt.Errorf("invalid level '%s' (available: %s, %s)", last, InvocationLevel, IterationLevel)
+	}
+	return args, InvocationLevel, nil
+}
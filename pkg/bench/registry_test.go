@@ -0,0 +1,138 @@
+package bench_test
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/chrstphlbr/pa/pkg/bench"
+)
+
+func TestTransformerRegistryParseNames(t *testing.T) {
+	for _, c := range []struct {
+		chain string
+		name  string
+	}{
+		{"id", "ID"},
+		{"f0.001", "ConstantFactor(0.001)"},
+		{"factor(2)", "ConstantFactor(2)"},
+		{"wi5", "WarmupIterations(5)"},
+		{"warmup(5)", "WarmupIterations(5)"},
+		{"warmupforks(1)", "WarmupForks(1)"},
+		{"ss", "SteadyState(5)"},
+		{"ss10", "SteadyState(10)"},
+		{"tukey", "Tukey(1.5, invocations)"},
+		{"tukey3it", "Tukey(3, iterations)"},
+		{"tukey(3, iterations)", "Tukey(3, iterations)"},
+		{"madit", "MAD(3, iterations)"},
+		{"mad(2.5)", "MAD(2.5, invocations)"},
+		{"win0.99", "Winsorize(0.01, 0.99, invocations)"},
+		{"winsorize(0.99, 0.05, iterations)", "Winsorize(0.05, 0.99, iterations)"},
+		{"warmup(5)|f0.001|winsorize(0.99)", "WarmupIterations(5) | ConstantFactor(0.001) | Winsorize(0.01, 0.99, invocations)"},
+		{" wi5 | id ", "WarmupIterations(5) | ID"},
+	} {
+		tr, err := bench.DefaultTransformers.Parse(c.chain, nil)
+		if err != nil {
+			t.Fatalf("Could not parse '%s': %v", c.chain, err)
+		}
+		if tr.Name != c.name {
+			t.Fatalf("Unexpected name of '%s': was '%s', expected '%s'", c.chain, tr.Name, c.name)
+		}
+	}
+}
+
+func TestTransformerRegistryParseInvalid(t *testing.T) {
+	for _, c := range []struct {
+		chain string
+		err   string
+	}{
+		{"", "empty transformer"},
+		{"wi5|", "empty transformer"},
+		{"x", "unknown transformer 'x'"},
+		{"warm(5)", "unknown transformer 'warm'"},
+		{"warmup(5", "missing closing parenthesis"},
+		{"warmup", "expected 1 argument(s), got 0"},
+		{"wi-1", "invalid number of iterations '-1'"},
+		{"fx", "unknown transformer 'fx'"},
+		{"f1.2.3", "invalid factor '1.2.3'"},
+		{"tukey(1, 2)", "expected 0 to 1 arguments, got 2"},
+		{"tukey(1, forks)", "invalid level 'forks'"},
+		{"win1.5", "invalid percentile '1.5'"},
+		{"id(1)", "expected 0 argument(s), got 1"},
+	} {
+		_, err := bench.DefaultTransformers.Parse(c.chain, nil)
+		if err == nil {
+			t.Fatalf("Expected error for '%s'", c.chain)
+		}
+		if !strings.Contains(err.Error(), c.err) {
+			t.Fatalf("Unexpected error for '%s': was '%v', expected to contain '%s'", c.chain, err, c.err)
+		}
+	}
+}
+
+func TestTransformerRegistryParseIdentity(t *testing.T) {
+	tr, err := bench.DefaultTransformers.Parse("id|identity", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr.ExecutionTransformer != nil {
+		t.Fatalf("Expected no transformer for identity chain")
+	}
+}
+
+func TestTransformerRegistryParseChain(t *testing.T) {
+	e := complexExecution(t)
+
+	tr, err := bench.DefaultTransformers.Parse("wi3|id|wf2", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := make(bench.Chan, 3)
+	c <- bench.ExecutionValue{Type: bench.ExecStart}
+	c <- bench.ExecutionValue{Type: bench.ExecNext, Exec: e}
+	c <- bench.ExecutionValue{Type: bench.ExecEnd}
+	close(c)
+
+	var te *bench.Execution
+	for ev := range bench.TransformChan(tr, c) {
+		if ev.Type == bench.ExecNext {
+			te = ev.Exec
+		}
+	}
+
+	checkIterationIDs(t, te, []int{4, 5, 6, 7, 8, 9, 10})
+	trial := te.Instances[te.InstanceIDs[0]].Trials[1]
+	if !reflect.DeepEqual(trial.ForkIDs, []int{3, 4, 5}) {
+		t.Fatalf("Unexpected forks: %v", trial.ForkIDs)
+	}
+}
+
+func TestTransformerRegistryRegister(t *testing.T) {
+	r := bench.NewTransformerRegistry()
+	r.Register("double", []string{"d"}, "doubles all values", func(args []string, _ bench.Reporter) (bench.NamedExecutionTransformer, error) {
+		return bench.NamedExecutionTransformer{
+			ExecutionTransformer: bench.ConstantFactorExecutionTransformerFunc(2, 0),
+			Name:                 fmt.Sprintf("Double%v", args),
+		}, nil
+	})
+
+	tr, err := r.Parse("d|double(1,2)", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr.Name != "Double[] | Double[1 2]" {
+		t.Fatalf("Unexpected name: %s", tr.Name)
+	}
+	if u := r.Usage(); u != "double (alias d): doubles all values" {
+		t.Fatalf("Unexpected usage: %s", u)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("Expected panic for duplicate registration")
+		}
+	}()
+	r.Register("d", nil, "", nil)
+}
@@ -0,0 +1,149 @@
+package bench
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	st "github.com/chrstphlbr/pa/pkg/stat"
+)
+
+// DefaultSteadyStateMinIterations is the default minimum number of steady iterations of a fork
+const DefaultSteadyStateMinIterations = 5
+
+// steadyStatePenalty is the factor of the change point penalty (see SteadyStateStart), which was chosen such that normal noise of 50 iterations results in a false change point in about 1% of the forks
+const steadyStatePenalty = 4
+
+// Reporter receives reports of transformers about the execution of benchmark b
+type Reporter func(b *B, report fmt.Stringer)
+
+// SteadyStateStart detects the first iteration of the steady state of a fork's iteration means (in iteration order).
+// It detects changes in the mean with binary segmentation, where a change point is accepted if it reduces the sum of squared deviations
+// by more than the penalty 4 * sigma^2 * ln(n) (twice the BIC penalty), with sigma estimated robustly from the differences of consecutive iterations.
+// The steady state starts at the last change point; if it has fewer than minIterations iterations, the fork never reaches a steady state (steady is false).
+func SteadyStateStart(means []float64, minIterations int) (start int, steady bool) {
+	n := len(means)
+	if n == 0 || n < minIterations {
+		return 0, false
+	}
+
+	// prefix sums for constant-time segment costs
+	s1 := make([]float64, n+1)
+	s2 := make([]float64, n+1)
+	for i, m := range means {
+		s1[i+1] = s1[i] + m
+		s2[i+1] = s2[i] + m*m
+	}
+	cost := func(from, to int) float64 {
+		l := float64(to - from)
+		sum := s1[to] - s1[from]
+		return s2[to] - s2[from] - sum*sum/l
+	}
+
+	sigma := noiseLevel(means)
+	penalty := steadyStatePenalty * sigma * sigma * math.Log(float64(n))
+
+	start = lastChangePoint(0, n, cost, penalty)
+	return start, n-start >= minIterations
+}
+
+// lastChangePoint returns the last change point of the segment [from,to) found with binary segmentation, or from if there is none
+func lastChangePoint(from, to int, cost func(from, to int) float64, penalty float64) int {
+	if to-from < 2 {
+		return from
+	}
+
+	total := cost(from, to)
+	best, bestGain := -1, 0.0
+	for k := from + 1; k < to; k++ {
+		gain := total - cost(from, k) - cost(k, to)
+		if gain > bestGain {
+			best, bestGain = k, gain
+		}
+	}
+
+	if best == -1 || bestGain <= penalty {
+		return from
+	}
+	// the last change point is in the right segment or is the split itself
+	return lastChangePoint(best, to, cost, penalty)
+}
+
+// noiseLevel estimates the standard deviation of the noise of means with the median absolute difference of consecutive values,
+// which is robust against changes in the mean. If the median is 0 (e.g., due to timer resolution), the standard deviation of the differences is used.
+func noiseLevel(means []float64) float64 {
+	if len(means) < 2 {
+		return 0
+	}
+	diffs := make([]float64, len(means)-1)
+	for i := 1; i < len(means); i++ {
+		diffs[i-1] = math.Abs(means[i] - means[i-1])
+	}
+
+	// for normal noise, the differences have standard deviation sqrt(2) * sigma, and their absolute median is 0.6745 * sqrt(2) * sigma
+	if mad := st.Median(diffs); mad > 0 {
+		return mad / (0.6745 * math.Sqrt2)
+	}
+	if len(diffs) < 2 {
+		return 0
+	}
+	return st.StdDev(diffs) / math.Sqrt2
+}
+
+// SteadyStateReport reports the result of the steady-state detection of a benchmark
+type SteadyStateReport struct {
+	Forks int
+	// Iterations and DiscardedIterations are the number of iterations before and the number of iterations removed by the steady-state detection
+	Iterations          int
+	DiscardedIterations int
+	// NonSteadyForks are the forks (as instance/trial/fork) that never reached a steady state, which are kept as they are
+	NonSteadyForks []string
+}
+
+func (r SteadyStateReport) String() string {
+	s := fmt.Sprintf("steady state: discarded %d of %d iterations", r.DiscardedIterations, r.Iterations)
+	if len(r.NonSteadyForks) > 0 {
+		s += fmt.Sprintf("; %d of %d forks never steady (%s)", len(r.NonSteadyForks), r.Forks, strings.Join(r.NonSteadyForks, ", "))
+	}
+	return s
+}
+
+// SteadyStateExecutionTransformerFunc removes the iterations of every fork before its steady state (see SteadyStateStart).
+// Forks that never reach a steady state are kept as they are and flagged in the report, which is passed to report (if not nil).
+func SteadyStateExecutionTransformerFunc(minIterations int, report Reporter) ExecutionTransformerFunc {
+	return func(e *Execution) *Execution {
+		ne := e.Copy()
+
+		var r SteadyStateReport
+		for _, iid := range ne.InstanceIDs {
+			instance := ne.Instances[iid]
+			for _, tid := range instance.TrialIDs {
+				trial := instance.Trials[tid]
+				for _, fid := range trial.ForkIDs {
+					fork := trial.Forks[fid]
+
+					means := make([]float64, len(fork.IterationIDs))
+					for i, itid := range fork.IterationIDs {
+						means[i] = MeanInvocations(fork.Iterations[itid].Invocations)[0]
+					}
+
+					r.Forks++
+					r.Iterations += len(means)
+
+					start, steady := SteadyStateStart(means, minIterations)
+					if !steady {
+						r.NonSteadyForks = append(r.NonSteadyForks, fmt.Sprintf("%s/%d/%d", iid, tid, fid))
+						continue
+					}
+					r.DiscardedIterations += start
+					ne.addLen(-fork.dropIterations(start))
+				}
+			}
+		}
+
+		if report != nil {
+			report(ne.Benchmark, r)
+		}
+		return ne
+	}
+}
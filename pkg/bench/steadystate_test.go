@@ -0,0 +1,131 @@
+package bench_test
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/chrstphlbr/pa/pkg/bench"
+)
+
+// warmupSeries returns warmup iterations decreasing from 300 to 100, followed by steady iterations around 100 with (deterministic) noise
+func warmupSeries(warmup, steady int) []float64 {
+	rnd := rand.New(rand.NewSource(42))
+	var s []float64
+	for i := 0; i < warmup; i++ {
+		s = append(s, 300-float64(i)*200/float64(warmup)+rnd.NormFloat64())
+	}
+	for i := 0; i < steady; i++ {
+		s = append(s, 100+rnd.NormFloat64())
+	}
+	return s
+}
+
+func TestSteadyStateStartConstant(t *testing.T) {
+	s := make([]float64, 30)
+	for i := range s {
+		s[i] = 10
+	}
+
+	start, steady := bench.SteadyStateStart(s, 5)
+	if !steady || start != 0 {
+		t.Fatalf("Expected steady state from the first iteration, was %d (steady = %t)", start, steady)
+	}
+}
+
+func TestSteadyStateStartNoise(t *testing.T) {
+	start, steady := bench.SteadyStateStart(warmupSeries(0, 50), 5)
+	if !steady || start != 0 {
+		t.Fatalf("Expected steady state from the first iteration, was %d (steady = %t)", start, steady)
+	}
+}
+
+func TestSteadyStateStartWarmup(t *testing.T) {
+	for _, warmup := range []int{3, 10, 20} {
+		start, steady := bench.SteadyStateStart(warmupSeries(warmup, 40), 5)
+		if !steady {
+			t.Fatalf("Expected steady state for warmup %d", warmup)
+		}
+		if start != warmup {
+			t.Fatalf("Unexpected start of steady state: was %d, expected %d", start, warmup)
+		}
+	}
+}
+
+func TestSteadyStateStartNeverSteady(t *testing.T) {
+	// continuous trend
+	s := make([]float64, 30)
+	for i := range s {
+		s[i] = float64(100 + 10*i)
+	}
+
+	_, steady := bench.SteadyStateStart(s, 10)
+	if steady {
+		t.Fatalf("Expected no steady state for a trend")
+	}
+
+	// too few iterations
+	_, steady = bench.SteadyStateStart([]float64{1, 1, 1}, 5)
+	if steady {
+		t.Fatalf("Expected no steady state for fewer iterations than minIterations")
+	}
+}
+
+func TestSteadyStateExecutionTransformerFunc(t *testing.T) {
+	b := bench.New("b1")
+	e := bench.NewExecution(b)
+	trend := make([]float64, 30)
+	for i := range trend {
+		trend[i] = float64(100 + 10*i)
+	}
+	forks := [][]float64{
+		warmupSeries(10, 40),
+		trend,
+		warmupSeries(0, 30),
+	}
+	for f, s := range forks {
+		for i, v := range s {
+			addInvocationsHelper(t, e, []bench.InvocationsFlat{{
+				Benchmark:   b,
+				Instance:    "i1",
+				Trial:       1,
+				Fork:        f + 1,
+				Iteration:   i + 1,
+				Invocations: bench.Invocations{Count: 1, Value: v},
+			}})
+		}
+	}
+
+	var reports []fmt.Stringer
+	te := bench.SteadyStateExecutionTransformerFunc(10, func(rb *bench.B, r fmt.Stringer) {
+		if !rb.Equals(b) {
+			t.Fatalf("Unexpected benchmark in report: %v", rb)
+		}
+		reports = append(reports, r)
+	})(e)
+
+	trial := te.Instances["i1"].Trials[1]
+	expectedIterations := []int{40, 30, 30}
+	for f, expected := range expectedIterations {
+		if l := len(trial.Forks[f+1].IterationIDs); l != expected {
+			t.Fatalf("Unexpected number of iterations of fork %d: was %d, expected %d", f+1, l, expected)
+		}
+	}
+	if first := trial.Forks[1].IterationIDs[0]; first != 11 {
+		t.Fatalf("Unexpected first steady iteration: was %d, expected 11", first)
+	}
+
+	if len(reports) != 1 {
+		t.Fatalf("Expected one report, got %d", len(reports))
+	}
+	expected := bench.SteadyStateReport{
+		Forks:               3,
+		Iterations:          110,
+		DiscardedIterations: 10,
+		NonSteadyForks:      []string{"i1/1/2"},
+	}
+	if !reflect.DeepEqual(reports[0], expected) {
+		t.Fatalf("Unexpected report: was %v, expected %v", reports[0], expected)
+	}
+}
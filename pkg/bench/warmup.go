@@ -0,0 +1,102 @@
+package bench
+
+import "fmt"
+
+// WarmupIterationsExecutionTransformerFunc removes the first n iterations of every fork, which are considered warm-up iterations (e.g., due to JIT compilation).
+// Forks with at most n iterations are removed entirely.
+func WarmupIterationsExecutionTransformerFunc(n int) ExecutionTransformerFunc {
+	return func(e *Execution) *Execution {
+		ne := e.Copy()
+		for _, instance := range ne.Instances {
+			for _, trial := range instance.Trials {
+				for _, fork := range trial.Forks {
+					ne.addLen(-fork.dropIterations(n))
+				}
+			}
+		}
+		ne.removeEmpty()
+		return ne
+	}
+}
+
+// WarmupForksExecutionTransformerFunc removes the first n forks of every trial, which are considered warm-up forks.
+// Trials with at most n forks are removed entirely.
+func WarmupForksExecutionTransformerFunc(n int) ExecutionTransformerFunc {
+	return func(e *Execution) *Execution {
+		ne := e.Copy()
+		for _, instance := range ne.Instances {
+			for _, trial := range instance.Trials {
+				drop := n
+				if drop > len(trial.ForkIDs) {
+					drop = len(trial.ForkIDs)
+				}
+				for _, fid := range trial.ForkIDs[:drop] {
+					fork := trial.Forks[fid]
+					ne.addLen(-fork.dropIterations(len(fork.IterationIDs)))
+					delete(trial.Forks, fid)
+				}
+				trial.ForkIDs = trial.ForkIDs[drop:]
+			}
+		}
+		ne.removeEmpty()
+		return ne
+	}
+}
+
+// dropIterations removes the first n iterations (in the order they were added) and returns the number of removed invocations
+func (f *Fork) dropIterations(n int) int {
+	if n > len(f.IterationIDs) {
+		n = len(f.IterationIDs)
+	}
+
+	var removed int
+	for _, itid := range f.IterationIDs[:n] {
+		it, ok := f.Iterations[itid]
+		if !ok {
+			panic(fmt.Sprintf("Invalid state: IterationIDs and Iterations out of sync for %d", itid))
+		}
+		for _, iv := range it.Invocations {
+			removed += iv.Count
+		}
+		delete(f.Iterations, itid)
+	}
+	f.IterationIDs = f.IterationIDs[n:]
+	return removed
+}
+
+// removeEmpty removes forks without iterations, trials without forks, and instances without trials
+func (e *Execution) removeEmpty() {
+	iids := e.InstanceIDs[:0]
+	for _, iid := range e.InstanceIDs {
+		instance := e.Instances[iid]
+
+		tids := instance.TrialIDs[:0]
+		for _, tid := range instance.TrialIDs {
+			trial := instance.Trials[tid]
+
+			fids := trial.ForkIDs[:0]
+			for _, fid := range trial.ForkIDs {
+				if len(trial.Forks[fid].IterationIDs) == 0 {
+					delete(trial.Forks, fid)
+					continue
+				}
+				fids = append(fids, fid)
+			}
+			trial.ForkIDs = fids
+
+			if len(trial.ForkIDs) == 0 {
+				delete(instance.Trials, tid)
+				continue
+			}
+			tids = append(tids, tid)
+		}
+		instance.TrialIDs = tids
+
+		if len(instance.TrialIDs) == 0 {
+			delete(e.Instances, iid)
+			continue
+		}
+		iids = append(iids, iid)
+	}
+	e.InstanceIDs = iids
+}
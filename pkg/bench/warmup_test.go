@@ -0,0 +1,81 @@
+package bench_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/chrstphlbr/pa/pkg/bench"
+)
+
+func checkIterationIDs(t *testing.T, e *bench.Execution, expected []int) {
+	for _, iid := range e.InstanceIDs {
+		for _, tid := range e.Instances[iid].TrialIDs {
+			trial := e.Instances[iid].Trials[tid]
+			for _, fid := range trial.ForkIDs {
+				fork := trial.Forks[fid]
+				if !reflect.DeepEqual(fork.IterationIDs, expected) {
+					t.Fatalf("Unexpected iterations of fork %s/%d/%d: was %v, expected %v", iid, tid, fid, fork.IterationIDs, expected)
+				}
+				if len(fork.Iterations) != len(expected) {
+					t.Fatalf("IterationIDs and Iterations out of sync: %d != %d", len(fork.Iterations), len(expected))
+				}
+			}
+		}
+	}
+}
+
+func TestWarmupIterationsExecutionTransformerFunc(t *testing.T) {
+	e := complexExecution(t)
+	te := bench.WarmupIterationsExecutionTransformerFunc(3)(e)
+
+	checkIterationIDs(t, te, []int{4, 5, 6, 7, 8, 9, 10})
+	// original execution is unaltered
+	checkIterationIDs(t, e, []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10})
+
+	if nr := te.Flat().NrIterations(); nr != 3*4*5*7 {
+		t.Fatalf("Unexpected number of iterations: was %d, expected %d", nr, 3*4*5*7)
+	}
+}
+
+func TestWarmupIterationsExecutionTransformerFuncAll(t *testing.T) {
+	e := complexExecution(t)
+	te := bench.WarmupIterationsExecutionTransformerFunc(10)(e)
+
+	if len(te.InstanceIDs) != 0 || len(te.Instances) != 0 {
+		t.Fatalf("Expected empty execution, but has instances %v", te.InstanceIDs)
+	}
+}
+
+func TestWarmupIterationsExecutionTransformerFuncNone(t *testing.T) {
+	e := complexExecution(t)
+	te := bench.WarmupIterationsExecutionTransformerFunc(0)(e)
+
+	equalInstances(t, e, te, true)
+}
+
+func TestWarmupForksExecutionTransformerFunc(t *testing.T) {
+	e := complexExecution(t)
+	te := bench.WarmupForksExecutionTransformerFunc(2)(e)
+
+	for _, iid := range te.InstanceIDs {
+		for _, tid := range te.Instances[iid].TrialIDs {
+			trial := te.Instances[iid].Trials[tid]
+			if !reflect.DeepEqual(trial.ForkIDs, []int{3, 4, 5}) {
+				t.Fatalf("Unexpected forks of trial %s/%d: %v", iid, tid, trial.ForkIDs)
+			}
+			if len(trial.Forks) != 3 {
+				t.Fatalf("ForkIDs and Forks out of sync: %d != 3", len(trial.Forks))
+			}
+		}
+	}
+	checkIterationIDs(t, te, []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10})
+
+	if nr := te.Flat().NrIterations(); nr != 3*4*3*10 {
+		t.Fatalf("Unexpected number of iterations: was %d, expected %d", nr, 3*4*3*10)
+	}
+
+	te = bench.WarmupForksExecutionTransformerFunc(5)(e)
+	if len(te.InstanceIDs) != 0 {
+		t.Fatalf("Expected empty execution, but has instances %v", te.InstanceIDs)
+	}
+}
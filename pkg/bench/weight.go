@@ -0,0 +1,80 @@
+package bench
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// WeightSeparator separates the filter expression from the weight in a line of a weight file
+const WeightSeparator = ";"
+
+// Weights assigns weights to benchmarks, e.g., to weight their ratios in a suite-wide aggregate
+type Weights struct {
+	filters []Filter
+	weights []float64
+	// Default is the weight of benchmarks not matched by any filter
+	Default float64
+}
+
+// ParseWeights reads weights with one '<filter expression>;<weight>' per line (see ParseFilter for the expression syntax), e.g., 'size=1024;2' or '^pkg\.SortBench$;0.5'.
+// A benchmark gets the weight of the first matching line, or def if no line matches. Empty lines and lines starting with '#' are ignored.
+func ParseWeights(r io.Reader, def float64) (*Weights, error) {
+	if def < 0 {
+		return nil, fmt.Errorf("negative default weight %g", def)
+	}
+	w := &Weights{
+		Default: def,
+	}
+
+	s := bufio.NewScanner(r)
+	nr := 0
+	for s.Scan() {
+		nr++
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		i := strings.LastIndex(line, WeightSeparator)
+		if i == -1 {
+			return nil, fmt.Errorf("line %d: expected '<filter expression>%s<weight>', was '%s'", nr, WeightSeparator, line)
+		}
+		expr := strings.TrimSpace(line[:i])
+		weight, err := strconv.ParseFloat(strings.TrimSpace(line[i+1:]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid weight: %w", nr, err)
+		}
+		if weight < 0 {
+			return nil, fmt.Errorf("line %d: negative weight %g", nr, weight)
+		}
+		f, err := ParseFilter(expr)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", nr, err)
+		}
+
+		w.filters = append(w.filters, f)
+		w.weights = append(w.weights, weight)
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Weight returns the weight of b and whether a line matched
+func (w *Weights) Weight(b *B) (float64, bool) {
+	for i, f := range w.filters {
+		if f(b) {
+			return w.weights[i], true
+		}
+	}
+	return w.Default, false
+}
+
+// Len returns the number of weight lines
+func (w *Weights) Len() int {
+	return len(w.filters)
+}
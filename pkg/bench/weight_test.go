@@ -0,0 +1,58 @@
+package bench_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/chrstphlbr/pa/pkg/bench"
+)
+
+func TestParseWeights(t *testing.T) {
+	in := `# weights
+^pkg\.SortBench$;2
+size=1024 ; 0.5
+
+Map;0
+`
+	w, err := bench.ParseWeights(strings.NewReader(in), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Len() != 3 {
+		t.Fatalf("Unexpected number of weights: %d", w.Len())
+	}
+
+	for _, c := range []struct {
+		b        *bench.B
+		expected float64
+		matched  bool
+	}{
+		// first matching line wins
+		{benchWithParams("pkg.SortBench", "size=1024"), 2, true},
+		{benchWithParams("pkg.SortBenchFast", "size=1024"), 0.5, true},
+		{benchWithParams("pkg.MapBench", "size=64"), 0, true},
+		{benchWithParams("pkg.ListBench", "size=64"), 1, false},
+	} {
+		weight, matched := w.Weight(c.b)
+		if weight != c.expected || matched != c.matched {
+			t.Fatalf("Unexpected weight for %v: was %g (matched %t), expected %g (matched %t)", c.b, weight, matched, c.expected, c.matched)
+		}
+	}
+}
+
+func TestParseWeightsInvalid(t *testing.T) {
+	for _, in := range []string{
+		"SortBench",
+		"SortBench;x",
+		"SortBench;-1",
+		"Sort(;1",
+	} {
+		if _, err := bench.ParseWeights(strings.NewReader(in), 1); err == nil {
+			t.Fatalf("Expected error for '%s'", in)
+		}
+	}
+
+	if _, err := bench.ParseWeights(strings.NewReader(""), -1); err == nil {
+		t.Fatalf("Expected error for negative default weight")
+	}
+}
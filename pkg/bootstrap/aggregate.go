@@ -0,0 +1,116 @@
+package bootstrap
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	st "github.com/chrstphlbr/pa/pkg/stat"
+)
+
+// GeometricMeanCI computes the CIs of the (weighted) geometric mean of multiple ratios, e.g., the performance changes of multiple benchmarks.
+// metrics are the ratios' metrics and simulatedRatios their bootstrap simulations (in simulation order, see CIRatioSimulations).
+// The simulations are combined per simulation index, i.e., the i-th simulated geometric mean is the geometric mean of the i-th simulated ratios.
+// As every ratio is bootstrapped independently within its own execution hierarchy, this corresponds to a joint bootstrap of all ratios.
+// If the ratios have different numbers of simulations (e.g., due to adaptive simulations), the smallest number is used.
+// weights may be nil, in which case all ratios are weighted equally.
+func GeometricMeanCI(metrics []float64, simulatedRatios [][]float64, weights []float64, significanceLevels []float64) ([]st.CI, error) {
+	l := len(metrics)
+	if l == 0 {
+		return nil, fmt.Errorf("no ratios to aggregate")
+	}
+	if len(simulatedRatios) != l {
+		return nil, fmt.Errorf("number of metrics (%d) and simulated ratios (%d) do not match", l, len(simulatedRatios))
+	}
+	if weights != nil && len(weights) != l {
+		return nil, fmt.Errorf("number of metrics (%d) and weights (%d) do not match", l, len(weights))
+	}
+
+	g := NewGeometricMean()
+	for i, metric := range metrics {
+		w := 1.0
+		if weights != nil {
+			w = weights[i]
+		}
+		if err := g.Add(metric, simulatedRatios[i], w); err != nil {
+			return nil, err
+		}
+	}
+	return g.CIs(significanceLevels)
+}
+
+// ErrNonPositiveRatio is returned for ratios (or their simulations) that are not positive, which have no logarithm for the geometric mean
+var ErrNonPositiveRatio = errors.New("non-positive ratio")
+
+// GeometricMean accumulates ratios and their simulations for a (weighted) geometric mean (see GeometricMeanCI), without keeping the simulations of every ratio in memory.
+// It is not safe for concurrent use.
+type GeometricMean struct {
+	n int
+	// weighted sums of the logarithms of the metrics and of the simulations per simulation index
+	logMetric float64
+	logSims   []float64
+	weights   float64
+}
+
+func NewGeometricMean() *GeometricMean {
+	return &GeometricMean{}
+}
+
+// Add adds a ratio's metric and simulations with a non-negative weight.
+// A ratio with positive weight whose metric or simulations are not positive (or NaN) is not added and returns ErrNonPositiveRatio.
+func (g *GeometricMean) Add(metric float64, simulatedRatios []float64, weight float64) error {
+	if weight < 0 {
+		return fmt.Errorf("negative weight %g", weight)
+	}
+	if len(simulatedRatios) == 0 {
+		return fmt.Errorf("no simulated ratios to aggregate")
+	}
+	if weight > 0 {
+		if !(metric > 0) {
+			return fmt.Errorf("%w: metric %g", ErrNonPositiveRatio, metric)
+		}
+		for i, r := range simulatedRatios {
+			if !(r > 0) {
+				return fmt.Errorf("%w: simulation %d is %g", ErrNonPositiveRatio, i, r)
+			}
+		}
+	}
+
+	if g.n == 0 {
+		g.logSims = make([]float64, len(simulatedRatios))
+	} else if len(simulatedRatios) < len(g.logSims) {
+		g.logSims = g.logSims[:len(simulatedRatios)]
+	}
+	g.n++
+	if weight == 0 {
+		return nil
+	}
+
+	g.logMetric += weight * math.Log(metric)
+	for i := range g.logSims {
+		g.logSims[i] += weight * math.Log(simulatedRatios[i])
+	}
+	g.weights += weight
+	return nil
+}
+
+// Len returns the number of added ratios
+func (g *GeometricMean) Len() int {
+	return g.n
+}
+
+// CIs computes the CIs of the geometric mean of all added ratios
+func (g *GeometricMean) CIs(significanceLevels []float64) ([]st.CI, error) {
+	if g.n == 0 {
+		return nil, fmt.Errorf("no ratios to aggregate")
+	}
+	if g.weights == 0 {
+		return nil, fmt.Errorf("sum of weights is 0")
+	}
+
+	sims := make([]float64, len(g.logSims))
+	for i, ls := range g.logSims {
+		sims[i] = math.Exp(ls / g.weights)
+	}
+	return ci(math.Exp(g.logMetric/g.weights), sims, significanceLevels), nil
+}
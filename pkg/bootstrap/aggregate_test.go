@@ -0,0 +1,174 @@
+package bootstrap_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/chrstphlbr/pa/pkg/bootstrap"
+	"github.com/chrstphlbr/pa/pkg/stat"
+)
+
+func constantRatios(v float64, n int) []float64 {
+	rs := make([]float64, n)
+	for i := range rs {
+		rs[i] = v
+	}
+	return rs
+}
+
+func TestGeometricMeanCI(t *testing.T) {
+	cis, err := bootstrap.GeometricMeanCI([]float64{2, 8}, [][]float64{constantRatios(2, 100), constantRatios(8, 100)}, nil, ciLevels)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(cis) != len(ciLevels) {
+		t.Fatalf("Unexpected number of CIs: %d", len(cis))
+	}
+	for _, ci := range cis {
+		if ci.Metric != 4 || ci.Lower != 4 || ci.Upper != 4 || ci.Simulations != 100 {
+			t.Fatalf("Unexpected CI: %+v", ci)
+		}
+	}
+}
+
+func TestGeometricMeanCIWeighted(t *testing.T) {
+	cis, err := bootstrap.GeometricMeanCI([]float64{2, 8}, [][]float64{constantRatios(2, 100), constantRatios(8, 100)}, []float64{3, 1}, ciLevels)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// (2^3 * 8)^(1/4) = 2^1.5
+	expected := math.Pow(2, 1.5)
+	for _, ci := range cis {
+		if math.Abs(ci.Metric-expected) > 1e-9 || math.Abs(ci.Lower-expected) > 1e-9 || math.Abs(ci.Upper-expected) > 1e-9 {
+			t.Fatalf("Unexpected CI: %+v, expected %f", ci, expected)
+		}
+	}
+}
+
+func TestGeometricMeanCIPerSimulation(t *testing.T) {
+	// simulations are combined per index: sqrt(1*4) = sqrt(4*1) = 2
+	r1 := []float64{1, 4, 1, 4, 1, 4, 1, 4, 1, 4}
+	r2 := []float64{4, 1, 4, 1, 4, 1, 4, 1, 4, 1, 4, 1}
+	cis, err := bootstrap.GeometricMeanCI([]float64{2, 2}, [][]float64{r1, r2}, nil, ciLevels)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, ci := range cis {
+		if ci.Lower != 2 || ci.Upper != 2 {
+			t.Fatalf("Unexpected CI: %+v", ci)
+		}
+		// the smaller number of simulations is used
+		if ci.Simulations != 10 {
+			t.Fatalf("Unexpected number of simulations: %d", ci.Simulations)
+		}
+	}
+	// simulated ratios are not altered
+	if r1[0] != 1 || r1[1] != 4 {
+		t.Fatalf("Simulated ratios altered: %v", r1)
+	}
+}
+
+func TestGeometricMeanCIInvalid(t *testing.T) {
+	rs := [][]float64{constantRatios(2, 10), constantRatios(8, 10)}
+	for _, c := range []struct {
+		metrics []float64
+		ratios  [][]float64
+		weights []float64
+	}{
+		{nil, nil, nil},
+		{[]float64{1}, rs, nil},
+		{[]float64{1, 1}, rs, []float64{1}},
+		{[]float64{1, 1}, rs, []float64{1, -1}},
+		{[]float64{1, 1}, rs, []float64{0, 0}},
+		{[]float64{1, 1}, [][]float64{nil, nil}, nil},
+	} {
+		if _, err := bootstrap.GeometricMeanCI(c.metrics, c.ratios, c.weights, ciLevels); err == nil {
+			t.Fatalf("Expected error for metrics %v, %d simulated ratios, and weights %v", c.metrics, len(c.ratios), c.weights)
+		}
+	}
+}
+
+func TestCIRatioSimulations(t *testing.T) {
+	ea := randomExecution(t, "b1", 5, 10, 100)
+	eb := randomExecution(t, "b1", 5, 10, 110)
+
+	cirs, simRatios, err := bootstrap.CIRatioSimulations(context.Background(), bootstrap.FixedSimulations(200), 2, stat.Mean, ciLevels, ea, eb, bootstrap.ResampleAllLevels(bootstrap.MeanInvocations))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(simRatios) != 200 {
+		t.Fatalf("Unexpected number of simulated ratios: %d", len(simRatios))
+	}
+
+	// the simulated ratios reproduce the ratio CIs
+	cis, err := bootstrap.GeometricMeanCI([]float64{cirs[0].CIRatio.Metric}, [][]float64{simRatios}, nil, ciLevels)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, ci := range cis {
+		cir := cirs[i].CIRatio
+		if math.Abs(ci.Lower-cir.Lower) > 1e-12 || math.Abs(ci.Upper-cir.Upper) > 1e-12 {
+			t.Fatalf("Unexpected CI from simulated ratios: was %+v, expected %+v", ci, cir)
+		}
+	}
+}
+
+func TestGeometricMean(t *testing.T) {
+	g := bootstrap.NewGeometricMean()
+	if _, err := g.CIs(ciLevels); err == nil {
+		t.Fatalf("Expected error for empty geometric mean")
+	}
+
+	for _, r := range []struct {
+		metric float64
+		weight float64
+	}{
+		{2, 3},
+		{8, 1},
+		{100, 0},
+	} {
+		if err := g.Add(r.metric, constantRatios(r.metric, 50), r.weight); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := g.Add(2, constantRatios(2, 50), -1); err == nil {
+		t.Fatalf("Expected error for negative weight")
+	}
+	for _, r := range []struct {
+		metric float64
+		sims   []float64
+	}{
+		{-2, constantRatios(2, 50)},
+		{2, append(constantRatios(2, 49), 0)},
+		{2, append(constantRatios(2, 49), math.NaN())},
+	} {
+		if err := g.Add(r.metric, r.sims, 1); !errors.Is(err, bootstrap.ErrNonPositiveRatio) {
+			t.Fatalf("Expected non-positive ratio error for metric %g, was %v", r.metric, err)
+		}
+	}
+	// a ratio with weight 0 does not contribute and therefore does not have to be positive
+	if err := g.Add(-1, constantRatios(-1, 50), 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if g.Len() != 4 {
+		t.Fatalf("Unexpected number of ratios: %d", g.Len())
+	}
+
+	cis, err := g.CIs(ciLevels)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// ratios with weight 0 do not contribute: (2^3 * 8)^(1/4) = 2^1.5
+	expected := math.Pow(2, 1.5)
+	for _, ci := range cis {
+		if math.Abs(ci.Metric-expected) > 1e-9 || math.Abs(ci.Lower-expected) > 1e-9 || math.Abs(ci.Upper-expected) > 1e-9 || ci.Simulations != 50 {
+			t.Fatalf("Unexpected CI: %+v, expected %f", ci, expected)
+		}
+	}
+}
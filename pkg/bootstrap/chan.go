@@ -1,8 +1,10 @@
 package bootstrap
 
 import (
+	"context"
 	"fmt"
 
+	"github.com/chrstphlbr/pa/internal/ordered"
 	"github.com/chrstphlbr/pa/pkg/bench"
 	"github.com/chrstphlbr/pa/pkg/stat"
 )
@@ -14,30 +16,84 @@ type CIResult struct {
 }
 
 func CIs(c bench.Chan, ciFunc CIFunc) <-chan CIResult {
+	return ConcurrentCIs(context.Background(), c, ciFunc, 1)
+}
+
+// ConcurrentCIs computes the CIs of up to maxInFlight benchmarks concurrently, while the results are still sent in the order of c.
+// If ctx is done, reading from c, computing, and sending results stops and the result channel is closed.
+// Results are only sent while ctx is not done, i.e., the results of benchmarks whose computation was cancelled are discarded.
+func ConcurrentCIs(ctx context.Context, c bench.Chan, ciFunc CIFunc, maxInFlight int) <-chan CIResult {
 	out := make(chan CIResult)
+	o := ordered.New(ctx, maxInFlight, func() { close(out) })
+	submit := func(job func() CIResult) bool {
+		return o.Submit(func() func() {
+			res := job()
+			return func() {
+				select {
+				case out <- res:
+				case <-ctx.Done():
+				}
+			}
+		})
+	}
+
 	go func() {
-		defer close(out)
-		for br := range c {
+		defer o.Close()
+		for {
+			br, ok := receive(ctx, c)
+			if !ok {
+				return
+			}
+
 			switch br.Type {
 			case bench.ExecError:
-				out <- CIResult{
-					Err: br.Err,
-				}
+				ok = submit(func() CIResult {
+					return CIResult{
+						Err: br.Err,
+					}
+				})
 			case bench.ExecNext:
-				out <- CIResult{
-					Benchmark: br.Exec.Benchmark,
-					CIs:       ciFunc(br.Exec),
-				}
+				exec := br.Exec
+				ok = submit(func() CIResult {
+					cis, err := ciFunc(ctx, exec)
+					return CIResult{
+						Benchmark: exec.Benchmark,
+						CIs:       cis,
+						Err:       benchmarkError(exec.Benchmark, err),
+					}
+				})
+			}
+			if !ok {
+				return
 			}
 		}
 	}()
 	return out
 }
 
+// receive receives the next value from c, unless ctx is done or c is closed
+func receive(ctx context.Context, c bench.Chan) (bench.ExecutionValue, bool) {
+	select {
+	case ev, ok := <-c:
+		return ev, ok
+	case <-ctx.Done():
+		return bench.ExecutionValue{}, false
+	}
+}
+
+func benchmarkError(b *bench.B, err error) error {
+	if err == nil {
+		return nil
+	}
+	return fmt.Errorf("%v: %w", b, err)
+}
+
 type CIRatioResult struct {
 	Benchmark *bench.B
 	CIRatios  []stat.CIRatio
-	Err       error
+	// SimulatedRatios are the simulated ratios (B/A) in simulation order, if both versions have a result
+	SimulatedRatios []float64
+	Err             error
 }
 
 type chanNumber int
@@ -47,83 +103,71 @@ const (
 	cNr2
 )
 
-type leftOver struct {
-	ev  *bench.ExecutionValue
-	cnr chanNumber
+func CIRatios(c1, c2 bench.Chan, ciFunc CIFunc, ciRatioFunc CIRatioFunc) <-chan CIRatioResult {
+	return ConcurrentCIRatios(context.Background(), c1, c2, ciFunc, ciRatioFunc, 1)
 }
 
-func CIRatios(c1, c2 bench.Chan, ciFunc CIFunc, ciRatioFunc CIRatioFunc) <-chan CIRatioResult {
+// ConcurrentCIRatios computes the CIs and CI ratios of up to maxInFlight benchmarks concurrently, while the results are still sent in benchmark order.
+// Benchmarks are paired with bench.PairChansContext.
+// If ctx is done, reading from c1 and c2, computing, and sending results stops and the result channel is closed.
+func ConcurrentCIRatios(ctx context.Context, c1, c2 bench.Chan, ciFunc CIFunc, ciRatioFunc CIRatioFunc, maxInFlight int) <-chan CIRatioResult {
 	out := make(chan CIRatioResult)
-
-	go func() {
-		defer close(out)
-
-		var leftOver *leftOver
-		for {
-			var ev1, ev2 *bench.ExecutionValue
-			var ok1, ok2 bool
-
-			if leftOver != nil {
-				// has leftOver -> only read from one channel
-				if leftOver.cnr == cNr1 {
-					ev1 = leftOver.ev
-					ok1 = true
-					ev2v, ok2v := <-c2
-					ev2 = &ev2v
-					ok2 = ok2v
-					// fmt.Fprintf(os.Stderr, "leftOver1: %v  %t\n\t%v  %t\n", ev1, ok1, ev2, ok2)
-				} else if leftOver.cnr == cNr2 {
-					ev2 = leftOver.ev
-					ok2 = true
-					ev1v, ok1v := <-c1
-					ev1 = &ev1v
-					ok1 = ok1v
-					// fmt.Fprintf(os.Stderr, "leftOver2: %v  %t\n\t%v  %t\n", ev1, ok1, ev2, ok2)
-				} else {
-					panic(fmt.Sprintf("Invalid channel number %d", leftOver.cnr))
+	o := ordered.New(ctx, maxInFlight, func() { close(out) })
+	submit := func(job func(ctx context.Context) CIRatioResult) {
+		o.Submit(func() func() {
+			res := job(ctx)
+			return func() {
+				select {
+				case out <- res:
+				case <-ctx.Done():
 				}
-				// needed if files are not ordered
-				leftOver = nil
-			} else {
-				// no leftOver -> read from both channels
-				ev1v, ok1v := <-c1
-				ev1 = &ev1v
-				ok1 = ok1v
-				ev2v, ok2v := <-c2
-				ev2 = &ev2v
-				ok2 = ok2v
 			}
+		})
+	}
 
-			if ok1 && ok2 {
-				// both values received
-				leftOver = handleTwoResults(out, ev1, ev2, ciFunc, ciRatioFunc)
-			} else if ok1 {
-				// only c1 received
-				handleSingleResult(out, ev1, cNr1, ciFunc)
-			} else if ok2 {
-				// only c2 received
-				handleSingleResult(out, ev2, cNr2, ciFunc)
-			} else {
-				// done
-				break
-			}
+	go func() {
+		defer o.Close()
+		for p := range bench.PairChansContext(ctx, c1, c2) {
+			p := p
+			submit(func(ctx context.Context) CIRatioResult {
+				return PairResult(ctx, p, ciFunc, ciRatioFunc)
+			})
 		}
 	}()
 
 	return out
 }
 
-func handleSingleResult(out chan<- CIRatioResult, ev *bench.ExecutionValue, cnr chanNumber, ciFunc CIFunc) {
-	if ev.Type == bench.ExecStart || ev.Type == bench.ExecEnd {
-		return
-	} else if ev.Type == bench.ExecError {
-		out <- CIRatioResult{
-			Err: ev.Err,
+// PairResult computes the CI ratios of the executions of both versions, or the CIs of the only version with an execution
+func PairResult(ctx context.Context, p bench.ExecutionPair, ciFunc CIFunc, ciRatioFunc CIRatioFunc) CIRatioResult {
+	switch {
+	case p.Err != nil:
+		return CIRatioResult{
+			Err: p.Err,
+		}
+	case p.A != nil && p.B != nil:
+		ciRatios, simRatios, err := ciRatioFunc(ctx, p.A, p.B)
+		return CIRatioResult{
+			Benchmark:       p.A.Benchmark,
+			CIRatios:        ciRatios,
+			SimulatedRatios: simRatios,
+			Err:             benchmarkError(p.A.Benchmark, err),
 		}
-		return
+	case p.A != nil:
+		return singleResult(ctx, p.A, cNr1, ciFunc)
+	default:
+		return singleResult(ctx, p.B, cNr2, ciFunc)
 	}
+}
 
-	cis := ciFunc(ev.Exec)
+func singleResult(ctx context.Context, exec *bench.Execution, cnr chanNumber, ciFunc CIFunc) CIRatioResult {
+	cis, err := ciFunc(ctx, exec)
+	if err != nil {
+		return CIRatioResult{
+			Benchmark: exec.Benchmark,
+			Err:       benchmarkError(exec.Benchmark, err),
+		}
+	}
 	ciRatios := make([]stat.CIRatio, len(cis))
 
 	for i, ci := range cis {
@@ -139,70 +183,8 @@ func handleSingleResult(out chan<- CIRatioResult, ev *bench.ExecutionValue, cnr
 		ciRatios[i] = cir
 	}
 
-	out <- CIRatioResult{
-		Benchmark: ev.Exec.Benchmark,
+	return CIRatioResult{
+		Benchmark: exec.Benchmark,
 		CIRatios:  ciRatios,
 	}
 }
-
-func handleTwoResults(out chan<- CIRatioResult, ev1, ev2 *bench.ExecutionValue, ciFunc CIFunc, ciRatioFunc CIRatioFunc) *leftOver {
-	if (ev1.Type == bench.ExecStart || ev1.Type == bench.ExecEnd) && (ev2.Type == bench.ExecStart || ev2.Type == bench.ExecEnd) {
-		// handle both started or both done
-		return nil
-	}
-
-	if ev1.Type == bench.ExecError {
-		// channel 1 sent error
-		out <- CIRatioResult{
-			Err: ev1.Err,
-		}
-		handleSingleResult(out, ev2, cNr2, ciFunc)
-	} else if ev2.Type == bench.ExecError {
-		// channel 1 sent error
-		out <- CIRatioResult{
-			Err: ev2.Err,
-		}
-		handleSingleResult(out, ev1, cNr1, ciFunc)
-	} else if ev1.Type == bench.ExecEnd {
-		// channel 1 is done
-		handleSingleResult(out, ev2, cNr2, ciFunc)
-	} else if ev2.Type == bench.ExecEnd {
-		// channel 2 is done
-		handleSingleResult(out, ev1, cNr1, ciFunc)
-	} else {
-		// both channels have a result
-		return handleTwoValidResults(out, ev1, ev2, ciFunc, ciRatioFunc)
-	}
-
-	return nil
-}
-
-func handleTwoValidResults(out chan<- CIRatioResult, ev1, ev2 *bench.ExecutionValue, ciFunc CIFunc, ciRatioFunc CIRatioFunc) *leftOver {
-	ex1 := ev1.Exec
-	ex2 := ev2.Exec
-
-	cmp := ex1.Benchmark.Compare(ex2.Benchmark)
-
-	switch cmp {
-	case 0:
-		out <- CIRatioResult{
-			Benchmark: ex1.Benchmark,
-			CIRatios:  ciRatioFunc(ex1, ex2),
-		}
-	case -1:
-		handleSingleResult(out, ev1, cNr1, ciFunc)
-		return &leftOver{
-			ev:  ev2,
-			cnr: cNr2,
-		}
-	case 1:
-		handleSingleResult(out, ev2, cNr2, ciFunc)
-		return &leftOver{
-			ev:  ev1,
-			cnr: cNr1,
-		}
-	default:
-		panic(fmt.Sprintf("Invalid result from Compare: %d", cmp))
-	}
-	return nil
-}
@@ -4,9 +4,9 @@ import (
 	"fmt"
 	"testing"
 
-	"github.com/chrstphlbr/pa/pkg/stat"
 	"github.com/chrstphlbr/pa/pkg/bench"
 	"github.com/chrstphlbr/pa/pkg/bootstrap"
+	"github.com/chrstphlbr/pa/pkg/stat"
 )
 
 var ciLevels = []float64{0.05, 0.01}
@@ -18,8 +18,10 @@ func checkChannelEmpty(t *testing.T, rc <-chan bootstrap.CIRatioResult) {
 	}
 }
 
-func ciFuncs(sim, nrWorkers int, sf stat.StatisticFunc, sls []float64, sampler bench.InvocationSampler) (bootstrap.CIFunc, bootstrap.CIRatioFunc) {
-	return bootstrap.CIFuncSetup(sim, nrWorkers, sf, sls, sampler), bootstrap.CIRatioFuncSetup(sim, nrWorkers, sf, sls, sampler)
+func ciFuncs(sim, nrWorkers int, sf stat.StatisticFunc, sls []float64, sampler bootstrap.InvocationSampling) (bootstrap.CIFunc, bootstrap.CIRatioFunc) {
+	sims := bootstrap.FixedSimulations(sim)
+	plan := bootstrap.ResampleAllLevels(sampler)
+	return bootstrap.CIFuncSetup(sims, nrWorkers, sf, sls, plan), bootstrap.CIRatioFuncSetup(sims, nrWorkers, sf, sls, plan)
 }
 func TestCIRatiosEmpty(t *testing.T) {
 	bc1 := make(bench.Chan)
@@ -27,7 +29,7 @@ func TestCIRatiosEmpty(t *testing.T) {
 	bc2 := make(bench.Chan)
 	close(bc2)
 
-	cif, cirf := ciFuncs(1, 1, stat.Mean, ciLevels, bench.AllInvocations)
+	cif, cirf := ciFuncs(1, 1, stat.Mean, ciLevels, bootstrap.AllInvocations)
 	rc := bootstrap.CIRatios(bc1, bc2, cif, cirf)
 
 	checkChannelEmpty(t, rc)
@@ -56,7 +58,7 @@ func TestCIRatiosNoValues(t *testing.T) {
 		}
 	}()
 
-	cif, cirf := ciFuncs(1, 1, stat.Mean, ciLevels, bench.AllInvocations)
+	cif, cirf := ciFuncs(1, 1, stat.Mean, ciLevels, bootstrap.AllInvocations)
 	rc := bootstrap.CIRatios(bc1, bc2, cif, cirf)
 
 	checkChannelEmpty(t, rc)
@@ -91,7 +93,7 @@ func ciRatiosError(t *testing.T, side int) {
 	bc2, execs := createChannel(0, nrExecs)
 
 	var rc <-chan bootstrap.CIRatioResult
-	cif, cirf := ciFuncs(2, 1, stat.Mean, ciLevels, bench.AllInvocations)
+	cif, cirf := ciFuncs(2, 1, stat.Mean, ciLevels, bootstrap.AllInvocations)
 	if side == 1 {
 		rc = bootstrap.CIRatios(bc1, bc2, cif, cirf)
 	} else if side == 2 {
@@ -167,16 +169,18 @@ func checkOneSided(t *testing.T, rc <-chan bootstrap.CIRatioResult, execs []*ben
 
 		ecis := []stat.CI{
 			stat.CI{
-				Metric: 4,
-				Level:  0.95,
-				Lower:  4,
-				Upper:  4,
+				Metric:      4,
+				Level:       0.95,
+				Lower:       4,
+				Upper:       4,
+				Simulations: 2,
 			},
 			stat.CI{
-				Metric: 4,
-				Level:  0.99,
-				Lower:  4,
-				Upper:  4,
+				Metric:      4,
+				Level:       0.99,
+				Lower:       4,
+				Upper:       4,
+				Simulations: 2,
 			},
 		}
 
@@ -223,31 +227,35 @@ func checkMerged(t *testing.T, rc <-chan bootstrap.CIRatioResult, ex1, ex2 []*be
 
 		ecis := []stat.CI{
 			stat.CI{
-				Metric: 4,
-				Level:  0.95,
-				Lower:  4,
-				Upper:  4,
+				Metric:      4,
+				Level:       0.95,
+				Lower:       4,
+				Upper:       4,
+				Simulations: 2,
 			},
 			stat.CI{
-				Metric: 4,
-				Level:  0.99,
-				Lower:  4,
-				Upper:  4,
+				Metric:      4,
+				Level:       0.99,
+				Lower:       4,
+				Upper:       4,
+				Simulations: 2,
 			},
 		}
 
 		eciRatios := []stat.CI{
 			stat.CI{
-				Metric: 1,
-				Level:  0.95,
-				Lower:  1,
-				Upper:  1,
+				Metric:      1,
+				Level:       0.95,
+				Lower:       1,
+				Upper:       1,
+				Simulations: 2,
 			},
 			stat.CI{
-				Metric: 1,
-				Level:  0.99,
-				Lower:  1,
-				Upper:  1,
+				Metric:      1,
+				Level:       0.99,
+				Lower:       1,
+				Upper:       1,
+				Simulations: 2,
 			},
 		}
 
@@ -262,7 +270,7 @@ func TestCIRatios1empty(t *testing.T) {
 	bc2 := make(bench.Chan)
 	close(bc2)
 
-	cif, cirf := ciFuncs(2, 1, stat.Mean, ciLevels, bench.AllInvocations)
+	cif, cirf := ciFuncs(2, 1, stat.Mean, ciLevels, bootstrap.AllInvocations)
 	rc := bootstrap.CIRatios(bc1, bc2, cif, cirf)
 
 	// sole channel 2
@@ -276,7 +284,7 @@ func TestCIRatios2empty(t *testing.T) {
 	close(bc1)
 	bc2, ex2 := createChannel(0, 10)
 
-	cif, cirf := ciFuncs(2, 1, stat.Mean, ciLevels, bench.AllInvocations)
+	cif, cirf := ciFuncs(2, 1, stat.Mean, ciLevels, bootstrap.AllInvocations)
 	rc := bootstrap.CIRatios(bc1, bc2, cif, cirf)
 
 	// sole channel 2
@@ -289,7 +297,7 @@ func TestCIRatiosValues1earlier(t *testing.T) {
 	bc1, ex1 := createChannel(0, 7)
 	bc2, ex2 := createChannel(0, 10)
 
-	cif, cirf := ciFuncs(2, 1, stat.Mean, ciLevels, bench.AllInvocations)
+	cif, cirf := ciFuncs(2, 1, stat.Mean, ciLevels, bootstrap.AllInvocations)
 	rc := bootstrap.CIRatios(bc1, bc2, cif, cirf)
 
 	// check ratios
@@ -305,7 +313,7 @@ func TestCIRatiosValues2earlier(t *testing.T) {
 	bc1, ex1 := createChannel(0, 10)
 	bc2, ex2 := createChannel(0, 7)
 
-	cif, cirf := ciFuncs(2, 1, stat.Mean, ciLevels, bench.AllInvocations)
+	cif, cirf := ciFuncs(2, 1, stat.Mean, ciLevels, bootstrap.AllInvocations)
 	rc := bootstrap.CIRatios(bc1, bc2, cif, cirf)
 
 	// check ratios
@@ -321,7 +329,7 @@ func TestCIRatiosValues1later(t *testing.T) {
 	bc1, ex1 := createChannel(3, 10)
 	bc2, ex2 := createChannel(0, 10)
 
-	cif, cirf := ciFuncs(2, 1, stat.Mean, ciLevels, bench.AllInvocations)
+	cif, cirf := ciFuncs(2, 1, stat.Mean, ciLevels, bootstrap.AllInvocations)
 	rc := bootstrap.CIRatios(bc1, bc2, cif, cirf)
 
 	// sole channel
@@ -337,7 +345,7 @@ func TestCIRatiosValues2later(t *testing.T) {
 	bc1, ex1 := createChannel(0, 10)
 	bc2, ex2 := createChannel(3, 10)
 
-	cif, cirf := ciFuncs(2, 1, stat.Mean, ciLevels, bench.AllInvocations)
+	cif, cirf := ciFuncs(2, 1, stat.Mean, ciLevels, bootstrap.AllInvocations)
 	rc := bootstrap.CIRatios(bc1, bc2, cif, cirf)
 
 	// sole channel
@@ -353,7 +361,7 @@ func TestCIRatiosValues1middle(t *testing.T) {
 	bc1, ex1 := createChannel(3, 7)
 	bc2, ex2 := createChannel(0, 10)
 
-	cif, cirf := ciFuncs(2, 1, stat.Mean, ciLevels, bench.AllInvocations)
+	cif, cirf := ciFuncs(2, 1, stat.Mean, ciLevels, bootstrap.AllInvocations)
 	rc := bootstrap.CIRatios(bc1, bc2, cif, cirf)
 
 	// sole channel 2 start
@@ -372,7 +380,7 @@ func TestCIRatiosValues2middle(t *testing.T) {
 	bc1, ex1 := createChannel(0, 10)
 	bc2, ex2 := createChannel(3, 7)
 
-	cif, cirf := ciFuncs(2, 1, stat.Mean, ciLevels, bench.AllInvocations)
+	cif, cirf := ciFuncs(2, 1, stat.Mean, ciLevels, bootstrap.AllInvocations)
 	rc := bootstrap.CIRatios(bc1, bc2, cif, cirf)
 
 	// sole channel 1 start
@@ -391,7 +399,7 @@ func TestCIRatiosValues(t *testing.T) {
 	bc1, ex1 := createChannel(0, 7)
 	bc2, ex2 := createChannel(5, 10)
 
-	cif, cirf := ciFuncs(2, 1, stat.Mean, ciLevels, bench.AllInvocations)
+	cif, cirf := ciFuncs(2, 1, stat.Mean, ciLevels, bootstrap.AllInvocations)
 	rc := bootstrap.CIRatios(bc1, bc2, cif, cirf)
 
 	// sole channel 1
@@ -428,7 +436,7 @@ func TestCIRatiosValuesGap1(t *testing.T) {
 
 	bc2 := appendChannels(bc21, bc22)
 
-	cif, cirf := ciFuncs(2, 1, stat.Mean, ciLevels, bench.AllInvocations)
+	cif, cirf := ciFuncs(2, 1, stat.Mean, ciLevels, bootstrap.AllInvocations)
 	rc := bootstrap.CIRatios(bc1, bc2, cif, cirf)
 
 	// sole channel 2
@@ -450,7 +458,7 @@ func TestCIRatiosValuesGap2(t *testing.T) {
 
 	bc1 := appendChannels(bc11, bc12)
 
-	cif, cirf := ciFuncs(2, 1, stat.Mean, ciLevels, bench.AllInvocations)
+	cif, cirf := ciFuncs(2, 1, stat.Mean, ciLevels, bootstrap.AllInvocations)
 	rc := bootstrap.CIRatios(bc1, bc2, cif, cirf)
 
 	// sole channel 1
@@ -474,7 +482,7 @@ func TestCIRatiosValuesInterleaved1(t *testing.T) {
 	bc1 := appendChannels(bc11, bc12)
 	bc2 := appendChannels(bc21, bc22)
 
-	cif, cirf := ciFuncs(2, 1, stat.Mean, ciLevels, bench.AllInvocations)
+	cif, cirf := ciFuncs(2, 1, stat.Mean, ciLevels, bootstrap.AllInvocations)
 	rc := bootstrap.CIRatios(bc1, bc2, cif, cirf)
 
 	// sole channel 1
@@ -501,7 +509,7 @@ func TestCIRatiosValuesInterleaved2(t *testing.T) {
 	bc1 := appendChannels(bc11, bc12)
 	bc2 := appendChannels(bc21, bc22)
 
-	cif, cirf := ciFuncs(2, 1, stat.Mean, ciLevels, bench.AllInvocations)
+	cif, cirf := ciFuncs(2, 1, stat.Mean, ciLevels, bootstrap.AllInvocations)
 	rc := bootstrap.CIRatios(bc1, bc2, cif, cirf)
 
 	// sole channel 2
@@ -523,7 +531,7 @@ func TestCIRatiosOverlap1(t *testing.T) {
 	bc1, ex1 := createChannel(0, 7)
 	bc2, ex2 := createChannel(4, 10)
 
-	cif, cirf := ciFuncs(2, 1, stat.Mean, ciLevels, bench.AllInvocations)
+	cif, cirf := ciFuncs(2, 1, stat.Mean, ciLevels, bootstrap.AllInvocations)
 	rc := bootstrap.CIRatios(bc1, bc2, cif, cirf)
 
 	checkOneSided(t, rc, ex1, 0, 4, 0, 1)
@@ -539,7 +547,7 @@ func TestCIRatiosOverlap2(t *testing.T) {
 	bc2, ex2 := createChannel(0, 7)
 	bc1, ex1 := createChannel(4, 10)
 
-	cif, cirf := ciFuncs(2, 1, stat.Mean, ciLevels, bench.AllInvocations)
+	cif, cirf := ciFuncs(2, 1, stat.Mean, ciLevels, bootstrap.AllInvocations)
 	rc := bootstrap.CIRatios(bc1, bc2, cif, cirf)
 
 	checkOneSided(t, rc, ex2, 0, 4, 0, 2)
@@ -560,7 +568,7 @@ func TestCIRatiosValuesInterleavedOverlap1(t *testing.T) {
 	bc1 := appendChannels(bc11, bc12)
 	bc2 := appendChannels(bc21, bc22)
 
-	cif, cirf := ciFuncs(2, 1, stat.Mean, ciLevels, bench.AllInvocations)
+	cif, cirf := ciFuncs(2, 1, stat.Mean, ciLevels, bootstrap.AllInvocations)
 	rc := bootstrap.CIRatios(bc1, bc2, cif, cirf)
 
 	// sole channel 1
@@ -590,7 +598,7 @@ func TestCIRatiosValuesInterleavedOverlap2(t *testing.T) {
 	bc1 := appendChannels(bc11, bc12)
 	bc2 := appendChannels(bc21, bc22)
 
-	cif, cirf := ciFuncs(2, 1, stat.Mean, ciLevels, bench.AllInvocations)
+	cif, cirf := ciFuncs(2, 1, stat.Mean, ciLevels, bootstrap.AllInvocations)
 	rc := bootstrap.CIRatios(bc1, bc2, cif, cirf)
 
 	// sole channel 2
@@ -621,7 +629,7 @@ func TestCIRatiosValuesInterleavedMultipleOverlap1(t *testing.T) {
 	bc1 := appendChannels(bc11, bc12)
 	bc2 := appendChannels(bc21, bc22, bc23)
 
-	cif, cirf := ciFuncs(2, 1, stat.Mean, ciLevels, bench.AllInvocations)
+	cif, cirf := ciFuncs(2, 1, stat.Mean, ciLevels, bootstrap.AllInvocations)
 	rc := bootstrap.CIRatios(bc1, bc2, cif, cirf)
 
 	// merged
@@ -652,7 +660,7 @@ func TestCIRatiosValuesInterleavedMultipleOverlap2(t *testing.T) {
 	bc1 := appendChannels(bc11, bc12, bc13)
 	bc2 := appendChannels(bc21, bc22)
 
-	cif, cirf := ciFuncs(2, 1, stat.Mean, ciLevels, bench.AllInvocations)
+	cif, cirf := ciFuncs(2, 1, stat.Mean, ciLevels, bootstrap.AllInvocations)
 	rc := bootstrap.CIRatios(bc1, bc2, cif, cirf)
 
 	// merged
@@ -682,7 +690,7 @@ func TestCIRatiosTwoOrdered(t *testing.T) {
 	bc1 := appendChannels(bc11, bc12)
 	bc2 := appendChannels(bc21, bc22)
 
-	cif, cirf := ciFuncs(2, 1, stat.Mean, ciLevels, bench.AllInvocations)
+	cif, cirf := ciFuncs(2, 1, stat.Mean, ciLevels, bootstrap.AllInvocations)
 	rc := bootstrap.CIRatios(bc1, bc2, cif, cirf)
 
 	checkMerged(t, rc, ex11, ex21, 0, 10, 0, 0)
@@ -13,7 +13,7 @@ func TestCIsEmpty(t *testing.T) {
 	bc := make(bench.Chan)
 	close(bc)
 
-	cif, _ := ciFuncs(1, 1, stat.Mean, []float64{0.05, 0.01}, bench.AllInvocations)
+	cif, _ := ciFuncs(1, 1, stat.Mean, []float64{0.05, 0.01}, bootstrap.AllInvocations)
 	rc := bootstrap.CIs(bc, cif)
 
 	_, ok := <-rc
@@ -34,7 +34,7 @@ func TestCIsNoValues(t *testing.T) {
 		}
 	}()
 
-	cif, _ := ciFuncs(1, 1, stat.Mean, []float64{0.05, 0.01}, bench.AllInvocations)
+	cif, _ := ciFuncs(1, 1, stat.Mean, []float64{0.05, 0.01}, bootstrap.AllInvocations)
 	rc := bootstrap.CIs(bc, cif)
 
 	_, ok := <-rc
@@ -64,7 +64,7 @@ func TestCIsError(t *testing.T) {
 		}
 	}()
 
-	cif, _ := ciFuncs(1, 1, stat.Mean, []float64{0.05, 0.01}, bench.AllInvocations)
+	cif, _ := ciFuncs(1, 1, stat.Mean, []float64{0.05, 0.01}, bootstrap.AllInvocations)
 	rc := bootstrap.CIs(bc, cif)
 
 	ev, ok := <-rc
@@ -135,7 +135,7 @@ func TestCIsValues(t *testing.T) {
 
 	sigLevels := []float64{0.05, 0.01}
 
-	cif, _ := ciFuncs(2, 1, stat.Mean, sigLevels, bench.AllInvocations)
+	cif, _ := ciFuncs(2, 1, stat.Mean, sigLevels, bootstrap.AllInvocations)
 	rc := bootstrap.CIs(bc, cif)
 
 	for i, e := range execs {
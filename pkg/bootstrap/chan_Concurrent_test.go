@@ -0,0 +1,106 @@
+package bootstrap_test
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/chrstphlbr/pa/pkg/bench"
+	"github.com/chrstphlbr/pa/pkg/bootstrap"
+	"github.com/chrstphlbr/pa/pkg/stat"
+)
+
+// inFlightCounter tracks the maximum number of concurrent calls
+type inFlightCounter struct {
+	l       sync.Mutex
+	current int
+	max     int
+}
+
+func (c *inFlightCounter) call() {
+	c.l.Lock()
+	c.current++
+	if c.current > c.max {
+		c.max = c.current
+	}
+	c.l.Unlock()
+
+	// random delay so that results finish out of order
+	time.Sleep(time.Duration(rand.Intn(5)) * time.Millisecond)
+
+	c.l.Lock()
+	c.current--
+	c.l.Unlock()
+}
+
+func (c *inFlightCounter) check(t *testing.T, maxInFlight int) {
+	if c.max > maxInFlight {
+		t.Fatalf("Too many concurrent benchmarks: was %d, expected at most %d", c.max, maxInFlight)
+	}
+	if c.max < 2 {
+		t.Fatalf("Benchmarks not computed concurrently")
+	}
+}
+
+func TestConcurrentCIsOrdered(t *testing.T) {
+	const maxInFlight = 4
+	bc, execs := createChannel(0, 30)
+
+	var cnt inFlightCounter
+	cif, _ := ciFuncs(2, 1, stat.Mean, ciLevels, bootstrap.AllInvocations)
+	slowCif := func(ctx context.Context, e bench.ExecutionSlice) ([]stat.CI, error) {
+		cnt.call()
+		return cif(ctx, e)
+	}
+
+	rc := bootstrap.ConcurrentCIs(context.Background(), bc, slowCif, maxInFlight)
+
+	for i, e := range execs {
+		ev, ok := <-rc
+		if !ok {
+			t.Fatalf("Expected value from channel, but did not receive one (pos: %d, bench: %v)", i, e.Benchmark)
+		}
+		if ev.Err != nil {
+			t.Fatalf("Received error: %v", ev.Err)
+		}
+		if !ev.Benchmark.Equals(e.Benchmark) {
+			t.Fatalf("Expected benchmark %v, got %v (pos: %d)", e.Benchmark, ev.Benchmark, i)
+		}
+	}
+
+	_, ok := <-rc
+	if ok {
+		t.Fatalf("Result channel has values")
+	}
+
+	cnt.check(t, maxInFlight)
+}
+
+func TestConcurrentCIRatiosOrdered(t *testing.T) {
+	const maxInFlight = 3
+	// b0-b4 only in c1, b5-b19 in both, b20-b24 only in c2
+	bc1, ex1 := createChannel(0, 20)
+	bc2, ex2 := createChannel(5, 25)
+
+	var cnt inFlightCounter
+	cif, cirf := ciFuncs(2, 1, stat.Mean, ciLevels, bootstrap.AllInvocations)
+	slowCif := func(ctx context.Context, e bench.ExecutionSlice) ([]stat.CI, error) {
+		cnt.call()
+		return cif(ctx, e)
+	}
+	slowCirf := func(ctx context.Context, e1, e2 bench.ExecutionSlice) ([]stat.CIRatio, []float64, error) {
+		cnt.call()
+		return cirf(ctx, e1, e2)
+	}
+
+	rc := bootstrap.ConcurrentCIRatios(context.Background(), bc1, bc2, slowCif, slowCirf, maxInFlight)
+
+	checkOneSided(t, rc, ex1, 0, 5, 0, 1)
+	checkMerged(t, rc, ex1, ex2, 5, 20, 0, -5)
+	checkOneSided(t, rc, ex2, 15, 20, 0, 2)
+	checkChannelEmpty(t, rc)
+
+	cnt.check(t, maxInFlight)
+}
@@ -0,0 +1,168 @@
+package bootstrap_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/chrstphlbr/pa/pkg/bench"
+	"github.com/chrstphlbr/pa/pkg/bootstrap"
+	"github.com/chrstphlbr/pa/pkg/stat"
+)
+
+// contextChannel is like createChannel, but stops sending if ctx is done
+func contextChannel(ctx context.Context, t *testing.T, from, to int) bench.Chan {
+	bc := make(bench.Chan)
+	go func() {
+		defer close(bc)
+		evs := []bench.ExecutionValue{{Type: bench.ExecStart}}
+		for i := from; i < to; i++ {
+			evs = append(evs, bench.ExecutionValue{
+				Type: bench.ExecNext,
+				Exec: randomExecution(t, fmt.Sprintf("b%d", i), 5, 10, 100),
+			})
+		}
+		evs = append(evs, bench.ExecutionValue{Type: bench.ExecEnd})
+
+		for _, ev := range evs {
+			select {
+			case bc <- ev:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return bc
+}
+
+// checkNoGoroutineLeak waits until the number of goroutines dropped to at most before
+func checkNoGoroutineLeak(t *testing.T, before int) {
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		now := runtime.NumGoroutine()
+		if now <= before {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Goroutines leaked: %d before, %d after cancellation", before, now)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestCIContextCancelled(t *testing.T) {
+	e := randomExecution(t, "b1", 5, 10, 100)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := bootstrap.CI(ctx, bootstrap.FixedSimulations(1000), 2, stat.Mean, ciLevels, e, bootstrap.ResampleAllLevels(bootstrap.MeanInvocations))
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+
+	_, err = bootstrap.CIRatio(ctx, bootstrap.FixedSimulations(1000), 2, stat.Mean, ciLevels, e, e, bootstrap.ResampleAllLevels(bootstrap.MeanInvocations))
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+}
+
+func TestCIFuncWithTimeout(t *testing.T) {
+	bc, _ := createChannel(0, 3)
+
+	cif, _ := ciFuncs(5000000, 1, stat.Median, ciLevels, bootstrap.AllInvocations)
+	rc := bootstrap.CIs(bc, bootstrap.CIFuncWithTimeout(cif, time.Millisecond))
+
+	var cnt int
+	for res := range rc {
+		cnt++
+		if !errors.Is(res.Err, context.DeadlineExceeded) {
+			t.Fatalf("Expected context.DeadlineExceeded, got %v", res.Err)
+		}
+		if res.Benchmark == nil {
+			t.Fatalf("Expected benchmark of timed out result")
+		}
+	}
+	if cnt != 3 {
+		t.Fatalf("Expected 3 results, got %d", cnt)
+	}
+}
+
+func TestConcurrentCIsCancel(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cif, _ := ciFuncs(10000, 2, stat.Mean, ciLevels, bootstrap.MeanInvocations)
+	rc := bootstrap.ConcurrentCIs(ctx, contextChannel(ctx, t, 0, 100), cif, 4)
+
+	res, ok := <-rc
+	if !ok || res.Err != nil {
+		t.Fatalf("Expected first result, got %v (ok = %t)", res, ok)
+	}
+	// consumer stops reading
+	cancel()
+
+	checkNoGoroutineLeak(t, before)
+}
+
+func TestConcurrentCIRatiosCancel(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cif, cirf := ciFuncs(10000, 2, stat.Mean, ciLevels, bootstrap.MeanInvocations)
+	rc := bootstrap.ConcurrentCIRatios(ctx, contextChannel(ctx, t, 0, 100), contextChannel(ctx, t, 50, 150), cif, cirf, 4)
+
+	res, ok := <-rc
+	if !ok || res.Err != nil {
+		t.Fatalf("Expected first result, got %v (ok = %t)", res, ok)
+	}
+	// consumer stops reading
+	cancel()
+
+	checkNoGoroutineLeak(t, before)
+}
+
+func TestConcurrentCIsCancelClosesResults(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cif, _ := ciFuncs(10000, 2, stat.Mean, ciLevels, bootstrap.MeanInvocations)
+	rc := bootstrap.ConcurrentCIs(ctx, contextChannel(ctx, t, 0, 100), cif, 4)
+
+	<-rc
+	cancel()
+
+	var cnt int
+	for range rc {
+		cnt++
+	}
+	if cnt >= 99 {
+		t.Fatalf("Expected cancelled result channel, but received all results")
+	}
+}
+
+func TestCIRatiosTruncated(t *testing.T) {
+	// c1 is closed before its end, e.g., because its input was cancelled
+	bc1, _ := createChannelStartEnd(0, 5, true, false)
+	bc2, _ := createChannel(0, 10)
+
+	cif, cirf := ciFuncs(2, 1, stat.Mean, ciLevels, bootstrap.AllInvocations)
+	rc := bootstrap.CIRatios(bc1, bc2, cif, cirf)
+
+	var cnt int
+	for res := range rc {
+		if res.Err != nil {
+			t.Fatalf("Received error: %v", res.Err)
+		}
+		for _, cir := range res.CIRatios {
+			if cir.CIRatio.Simulations == 0 {
+				t.Fatalf("Expected only paired results, got one-sided result for %v", res.Benchmark)
+			}
+		}
+		cnt++
+	}
+	if cnt != 5 {
+		t.Fatalf("Expected 5 results, got %d", cnt)
+	}
+}
@@ -1,6 +1,7 @@
 package bootstrap
 
 import (
+	"context"
 	"fmt"
 	"math"
 	"sort"
@@ -9,46 +10,125 @@ import (
 
 	"github.com/chrstphlbr/pa/pkg/bench"
 	st "github.com/chrstphlbr/pa/pkg/stat"
-
-	"golang.org/x/exp/rand"
-	"gonum.org/v1/gonum/stat"
-	"gonum.org/v1/gonum/stat/distuv"
-	"gonum.org/v1/gonum/stat/sampleuv"
 )
 
-type CIFunc = func(bench.ExecutionSlice) []st.CI
-type CIRatioFunc = func(bench.ExecutionSlice, bench.ExecutionSlice) []st.CIRatio
+type CIFunc = func(context.Context, bench.ExecutionSlice) ([]st.CI, error)
+
+// CIRatioFunc returns the CI ratios (per significance level) and the simulated ratios (in simulation order)
+type CIRatioFunc = func(context.Context, bench.ExecutionSlice, bench.ExecutionSlice) ([]st.CIRatio, []float64, error)
+
+// cancellationCheck is the number of simulations a worker performs between checks whether its context is done
+const cancellationCheck = 64
+
+const DefaultBatchSize = 1000
 
-func CIRatioFuncSetup(iters int, maxNrWorkers int, statFunc st.StatisticFunc, significanceLevels []float64, sampler bench.InvocationSampler) CIRatioFunc {
-	return func(executionsA bench.ExecutionSlice, executionsB bench.ExecutionSlice) []st.CIRatio {
-		return CIRatio(iters, maxNrWorkers, statFunc, significanceLevels, executionsA, executionsB, sampler)
+// Simulations defines how many bootstrap simulations are performed.
+// If Tolerance is 0, exactly Max simulations are performed.
+// Otherwise, simulations are performed in batches of BatchSize until the CI bounds of all significance levels change by at most Tolerance (relative to the previous batch) or Max simulations are reached.
+type Simulations struct {
+	Max       int
+	BatchSize int
+	Tolerance float64
+}
+
+func FixedSimulations(iters int) Simulations {
+	return Simulations{
+		Max: iters,
 	}
 }
 
-func CIFuncSetup(iters int, maxNrWorkers int, statFunc st.StatisticFunc, significanceLevels []float64, sampler bench.InvocationSampler) CIFunc {
-	return func(executions bench.ExecutionSlice) []st.CI {
-		return CI(iters, maxNrWorkers, statFunc, significanceLevels, executions, sampler)
+func AdaptiveSimulations(max, batchSize int, tolerance float64) Simulations {
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	return Simulations{
+		Max:       max,
+		BatchSize: batchSize,
+		Tolerance: tolerance,
 	}
 }
 
-func CIRatio(iters int, maxNrWorkers int, statisticFunc st.StatisticFunc, significanceLevels []float64, executionsA bench.ExecutionSlice, executionsB bench.ExecutionSlice, sampler bench.InvocationSampler) []st.CIRatio {
-	metricA, simStatA := metricAndSimulations(iters, maxNrWorkers, statisticFunc, executionsA, sampler)
-	ciAs := ci(metricA, simStatA, significanceLevels)
+func (s Simulations) Adaptive() bool {
+	return s.Tolerance > 0
+}
 
-	metricB, simStatB := metricAndSimulations(iters, maxNrWorkers, statisticFunc, executionsB, sampler)
-	ciBs := ci(metricB, simStatB, significanceLevels)
+// next returns the number of simulations of the next batch given that `done` simulations were already performed
+func (s Simulations) next(done int) int {
+	if !s.Adaptive() {
+		return s.Max - done
+	}
+	if rest := s.Max - done; rest < s.BatchSize {
+		return rest
+	}
+	return s.BatchSize
+}
+
+func CIRatioFuncSetup(sims Simulations, maxNrWorkers int, statFunc st.StatisticFunc, significanceLevels []float64, plan ResamplingPlan) CIRatioFunc {
+	return func(ctx context.Context, executionsA bench.ExecutionSlice, executionsB bench.ExecutionSlice) ([]st.CIRatio, []float64, error) {
+		return CIRatioSimulations(ctx, sims, maxNrWorkers, statFunc, significanceLevels, executionsA, executionsB, plan)
+	}
+}
 
-	lSimA := len(simStatA)
-	lSimB := len(simStatB)
-	if lSimA != lSimB {
-		panic(fmt.Sprintf("Simulated statistics not of same size: len(a) = %d; len(b) = %d", lSimA, lSimB))
+func CIFuncSetup(sims Simulations, maxNrWorkers int, statFunc st.StatisticFunc, significanceLevels []float64, plan ResamplingPlan) CIFunc {
+	return func(ctx context.Context, executions bench.ExecutionSlice) ([]st.CI, error) {
+		return CI(ctx, sims, maxNrWorkers, statFunc, significanceLevels, executions, plan)
 	}
+}
+
+// CIFuncWithTimeout limits the time of every CI computation of f to timeout
+func CIFuncWithTimeout(f CIFunc, timeout time.Duration) CIFunc {
+	if timeout <= 0 {
+		return f
+	}
+	return func(ctx context.Context, executions bench.ExecutionSlice) ([]st.CI, error) {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return f(ctx, executions)
+	}
+}
+
+// CIRatioFuncWithTimeout limits the time of every CI ratio computation of f to timeout
+func CIRatioFuncWithTimeout(f CIRatioFunc, timeout time.Duration) CIRatioFunc {
+	if timeout <= 0 {
+		return f
+	}
+	return func(ctx context.Context, executionsA bench.ExecutionSlice, executionsB bench.ExecutionSlice) ([]st.CIRatio, []float64, error) {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return f(ctx, executionsA, executionsB)
+	}
+}
+
+func CIRatio(ctx context.Context, sims Simulations, maxNrWorkers int, statisticFunc st.StatisticFunc, significanceLevels []float64, executionsA bench.ExecutionSlice, executionsB bench.ExecutionSlice, plan ResamplingPlan) ([]st.CIRatio, error) {
+	cirs, _, err := CIRatioSimulations(ctx, sims, maxNrWorkers, statisticFunc, significanceLevels, executionsA, executionsB, plan)
+	return cirs, err
+}
+
+// CIRatioSimulations is CIRatio but additionally returns the simulated ratios (B/A) in simulation order, e.g., to combine the ratios of multiple benchmarks per simulation (see GeometricMeanCI)
+func CIRatioSimulations(ctx context.Context, sims Simulations, maxNrWorkers int, statisticFunc st.StatisticFunc, significanceLevels []float64, executionsA bench.ExecutionSlice, executionsB bench.ExecutionSlice, plan ResamplingPlan) ([]st.CIRatio, []float64, error) {
+	var metricA, metricB float64
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		metricA = benchMetric(executionsA, statisticFunc)
+		metricB = benchMetric(executionsB, statisticFunc)
+		wg.Done()
+	}()
+
+	simStatA, simStatB, ratios, err := pairedSimulatedStatistics(ctx, sims, maxNrWorkers, statisticFunc, significanceLevels, newResampler(executionsA, plan), newResampler(executionsB, plan))
+
+	wg.Wait()
 
-	ratios := make([]float64, 0, lSimA)
-	for i := 0; i < iters; i++ {
-		ratio := simStatB[i] / simStatA[i]
-		ratios = append(ratios, ratio)
+	if err != nil {
+		return nil, nil, err
 	}
+
+	// ci sorts the simulated ratios
+	simRatios := make([]float64, len(ratios))
+	copy(simRatios, ratios)
+
+	ciAs := ci(metricA, simStatA, significanceLevels)
+	ciBs := ci(metricB, simStatB, significanceLevels)
 	ratioMetric := statisticFunc(ratios)
 	ciRatios := ci(ratioMetric, ratios, significanceLevels)
 
@@ -61,15 +141,18 @@ func CIRatio(iters int, maxNrWorkers int, statisticFunc st.StatisticFunc, signif
 			CIRatio: ciRatios[i],
 		}
 	}
-	return ret
+	return ret, simRatios, nil
 }
 
-func CI(iters int, maxNrWorkers int, statisticFunc st.StatisticFunc, significanceLevels []float64, executions bench.ExecutionSlice, sampler bench.InvocationSampler) []st.CI {
-	metric, simStat := metricAndSimulations(iters, maxNrWorkers, statisticFunc, executions, sampler)
-	return ci(metric, simStat, significanceLevels)
+func CI(ctx context.Context, sims Simulations, maxNrWorkers int, statisticFunc st.StatisticFunc, significanceLevels []float64, executions bench.ExecutionSlice, plan ResamplingPlan) ([]st.CI, error) {
+	metric, simStat, err := metricAndSimulations(ctx, sims, maxNrWorkers, statisticFunc, significanceLevels, executions, plan)
+	if err != nil {
+		return nil, err
+	}
+	return ci(metric, simStat, significanceLevels), nil
 }
 
-func metricAndSimulations(iters int, maxNrWorkers int, statisticFunc st.StatisticFunc, executions bench.ExecutionSlice, sampler bench.InvocationSampler) (metric float64, simStat []float64) {
+func metricAndSimulations(ctx context.Context, sims Simulations, maxNrWorkers int, statisticFunc st.StatisticFunc, significanceLevels []float64, executions bench.ExecutionSlice, plan ResamplingPlan) (metric float64, simStat []float64, err error) {
 	var wg sync.WaitGroup
 	wg.Add(2)
 
@@ -78,173 +161,198 @@ func metricAndSimulations(iters int, maxNrWorkers int, statisticFunc st.Statisti
 		wg.Done()
 	}()
 	go func() {
-		simStat = simulatedStatistics(iters, maxNrWorkers, statisticFunc, executions, sampler)
+		simStat, err = adaptiveSimulatedStatistics(ctx, sims, maxNrWorkers, statisticFunc, significanceLevels, newResampler(executions, plan))
 		wg.Done()
 	}()
 
 	wg.Wait()
 
-	return metric, simStat
-}
-
-func benchMetric(executions bench.ExecutionSlice, statisticFunc st.StatisticFunc) float64 {
-	meanIterations := executions.FlatSlice(bench.MeanInvocations)
-	metric := statisticFunc(meanIterations)
-	return metric
+	return metric, simStat, err
 }
 
-func ci(metric float64, d []float64, significanceLevels []float64) []st.CI {
-	sort.Float64s(d)
-	lstat := float64(len(d))
-
-	ret := make([]st.CI, len(significanceLevels))
-	for i, significanceLevel := range significanceLevels {
-
-		sl := st.SigLevel(significanceLevel)
-
-		slhalf := sl / 2
-		clhalf := 1 - slhalf
-
-		lqi := int(math.Ceil(lstat * slhalf))
-		uqi := int(math.Floor(lstat * clhalf))
+// adaptiveSimulatedStatistics performs the bootstrap simulations in batches until the CI bounds converge (see Simulations)
+func adaptiveSimulatedStatistics(ctx context.Context, sims Simulations, maxNrWorkers int, statisticFunc st.StatisticFunc, significanceLevels []float64, r *resampler) ([]float64, error) {
+	if !sims.Adaptive() {
+		return simulatedStatistics(ctx, sims.Max, maxNrWorkers, statisticFunc, r)
+	}
 
-		lq := d[lqi]
-		uq := d[uqi]
+	simStat := make([]float64, 0, sims.Max)
+	var prev []float64
+	for n := sims.next(0); n > 0; n = sims.next(len(simStat)) {
+		batch, err := simulatedStatistics(ctx, n, maxNrWorkers, statisticFunc, r)
+		if err != nil {
+			return nil, err
+		}
+		simStat = append(simStat, batch...)
 
-		ret[i] = st.CI{
-			Metric: metric,
-			Lower:  lq,
-			Upper:  uq,
-			Level:  1 - sl,
+		curr := bounds(simStat, significanceLevels)
+		if prev != nil && converged(prev, curr, sims.Tolerance) {
+			break
 		}
+		prev = curr
 	}
-	return ret
+	return simStat, nil
 }
 
-func simulatedStatistics(iters int, maxNrWorkers int, statisticFunc st.StatisticFunc, executions bench.ExecutionSlice, sampler bench.InvocationSampler) []float64 {
-	// create workers
-	var wg sync.WaitGroup
-	wg.Add(iters)
-
-	var anw int
-	if iters < maxNrWorkers {
-		anw = iters
-	} else {
-		anw = maxNrWorkers
-	}
-
-	workChan := make(chan int, iters)
-	samplingChan := make(chan []float64, iters)
-	for i := 0; i < anw; i++ {
+// pairedSimulatedStatistics performs the bootstrap simulations for executions A and B in lock-step, such that both have the same number of simulations.
+// Returned ratios are the simulated statistics of B divided by the ones of A, in the same order.
+// In adaptive mode, simulation stops when the CI bounds of A, B, and the ratios converge.
+func pairedSimulatedStatistics(ctx context.Context, sims Simulations, maxNrWorkers int, statisticFunc st.StatisticFunc, significanceLevels []float64, resamplerA, resamplerB *resampler) (simStatA, simStatB, ratios []float64, err error) {
+	simStatA = make([]float64, 0, sims.Max)
+	simStatB = make([]float64, 0, sims.Max)
+	ratios = make([]float64, 0, sims.Max)
+
+	var prev []float64
+	for n := sims.next(0); n > 0; n = sims.next(len(ratios)) {
+		var batchA, batchB []float64
+		var errA, errB error
+		var wg sync.WaitGroup
+		wg.Add(2)
 		go func() {
-		Loop:
-			for {
-				select {
-				case _, ok := <-workChan:
-					if ok {
-						rs := randomResampling(executions, sampler)
-						samplingChan <- rs
-						wg.Done()
-					} else {
-						break Loop
-					}
-				}
-			}
+			batchA, errA = simulatedStatistics(ctx, n, maxNrWorkers, statisticFunc, resamplerA)
+			wg.Done()
 		}()
-	}
+		go func() {
+			batchB, errB = simulatedStatistics(ctx, n, maxNrWorkers, statisticFunc, resamplerB)
+			wg.Done()
+		}()
+		wg.Wait()
 
-	// handle simulations
-	for i := 0; i < iters; i++ {
-		workChan <- i
-	}
-	close(workChan)
+		if errA != nil {
+			return nil, nil, nil, errA
+		}
+		if errB != nil {
+			return nil, nil, nil, errB
+		}
 
-	wg.Wait()
-	close(samplingChan)
+		lSimA := len(batchA)
+		lSimB := len(batchB)
+		if lSimA != lSimB {
+			panic(fmt.Sprintf("Simulated statistics not of same size: len(a) = %d; len(b) = %d", lSimA, lSimB))
+		}
+
+		for i := 0; i < lSimA; i++ {
+			ratios = append(ratios, batchB[i]/batchA[i])
+		}
+		simStatA = append(simStatA, batchA...)
+		simStatB = append(simStatB, batchB...)
 
-	// receive from sampling channel
-	simStat := make([]float64, 0, iters)
-	for randomSample := range samplingChan {
-		stat := statisticFunc(randomSample)
-		simStat = append(simStat, stat)
+		if !sims.Adaptive() {
+			continue
+		}
+
+		curr := bounds(simStatA, significanceLevels)
+		curr = append(curr, bounds(simStatB, significanceLevels)...)
+		curr = append(curr, bounds(ratios, significanceLevels)...)
+		if prev != nil && converged(prev, curr, sims.Tolerance) {
+			break
+		}
+		prev = curr
 	}
 
-	return simStat
+	return simStatA, simStatB, ratios, nil
 }
 
-func randomResampling(d bench.ExecutionSlice, sampler bench.InvocationSampler) []float64 {
-	s := d.Slice(sampler)
-
-	lis := len(s)
-	isample := sampleSize(lis)
+// bounds returns the lower and upper CI bounds for all significance levels, without altering d
+func bounds(d []float64, significanceLevels []float64) []float64 {
+	sorted := make([]float64, len(d))
+	copy(sorted, d)
+	sort.Float64s(sorted)
 
-	var ret []float64
-	// ret := make([]float64, 0, d.ElementCount())
+	ret := make([]float64, 0, 2*len(significanceLevels))
+	for _, significanceLevel := range significanceLevels {
+		lq, uq := quantiles(sorted, significanceLevel)
+		ret = append(ret, lq, uq)
+	}
+	return ret
+}
 
-	for _, i := range isample {
-		trials := s[i]
-		ltrials := len(trials)
-		tsample := sampleSize(ltrials)
+// converged checks whether all values in curr deviate at most by tolerance relative to the ones in prev
+func converged(prev, curr []float64, tolerance float64) bool {
+	for i, p := range prev {
+		c := curr[i]
+		if p == c {
+			continue
+		}
+		if math.Abs(c-p) > tolerance*math.Abs(p) {
+			return false
+		}
+	}
+	return true
+}
 
-		for _, t := range tsample {
-			forks := trials[t]
-			lforks := len(forks)
-			fsample := sampleSize(lforks)
+func benchMetric(executions bench.ExecutionSlice, statisticFunc st.StatisticFunc) float64 {
+	meanIterations := executions.FlatSlice(bench.MeanInvocations)
+	metric := statisticFunc(meanIterations)
+	return metric
+}
 
-			for _, f := range fsample {
-				iterations := forks[f]
-				literations := len(iterations)
-				itsample := sampleSize(literations)
+func ci(metric float64, d []float64, significanceLevels []float64) []st.CI {
+	sort.Float64s(d)
 
-				for _, it := range itsample {
-					invocations := iterations[it]
-					linvocations := len(invocations)
-					invsample := sampleSize(linvocations)
+	ret := make([]st.CI, len(significanceLevels))
+	for i, significanceLevel := range significanceLevels {
+		lq, uq := quantiles(d, significanceLevel)
 
-					for _, inv := range invsample {
-						ret = append(ret, invocations[inv])
-					}
-				}
-			}
+		ret[i] = st.CI{
+			Metric:      metric,
+			Lower:       lq,
+			Upper:       uq,
+			Level:       1 - st.SigLevel(significanceLevel),
+			Simulations: len(d),
 		}
 	}
-
 	return ret
 }
 
-func sample(d []float64) []float64 {
-	nd := distuv.Normal{
-		Mu:    stat.Mean(d, nil),
-		Sigma: stat.StdDev(d, nil),
-		Src:   rand.NewSource(uint64(time.Now().UnixNano())),
-	}
+// quantiles returns the lower and upper quantile of the sorted simulated statistics d for a significance level
+func quantiles(d []float64, significanceLevel float64) (lower, upper float64) {
+	lstat := float64(len(d))
 
-	sampler := sampleuv.IIDer{
-		Dist: nd,
-	}
+	sl := st.SigLevel(significanceLevel)
 
-	sampler.Sample(d)
-	return d
+	slhalf := sl / 2
+	clhalf := 1 - slhalf
+
+	lqi := int(math.Ceil(lstat * slhalf))
+	uqi := int(math.Floor(lstat * clhalf))
+
+	return d[lqi], d[uqi]
 }
 
-func sampleSize(l int) []int {
-	if l == 0 {
-		return []int{}
-	} else if l == 1 {
-		return []int{0}
-	}
+// simulatedStatistics performs `iters` bootstrap simulations with at most maxNrWorkers concurrent workers.
+// Every worker computes the statistics of a disjoint set of simulations directly into the result slice.
+// Workers stop early if ctx is done, in which case the context's error is returned.
+func simulatedStatistics(ctx context.Context, iters int, maxNrWorkers int, statisticFunc st.StatisticFunc, r *resampler) ([]float64, error) {
+	simStat := make([]float64, iters)
 
-	id := make([]float64, 0, l)
-	for dp := 0; dp < l; dp++ {
-		id = append(id, float64(dp))
+	var anw int
+	if iters < maxNrWorkers {
+		anw = iters
+	} else {
+		anw = maxNrWorkers
 	}
 
-	sampled := sample(id)
+	var wg sync.WaitGroup
+	wg.Add(anw)
+	for i := 0; i < anw; i++ {
+		go func(i int) {
+			defer wg.Done()
+			w := workerPool.Get().(*worker)
+			defer workerPool.Put(w)
+
+			for sim, n := i, 0; sim < iters; sim, n = sim+anw, n+1 {
+				if n%cancellationCheck == 0 && ctx.Err() != nil {
+					return
+				}
+				simStat[sim] = r.simulation(w, statisticFunc)
+			}
+		}(i)
+	}
+	wg.Wait()
 
-	ret := make([]int, 0, l)
-	lf64 := float64(l)
-	for _, dp := range sampled {
-		ret = append(ret, int(math.Mod(math.Abs(dp), lf64)))
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
-	return ret
+	return simStat, nil
 }
@@ -0,0 +1,97 @@
+package bootstrap_test
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+
+	"github.com/chrstphlbr/pa/pkg/bench"
+	"github.com/chrstphlbr/pa/pkg/bootstrap"
+	"github.com/chrstphlbr/pa/pkg/stat"
+)
+
+func randomExecution(t *testing.T, name string, forks, iterations int, mean float64) *bench.Execution {
+	b := bench.New(name)
+	e := bench.NewExecution(b)
+	for f := 1; f <= forks; f++ {
+		for i := 1; i <= iterations; i++ {
+			err := e.AddInvocations(bench.InvocationsFlat{
+				Benchmark:   b,
+				Instance:    "i1",
+				Trial:       1,
+				Fork:        f,
+				Iteration:   i,
+				Invocations: bench.Invocations{Count: 1, Value: mean + rand.NormFloat64()},
+			})
+			if err != nil {
+				t.Fatalf("Could not add invocations: %v", err)
+			}
+		}
+	}
+	return e
+}
+
+func checkSimulations(t *testing.T, cis []stat.CI, expected int) {
+	for i, ci := range cis {
+		if ci.Simulations != expected {
+			t.Fatalf("Unexpected number of simulations (pos: %d): was %d, expected %d", i, ci.Simulations, expected)
+		}
+	}
+}
+
+func TestCIFixedSimulations(t *testing.T) {
+	e := randomExecution(t, "b1", 5, 10, 100)
+
+	cis, err := bootstrap.CI(context.Background(), bootstrap.FixedSimulations(150), 2, stat.Mean, ciLevels, e, bootstrap.ResampleAllLevels(bootstrap.MeanInvocations))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	checkSimulations(t, cis, 150)
+}
+
+func TestCIAdaptiveSimulationsConverged(t *testing.T) {
+	e := randomExecution(t, "b1", 5, 10, 100)
+
+	// with a tolerance of 100%, the CIs converge after the second batch
+	sims := bootstrap.AdaptiveSimulations(1000, 100, 1)
+	cis, err := bootstrap.CI(context.Background(), sims, 2, stat.Mean, ciLevels, e, bootstrap.ResampleAllLevels(bootstrap.MeanInvocations))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	checkSimulations(t, cis, 200)
+}
+
+func TestCIAdaptiveSimulationsMax(t *testing.T) {
+	e := randomExecution(t, "b1", 5, 10, 100)
+
+	// the first batch never converges -> maximum number of simulations with a partial second batch
+	sims := bootstrap.AdaptiveSimulations(150, 100, 1e-15)
+	cis, err := bootstrap.CI(context.Background(), sims, 2, stat.Mean, ciLevels, e, bootstrap.ResampleAllLevels(bootstrap.MeanInvocations))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	checkSimulations(t, cis, 150)
+}
+
+func TestCIRatioAdaptiveSimulations(t *testing.T) {
+	ea := randomExecution(t, "b1", 5, 10, 100)
+	eb := randomExecution(t, "b1", 5, 10, 110)
+
+	sims := bootstrap.AdaptiveSimulations(1000, 100, 1)
+	cirs, err := bootstrap.CIRatio(context.Background(), sims, 2, stat.Mean, ciLevels, ea, eb, bootstrap.ResampleAllLevels(bootstrap.MeanInvocations))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, cir := range cirs {
+		checkSimulations(t, []stat.CI{cir.CIA, cir.CIB, cir.CIRatio}, 200)
+	}
+
+	sims = bootstrap.AdaptiveSimulations(150, 100, 1e-15)
+	cirs, err = bootstrap.CIRatio(context.Background(), sims, 2, stat.Mean, ciLevels, ea, eb, bootstrap.ResampleAllLevels(bootstrap.MeanInvocations))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, cir := range cirs {
+		checkSimulations(t, []stat.CI{cir.CIA, cir.CIB, cir.CIRatio}, 150)
+	}
+}
@@ -0,0 +1,125 @@
+package bootstrap
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// LevelStrategy defines how a level of the execution hierarchy (instance, trial, fork, iteration) is treated in a bootstrap simulation
+type LevelStrategy int
+
+const (
+	// Resample draws the elements of a level randomly with replacement
+	Resample LevelStrategy = iota
+	// Keep takes all elements of a level as they are
+	Keep
+)
+
+func (s LevelStrategy) String() string {
+	switch s {
+	case Resample:
+		return "resample"
+	case Keep:
+		return "keep"
+	}
+	return "INVALID_LEVEL_STRATEGY"
+}
+
+// ResamplingPlan defines the strategy per level of the execution hierarchy.
+// Resampling only some levels (e.g., forks but not iterations) follows Kalibera and Jones, and Ren et al.
+type ResamplingPlan struct {
+	Instances   LevelStrategy
+	Trials      LevelStrategy
+	Forks       LevelStrategy
+	Iterations  LevelStrategy
+	Invocations InvocationSampling
+	// Streaming computes the statistic of every simulation as the mean of the resampled values without storing them, which is faster but only valid if the statistic is the mean
+	Streaming bool
+}
+
+// ResampleAllLevels resamples instances, trials, forks, and iterations, and treats the invocations as defined by invocations
+func ResampleAllLevels(invocations InvocationSampling) ResamplingPlan {
+	return ResamplingPlan{
+		Invocations: invocations,
+	}
+}
+
+func (p ResamplingPlan) String() string {
+	return fmt.Sprintf(
+		"instance:%s,trial:%s,fork:%s,iteration:%s,invocation:%s",
+		p.Instances, p.Trials, p.Forks, p.Iterations, p.Invocations.spec(),
+	)
+}
+
+// ParseResamplingPlan parses a specification such as `instance:resample,trial:resample,fork:resample,iteration:keep,invocation:mean`.
+// Levels instance, trial, fork, and iteration are either `resample` or `keep`.
+// The invocation level is one of `mean`, `all` (resample all), `keep` (take all as they are), or `sample<n>` (resample n drawn invocations).
+// Levels not in the specification are resampled, and the invocations are treated as defined by invocations.
+func ParseResamplingPlan(spec string, invocations InvocationSampling) (ResamplingPlan, error) {
+	p := ResampleAllLevels(invocations)
+	if spec == "" {
+		return p, nil
+	}
+
+	seen := map[string]bool{}
+	for _, level := range strings.Split(spec, ",") {
+		kv := strings.SplitN(strings.TrimSpace(level), ":", 2)
+		if len(kv) != 2 {
+			return p, fmt.Errorf("invalid level '%s', expected 'level:strategy'", level)
+		}
+		name, strategy := kv[0], kv[1]
+		if seen[name] {
+			return p, fmt.Errorf("level '%s' specified multiple times", name)
+		}
+		seen[name] = true
+
+		var err error
+		switch name {
+		case "instance":
+			p.Instances, err = parseLevelStrategy(strategy)
+		case "trial":
+			p.Trials, err = parseLevelStrategy(strategy)
+		case "fork":
+			p.Forks, err = parseLevelStrategy(strategy)
+		case "iteration":
+			p.Iterations, err = parseLevelStrategy(strategy)
+		case "invocation":
+			p.Invocations, err = parseInvocationSampling(strategy)
+		default:
+			err = fmt.Errorf("unknown level (available: instance, trial, fork, iteration, invocation)")
+		}
+		if err != nil {
+			return p, fmt.Errorf("invalid level '%s': %w", level, err)
+		}
+	}
+	return p, nil
+}
+
+func parseLevelStrategy(str string) (LevelStrategy, error) {
+	switch str {
+	case "resample":
+		return Resample, nil
+	case "keep":
+		return Keep, nil
+	}
+	return Resample, fmt.Errorf("unknown strategy '%s' (available: resample, keep)", str)
+}
+
+func parseInvocationSampling(str string) (InvocationSampling, error) {
+	switch {
+	case str == "mean":
+		return MeanInvocations, nil
+	case str == "all":
+		return AllInvocations, nil
+	case str == "keep":
+		return KeepInvocations, nil
+	case strings.HasPrefix(str, "sample"):
+		n, err := strconv.Atoi(str[len("sample"):])
+		if err != nil || n < 1 {
+			return MeanInvocations, fmt.Errorf("invalid number of samples in '%s'", str)
+		}
+		return SampleInvocations(n), nil
+	}
+	return MeanInvocations, fmt.Errorf("unknown strategy '%s' (available: mean, all, keep, sample<n>)", str)
+}
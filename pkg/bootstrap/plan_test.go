@@ -0,0 +1,170 @@
+package bootstrap_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/chrstphlbr/pa/pkg/bench"
+	"github.com/chrstphlbr/pa/pkg/bootstrap"
+	"github.com/chrstphlbr/pa/pkg/stat"
+)
+
+func TestParseResamplingPlan(t *testing.T) {
+	tests := []struct {
+		spec     string
+		expected bootstrap.ResamplingPlan
+	}{
+		{"", bootstrap.ResampleAllLevels(bootstrap.AllInvocations)},
+		{
+			"instance:resample,trial:resample,fork:resample,iteration:keep,invocation:mean",
+			bootstrap.ResamplingPlan{Iterations: bootstrap.Keep, Invocations: bootstrap.MeanInvocations},
+		},
+		{
+			"fork:keep, invocation:sample10",
+			bootstrap.ResamplingPlan{Forks: bootstrap.Keep, Invocations: bootstrap.SampleInvocations(10)},
+		},
+		{
+			"instance:keep,trial:keep,invocation:keep",
+			bootstrap.ResamplingPlan{Instances: bootstrap.Keep, Trials: bootstrap.Keep, Invocations: bootstrap.KeepInvocations},
+		},
+	}
+
+	for _, test := range tests {
+		p, err := bootstrap.ParseResamplingPlan(test.spec, bootstrap.AllInvocations)
+		if err != nil {
+			t.Fatalf("Unexpected error for '%s': %v", test.spec, err)
+		}
+		if p != test.expected {
+			t.Fatalf("Unexpected plan for '%s': was %v, expected %v", test.spec, p, test.expected)
+		}
+
+		// String is a valid specification
+		p2, err := bootstrap.ParseResamplingPlan(p.String(), bootstrap.MeanInvocations)
+		if err != nil || p2 != p {
+			t.Fatalf("String of plan is not parsable: %s (%v)", p, err)
+		}
+	}
+}
+
+func TestParseResamplingPlanInvalid(t *testing.T) {
+	specs := []string{
+		"fork",
+		"fork:drop",
+		"method:keep",
+		"fork:keep,fork:resample",
+		"invocation:sample",
+		"invocation:sample0",
+		"invocation:median",
+	}
+
+	for _, spec := range specs {
+		_, err := bootstrap.ParseResamplingPlan(spec, bootstrap.MeanInvocations)
+		if err == nil {
+			t.Fatalf("Expected error for '%s'", spec)
+		}
+	}
+}
+
+// levelsExecution creates an execution with 5 forks of 10 iterations, where every iteration has the value forkValue(f) + iterationValue(it)
+func levelsExecution(t *testing.T, forkValue, iterationValue func(int) float64) *bench.Execution {
+	b := bench.New("b1")
+	e := bench.NewExecution(b)
+	for f := 1; f <= 5; f++ {
+		for it := 1; it <= 10; it++ {
+			err := e.AddInvocations(bench.InvocationsFlat{
+				Benchmark:   b,
+				Instance:    "i1",
+				Trial:       1,
+				Fork:        f,
+				Iteration:   it,
+				Invocations: bench.Invocations{Count: 1, Value: forkValue(f) + iterationValue(it)},
+			})
+			if err != nil {
+				t.Fatalf("Could not add invocations: %v", err)
+			}
+		}
+	}
+	return e
+}
+
+func ciWidth(t *testing.T, e *bench.Execution, spec string) float64 {
+	p, err := bootstrap.ParseResamplingPlan(spec, bootstrap.MeanInvocations)
+	if err != nil {
+		t.Fatalf("Could not parse plan: %v", err)
+	}
+	cis, err := bootstrap.CI(context.Background(), bootstrap.FixedSimulations(1000), 2, stat.Mean, []float64{0.05}, e, p)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return cis[0].Upper - cis[0].Lower
+}
+
+func constant(int) float64 {
+	return 0
+}
+
+func identity(i int) float64 {
+	return float64(i)
+}
+
+func TestResamplingPlanWidthsIterationVariability(t *testing.T) {
+	// all forks are equal, variability is only between iterations
+	e := levelsExecution(t, constant, identity)
+
+	if w := ciWidth(t, e, "fork:resample,iteration:keep"); w != 0 {
+		t.Fatalf("Expected zero width when only resampling identical forks, was %f", w)
+	}
+	if w := ciWidth(t, e, "fork:keep,iteration:resample"); w <= 0 {
+		t.Fatalf("Expected positive width when resampling iterations, was %f", w)
+	}
+	if wAll, wIt := ciWidth(t, e, ""), ciWidth(t, e, "fork:keep"); wAll <= 0 || wIt <= 0 {
+		t.Fatalf("Expected positive widths, was %f (all) and %f (iterations)", wAll, wIt)
+	}
+}
+
+func TestResamplingPlanWidthsForkVariability(t *testing.T) {
+	// all iterations of a fork are equal, variability is only between forks
+	e := levelsExecution(t, func(f int) float64 { return float64(100 * f) }, constant)
+
+	if w := ciWidth(t, e, "fork:keep,iteration:resample"); w != 0 {
+		t.Fatalf("Expected zero width when only resampling identical iterations, was %f", w)
+	}
+	wFork := ciWidth(t, e, "fork:resample,iteration:keep")
+	if wFork <= 0 {
+		t.Fatalf("Expected positive width when resampling forks, was %f", wFork)
+	}
+	if w := ciWidth(t, e, "instance:keep,trial:keep,fork:keep,iteration:keep"); w != 0 {
+		t.Fatalf("Expected zero width when keeping all levels, was %f", w)
+	}
+}
+
+func TestResamplingPlanKeepInvocations(t *testing.T) {
+	b := bench.New("b1")
+	e := bench.NewExecution(b)
+	for i, c := range []int{1, 3} {
+		err := e.AddInvocations(bench.InvocationsFlat{
+			Benchmark:   b,
+			Instance:    "i1",
+			Trial:       1,
+			Fork:        1,
+			Iteration:   1,
+			Invocations: bench.Invocations{Count: c, Value: float64(4 * i)},
+		})
+		if err != nil {
+			t.Fatalf("Could not add invocations: %v", err)
+		}
+	}
+
+	p := bootstrap.ResampleAllLevels(bootstrap.KeepInvocations)
+	for _, sf := range []stat.StatisticFunc{stat.Mean, stat.Median} {
+		cis, err := bootstrap.CI(context.Background(), bootstrap.FixedSimulations(10), 1, sf, []float64{0.05}, e, p)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		// values 0, 4, 4, 4
+		expected := sf([]float64{0, 4, 4, 4})
+		if cis[0].Lower != expected || cis[0].Upper != expected {
+			t.Fatalf("Unexpected CI when keeping all invocations: [%f, %f], expected %f", cis[0].Lower, cis[0].Upper, expected)
+		}
+	}
+}
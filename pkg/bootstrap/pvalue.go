@@ -0,0 +1,70 @@
+package bootstrap
+
+import (
+	"fmt"
+	"math"
+
+	st "github.com/chrstphlbr/pa/pkg/stat"
+)
+
+// PValue computes the two-sided bootstrap p-value of the null hypothesis that the statistic equals null (e.g., a ratio of 1) from the simulated statistics (e.g., the simulated ratios of CIRatioSimulations),
+// i.e., twice the fraction of simulations on the smaller side of null (ties count half), capped at 1.
+// It corresponds to the percentile CIs: null is outside the CI at significance level sl if the p-value is smaller than sl (up to the quantiles' discreteness).
+// Returns NaN if there are no simulations.
+func PValue(simulated []float64, null float64) float64 {
+	l := len(simulated)
+	if l == 0 {
+		return math.NaN()
+	}
+
+	var below, above float64
+	for _, s := range simulated {
+		switch {
+		case s < null:
+			below++
+		case s > null:
+			above++
+		default:
+			below += 0.5
+			above += 0.5
+		}
+	}
+	return math.Min(1, 2*math.Min(below, above)/float64(l))
+}
+
+// PValueMethod defines how p-values are computed from simulated statistics
+type PValueMethod int
+
+const (
+	// PairedPValues computes p-values from the simulated statistics directly (see PValue)
+	PairedPValues PValueMethod = iota
+)
+
+func (m PValueMethod) String() string {
+	switch m {
+	case PairedPValues:
+		return "paired"
+	}
+	return fmt.Sprintf("PValueMethod(%d)", int(m))
+}
+
+func ParsePValueMethod(s string) (PValueMethod, error) {
+	switch s {
+	case "paired":
+		return PairedPValues, nil
+	}
+	return PairedPValues, fmt.Errorf("invalid p-value method '%s' (available: paired)", s)
+}
+
+// PValue computes the p-value of the null hypothesis that the statistic with the simulated statistics equals null
+func (m PValueMethod) PValue(simulated []float64, null float64) float64 {
+	return PValue(simulated, null)
+}
+
+// SimulationsCI computes the percentile CIs of metric from its simulated statistics, e.g., at a significance level adjusted for multiple comparisons.
+// simulated is not altered.
+func SimulationsCI(metric float64, simulated []float64, significanceLevels []float64) []st.CI {
+	d := make([]float64, len(simulated))
+	copy(d, simulated)
+	return ci(metric, d, significanceLevels)
+}
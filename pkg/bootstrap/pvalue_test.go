@@ -0,0 +1,68 @@
+package bootstrap_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/chrstphlbr/pa/pkg/bootstrap"
+)
+
+func TestPValue(t *testing.T) {
+	sims := make([]float64, 100)
+	for i := range sims {
+		// 0.96, 0.97, ..., 1.95
+		sims[i] = 0.96 + float64(i)/100
+	}
+
+	for _, c := range []struct {
+		null     float64
+		expected float64
+	}{
+		// 4 below, 1 tie, 95 above
+		{1, 2 * 4.5 / 100},
+		{0.5, 0},
+		{3, 0},
+		// 50 below, 50 above
+		{1.455, 1},
+	} {
+		if p := bootstrap.PValue(sims, c.null); math.Abs(p-c.expected) > 1e-12 {
+			t.Fatalf("Unexpected p-value for null %g: was %g, expected %g", c.null, p, c.expected)
+		}
+	}
+
+	if p := bootstrap.PValue(nil, 1); !math.IsNaN(p) {
+		t.Fatalf("Expected NaN p-value without simulations: %g", p)
+	}
+}
+
+func TestSimulationsCI(t *testing.T) {
+	sims := []float64{5, 3, 1, 4, 2}
+	cis := bootstrap.SimulationsCI(3, sims, []float64{0.5})
+	if len(cis) != 1 {
+		t.Fatalf("Unexpected number of CIs: %d", len(cis))
+	}
+	if ci := cis[0]; ci.Metric != 3 || ci.Lower != 3 || ci.Upper != 4 || ci.Level != 0.5 || ci.Simulations != 5 {
+		t.Fatalf("Unexpected CI: %+v", ci)
+	}
+	if sims[0] != 5 || sims[2] != 1 {
+		t.Fatalf("Simulations altered: %v", sims)
+	}
+}
+
+func TestPValueMethod(t *testing.T) {
+	sims := []float64{0.9, 1.1, 1.2, 1.3}
+	if p := bootstrap.PairedPValues.PValue(sims, 1); p != bootstrap.PValue(sims, 1) {
+		t.Fatalf("Unexpected paired p-value: %g", p)
+	}
+
+	m := bootstrap.PairedPValues
+	pm, err := bootstrap.ParsePValueMethod(m.String())
+	if err != nil || pm != m {
+		t.Fatalf("Unexpected p-value method for '%s': %v (%v)", m, pm, err)
+	}
+	for _, s := range []string{"shift", "exact"} {
+		if _, err := bootstrap.ParsePValueMethod(s); err == nil {
+			t.Fatalf("Expected error for unknown p-value method '%s'", s)
+		}
+	}
+}
@@ -0,0 +1,335 @@
+package bootstrap
+
+import (
+	"fmt"
+	"math/bits"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/chrstphlbr/pa/pkg/bench"
+	st "github.com/chrstphlbr/pa/pkg/stat"
+
+	"golang.org/x/exp/rand"
+)
+
+type invocationMode int
+
+const (
+	invocationsMean invocationMode = iota
+	invocationsAll
+	invocationsSample
+	invocationsKeep
+)
+
+// InvocationSampling defines how the invocations of an iteration are resampled
+type InvocationSampling struct {
+	mode    invocationMode
+	samples int
+}
+
+var (
+	// MeanInvocations uses the mean across all invocations of an iteration
+	MeanInvocations = InvocationSampling{mode: invocationsMean}
+	// AllInvocations resamples all invocations of an iteration
+	AllInvocations = InvocationSampling{mode: invocationsAll}
+	// KeepInvocations takes all invocations of an iteration as they are, i.e., without resampling them
+	KeepInvocations = InvocationSampling{mode: invocationsKeep}
+)
+
+// SampleInvocations draws (with replacement) `samples` invocations of an iteration, which are then resampled
+func SampleInvocations(samples int) InvocationSampling {
+	return InvocationSampling{
+		mode:    invocationsSample,
+		samples: samples,
+	}
+}
+
+func (s InvocationSampling) String() string {
+	switch s.mode {
+	case invocationsMean:
+		return "Mean"
+	case invocationsAll:
+		return "All"
+	case invocationsSample:
+		return fmt.Sprintf("%d invocations per iteration", s.samples)
+	case invocationsKeep:
+		return "Keep"
+	}
+	return "INVALID_INVOCATION_SAMPLING"
+}
+
+// spec returns the representation of s in a ResamplingPlan specification
+func (s InvocationSampling) spec() string {
+	switch s.mode {
+	case invocationsMean:
+		return "mean"
+	case invocationsAll:
+		return "all"
+	case invocationsSample:
+		return fmt.Sprintf("sample%d", s.samples)
+	case invocationsKeep:
+		return "keep"
+	}
+	return "INVALID_INVOCATION_SAMPLING"
+}
+
+// resampler performs hierarchical random resampling with replacement on a flattened execution, where the levels are treated according to a ResamplingPlan.
+// It is created once per execution and shared by all workers, i.e., it is read-only after creation.
+type resampler struct {
+	flat        *bench.FlatExecution
+	plan        ResamplingPlan
+	invocations InvocationSampling
+	// iterationMeans are the means across all invocations per iteration
+	iterationMeans []float64
+	// totals are the summed invocation counts per iteration
+	totals []int
+	// aliasProbs and aliases are the per-iteration alias tables (Vose's alias method) for drawing an invocation weighted by its count in constant time:
+	// invocation i is drawn with probability aliasProbs[i], otherwise invocation aliases[i] (of the same iteration)
+	aliasProbs []float64
+	aliases    []int
+}
+
+func newResampler(executions bench.ExecutionSlice, plan ResamplingPlan) *resampler {
+	fe := executions.Flat()
+	invocations := plan.Invocations
+	r := &resampler{
+		flat:        fe,
+		plan:        plan,
+		invocations: invocations,
+	}
+
+	nrIterations := fe.NrIterations()
+	if invocations.mode == invocationsMean {
+		r.iterationMeans = make([]float64, nrIterations)
+		for it := 0; it < nrIterations; it++ {
+			r.iterationMeans[it] = bench.MeanInvocations(fe.IterationInvocations(it))[0]
+		}
+		return r
+	}
+
+	r.totals = make([]int, nrIterations)
+	if invocations.mode == invocationsKeep {
+		for it := 0; it < nrIterations; it++ {
+			for _, iv := range fe.IterationInvocations(it) {
+				r.totals[it] += iv.Count
+			}
+		}
+		return r
+	}
+
+	r.aliasProbs = make([]float64, len(fe.Invocations))
+	r.aliases = make([]int, len(fe.Invocations))
+	var small, large []int
+	for it := 0; it < nrIterations; it++ {
+		from, to := fe.Iterations[it], fe.Iterations[it+1]
+		var total int
+		for i := from; i < to; i++ {
+			total += fe.Invocations[i].Count
+		}
+		r.totals[it] = total
+		if total == 0 {
+			continue
+		}
+
+		// build alias table
+		small, large = small[:0], large[:0]
+		n := float64(to - from)
+		for i := from; i < to; i++ {
+			r.aliasProbs[i] = float64(fe.Invocations[i].Count) * n / float64(total)
+			r.aliases[i] = i
+			if r.aliasProbs[i] < 1 {
+				small = append(small, i)
+			} else {
+				large = append(large, i)
+			}
+		}
+		for len(small) > 0 && len(large) > 0 {
+			s := small[len(small)-1]
+			small = small[:len(small)-1]
+			l := large[len(large)-1]
+			large = large[:len(large)-1]
+
+			r.aliases[s] = l
+			r.aliasProbs[l] = r.aliasProbs[l] + r.aliasProbs[s] - 1
+			if r.aliasProbs[l] < 1 {
+				small = append(small, l)
+			} else {
+				large = append(large, l)
+			}
+		}
+		// remaining ones are (numerically) 1
+		for _, i := range append(small, large...) {
+			r.aliasProbs[i] = 1
+		}
+	}
+	return r
+}
+
+// worker holds the per-goroutine state of the resampling, which is reused across simulations
+type worker struct {
+	rng *rand.Rand
+	// streaming defines whether the statistic is computed without storing the resampled values (see ResamplingPlan.Streaming)
+	streaming bool
+	sum       float64
+	n         int
+	// values are the resampled values if not streaming
+	values []float64
+	// drawn are the invocations drawn from an iteration before resampling
+	drawn []float64
+}
+
+var workerSeed uint64
+
+var workerPool = sync.Pool{
+	New: func() interface{} {
+		seed := uint64(time.Now().UnixNano()) + atomic.AddUint64(&workerSeed, 1)
+		return &worker{
+			rng: rand.New(rand.NewSource(seed)),
+		}
+	},
+}
+
+func (w *worker) reset(streaming bool) {
+	w.streaming = streaming
+	w.sum = 0
+	w.n = 0
+	w.values = w.values[:0]
+}
+
+func (w *worker) add(v float64) {
+	if w.streaming {
+		w.sum += v
+		w.n++
+		return
+	}
+	w.values = append(w.values, v)
+}
+
+// addN adds v count times
+func (w *worker) addN(v float64, count int) {
+	if w.streaming {
+		w.sum += v * float64(count)
+		w.n += count
+		return
+	}
+	for i := 0; i < count; i++ {
+		w.values = append(w.values, v)
+	}
+}
+
+func (w *worker) statistic(statisticFunc st.StatisticFunc) float64 {
+	if w.streaming {
+		return w.sum / float64(w.n)
+	}
+	return statisticFunc(w.values)
+}
+
+// simulation performs one bootstrap simulation and returns its statistic
+func (r *resampler) simulation(w *worker, statisticFunc st.StatisticFunc) float64 {
+	w.reset(r.plan.Streaming)
+
+	rng := w.rng
+	flat := r.flat
+	plan := r.plan
+	nrInstances := flat.NrInstances()
+
+	for ii := 0; ii < nrInstances; ii++ {
+		i := pick(rng, plan.Instances, ii, 0, nrInstances)
+		tFrom, tTo := flat.Instances[i], flat.Instances[i+1]
+
+		for ti := tFrom; ti < tTo; ti++ {
+			t := pick(rng, plan.Trials, ti, tFrom, tTo)
+			fFrom, fTo := flat.Trials[t], flat.Trials[t+1]
+
+			for fi := fFrom; fi < fTo; fi++ {
+				f := pick(rng, plan.Forks, fi, fFrom, fTo)
+				itFrom, itTo := flat.Forks[f], flat.Forks[f+1]
+
+				for iti := itFrom; iti < itTo; iti++ {
+					it := pick(rng, plan.Iterations, iti, itFrom, itTo)
+					r.resampleInvocations(w, it)
+				}
+			}
+		}
+	}
+
+	return w.statistic(statisticFunc)
+}
+
+// pick returns the element of the range [from,to) for position i, which is i itself if the level is kept or a random element if it is resampled
+func pick(rng *rand.Rand, strategy LevelStrategy, i, from, to int) int {
+	if strategy == Keep {
+		return i
+	}
+	return draw(rng, from, to)
+}
+
+// draw draws a random element of the range [from,to)
+func draw(rng *rand.Rand, from, to int) int {
+	if l := to - from; l > 1 {
+		return from + intn(rng, l)
+	}
+	return from
+}
+
+// intn returns a random number in [0,n) using the multiply-high method, which is considerably faster than rand.Intn and has a negligible bias of n/2^64
+func intn(rng *rand.Rand, n int) int {
+	hi, _ := bits.Mul64(rng.Uint64(), uint64(n))
+	return int(hi)
+}
+
+func (r *resampler) resampleInvocations(w *worker, it int) {
+	if r.invocations.mode == invocationsMean {
+		w.add(r.iterationMeans[it])
+		return
+	}
+
+	from, to := r.flat.Iterations[it], r.flat.Iterations[it+1]
+	if from == to {
+		return
+	}
+	total := r.totals[it]
+	if total == 0 {
+		return
+	}
+
+	if r.invocations.mode == invocationsKeep {
+		for _, iv := range r.flat.Invocations[from:to] {
+			w.addN(iv.Value, iv.Count)
+		}
+		return
+	}
+
+	if r.invocations.mode == invocationsAll || total <= r.invocations.samples {
+		for i := 0; i < total; i++ {
+			w.add(r.drawInvocation(w.rng, from, to))
+		}
+		return
+	}
+
+	// draw samples from the iteration and resample them
+	samples := r.invocations.samples
+	w.drawn = w.drawn[:0]
+	for i := 0; i < samples; i++ {
+		w.drawn = append(w.drawn, r.drawInvocation(w.rng, from, to))
+	}
+	for i := 0; i < samples; i++ {
+		w.add(w.drawn[intn(w.rng, samples)])
+	}
+}
+
+// drawInvocation draws a random invocation value of the invocations [from,to), where every value is weighted by its count
+func (r *resampler) drawInvocation(rng *rand.Rand, from, to int) float64 {
+	if to-from == 1 {
+		return r.flat.Invocations[from].Value
+	}
+
+	// the high bits select the invocation, the low bits are the (uniform) fraction for the alias decision
+	hi, lo := bits.Mul64(rng.Uint64(), uint64(to-from))
+	i := from + int(hi)
+	if float64(lo>>11)/(1<<53) >= r.aliasProbs[i] {
+		i = r.aliases[i]
+	}
+	return r.flat.Invocations[i].Value
+}
@@ -0,0 +1,156 @@
+package bootstrap
+
+import (
+	"context"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/chrstphlbr/pa/pkg/bench"
+	st "github.com/chrstphlbr/pa/pkg/stat"
+
+	"golang.org/x/exp/rand"
+	"gonum.org/v1/gonum/stat"
+	"gonum.org/v1/gonum/stat/distuv"
+	"gonum.org/v1/gonum/stat/sampleuv"
+)
+
+const benchmarkSimulations = 10000
+
+// slice-based resampling engine as reference, which rebuilds the nested slices from the execution in every simulation
+
+func sliceSimulatedStatistics(iters int, maxNrWorkers int, statisticFunc st.StatisticFunc, executions bench.ExecutionSlice, sampler bench.InvocationSampler) []float64 {
+	var wg sync.WaitGroup
+	wg.Add(iters)
+
+	var anw int
+	if iters < maxNrWorkers {
+		anw = iters
+	} else {
+		anw = maxNrWorkers
+	}
+
+	workChan := make(chan int, iters)
+	samplingChan := make(chan []float64, iters)
+	for i := 0; i < anw; i++ {
+		go func() {
+			for range workChan {
+				samplingChan <- sliceResampling(executions, sampler)
+				wg.Done()
+			}
+		}()
+	}
+
+	for i := 0; i < iters; i++ {
+		workChan <- i
+	}
+	close(workChan)
+
+	wg.Wait()
+	close(samplingChan)
+
+	simStat := make([]float64, 0, iters)
+	for randomSample := range samplingChan {
+		simStat = append(simStat, statisticFunc(randomSample))
+	}
+	return simStat
+}
+
+func sliceResampling(d bench.ExecutionSlice, sampler bench.InvocationSampler) []float64 {
+	s := d.Slice(sampler)
+
+	var ret []float64
+	for _, i := range sliceSampleSize(len(s)) {
+		trials := s[i]
+		for _, t := range sliceSampleSize(len(trials)) {
+			forks := trials[t]
+			for _, f := range sliceSampleSize(len(forks)) {
+				iterations := forks[f]
+				for _, it := range sliceSampleSize(len(iterations)) {
+					invocations := iterations[it]
+					for _, inv := range sliceSampleSize(len(invocations)) {
+						ret = append(ret, invocations[inv])
+					}
+				}
+			}
+		}
+	}
+	return ret
+}
+
+func sliceSampleSize(l int) []int {
+	if l == 0 {
+		return []int{}
+	} else if l == 1 {
+		return []int{0}
+	}
+
+	id := make([]float64, 0, l)
+	for dp := 0; dp < l; dp++ {
+		id = append(id, float64(dp))
+	}
+
+	nd := distuv.Normal{
+		Mu:    stat.Mean(id, nil),
+		Sigma: stat.StdDev(id, nil),
+		Src:   rand.NewSource(uint64(time.Now().UnixNano())),
+	}
+	sampler := sampleuv.IIDer{
+		Dist: nd,
+	}
+	sampler.Sample(id)
+
+	ret := make([]int, 0, l)
+	lf64 := float64(l)
+	for _, dp := range id {
+		ret = append(ret, int(math.Mod(math.Abs(dp), lf64)))
+	}
+	return ret
+}
+
+// benchmarks
+
+func benchmarkSlice(b *testing.B, statisticFunc st.StatisticFunc, sampler bench.InvocationSampler) {
+	e := execution(b, 10, 20, 50)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		sliceSimulatedStatistics(benchmarkSimulations, 4, statisticFunc, e, sampler)
+	}
+}
+
+func benchmarkFlat(b *testing.B, statisticFunc st.StatisticFunc, streaming bool, invocations InvocationSampling) {
+	e := execution(b, 10, 20, 50)
+	plan := ResampleAllLevels(invocations)
+	plan.Streaming = streaming
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		simulatedStatistics(context.Background(), benchmarkSimulations, 4, statisticFunc, newResampler(e, plan))
+	}
+}
+
+func BenchmarkSimulatedStatisticsSliceMean(b *testing.B) {
+	benchmarkSlice(b, st.Mean, bench.MeanInvocations)
+}
+
+func BenchmarkSimulatedStatisticsFlatMean(b *testing.B) {
+	benchmarkFlat(b, st.Mean, true, MeanInvocations)
+}
+
+func BenchmarkSimulatedStatisticsSliceMedian(b *testing.B) {
+	benchmarkSlice(b, st.Median, bench.MeanInvocations)
+}
+
+func BenchmarkSimulatedStatisticsFlatMedian(b *testing.B) {
+	benchmarkFlat(b, st.Median, false, MeanInvocations)
+}
+
+func BenchmarkSimulatedStatisticsSliceAllInvocations(b *testing.B) {
+	benchmarkSlice(b, st.Mean, bench.AllInvocations)
+}
+
+func BenchmarkSimulatedStatisticsFlatAllInvocations(b *testing.B) {
+	benchmarkFlat(b, st.Mean, true, AllInvocations)
+}
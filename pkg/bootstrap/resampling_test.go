@@ -0,0 +1,129 @@
+package bootstrap
+
+import (
+	"math"
+	"testing"
+
+	"github.com/chrstphlbr/pa/pkg/bench"
+	st "github.com/chrstphlbr/pa/pkg/stat"
+)
+
+// execution creates an execution with `forks` forks of `iterations` iterations, each having `invocations` invocations with Count 2 and Value = fork*iteration
+func execution(t testing.TB, forks, iterations, invocations int) *bench.Execution {
+	b := bench.New("b1")
+	e := bench.NewExecution(b)
+	for f := 1; f <= forks; f++ {
+		for it := 1; it <= iterations; it++ {
+			for inv := 0; inv < invocations; inv++ {
+				err := e.AddInvocations(bench.InvocationsFlat{
+					Benchmark:   b,
+					Instance:    "i1",
+					Trial:       1,
+					Fork:        f,
+					Iteration:   it,
+					Invocations: bench.Invocations{Count: 2, Value: float64(f * it)},
+				})
+				if err != nil {
+					t.Fatalf("Could not add invocations: %v", err)
+				}
+			}
+		}
+	}
+	return e
+}
+
+func TestResamplerNrValues(t *testing.T) {
+	const (
+		forks       = 3
+		iterations  = 4
+		invocations = 5
+	)
+
+	tests := []struct {
+		invocations InvocationSampling
+		expected    int
+	}{
+		{MeanInvocations, forks * iterations},
+		{AllInvocations, forks * iterations * invocations * 2},
+		{SampleInvocations(3), forks * iterations * 3},
+		{KeepInvocations, forks * iterations * invocations * 2},
+		// more samples than invocations -> all invocations
+		{SampleInvocations(100), forks * iterations * invocations * 2},
+	}
+
+	e := execution(t, forks, iterations, invocations)
+	w := workerPool.Get().(*worker)
+	defer workerPool.Put(w)
+
+	for _, test := range tests {
+		r := newResampler(e, ResampleAllLevels(test.invocations))
+		r.simulation(w, st.Median)
+		if l := len(w.values); l != test.expected {
+			t.Fatalf("Unexpected number of resampled values for %s: was %d, expected %d", test.invocations, l, test.expected)
+		}
+		for _, v := range w.values {
+			// every value is fork*iteration
+			if v < 1 || v > forks*iterations || v != math.Trunc(v) {
+				t.Fatalf("Unexpected resampled value for %s: %f", test.invocations, v)
+			}
+		}
+	}
+}
+
+func TestResamplerStreaming(t *testing.T) {
+	// streaming and non-streaming mean are equal for constant values
+	e := execution(t, 1, 1, 10)
+	plan := ResampleAllLevels(AllInvocations)
+	w := workerPool.Get().(*worker)
+	defer workerPool.Put(w)
+
+	stored := newResampler(e, plan).simulation(w, st.Mean)
+	if w.streaming {
+		t.Fatalf("Expected the resampled values to be stored")
+	}
+	plan.Streaming = true
+	streamed := newResampler(e, plan).simulation(w, st.Mean)
+	if !w.streaming {
+		t.Fatalf("Expected the mean to be streamed (see ResamplingPlan.Streaming)")
+	}
+	if streamed != 1 || stored != 1 {
+		t.Fatalf("Unexpected mean: streamed %f, stored %f", streamed, stored)
+	}
+}
+
+func TestResamplerWeightedInvocations(t *testing.T) {
+	b := bench.New("b1")
+	e := bench.NewExecution(b)
+	counts := []int{1, 3, 0, 4}
+	for i, c := range counts {
+		err := e.AddInvocations(bench.InvocationsFlat{
+			Benchmark:   b,
+			Instance:    "i1",
+			Trial:       1,
+			Fork:        1,
+			Iteration:   1,
+			Invocations: bench.Invocations{Count: c, Value: float64(i)},
+		})
+		if err != nil {
+			t.Fatalf("Could not add invocations: %v", err)
+		}
+	}
+
+	r := newResampler(e, ResampleAllLevels(AllInvocations))
+	w := workerPool.Get().(*worker)
+	defer workerPool.Put(w)
+
+	const draws = 100000
+	occurrences := make([]int, len(counts))
+	for i := 0; i < draws; i++ {
+		occurrences[int(r.drawInvocation(w.rng, 0, len(counts)))]++
+	}
+
+	for i, c := range counts {
+		expected := float64(c) / 8
+		actual := float64(occurrences[i]) / draws
+		if math.Abs(expected-actual) > 0.01 {
+			t.Fatalf("Unexpected relative frequency of invocation %d: was %f, expected %f", i, actual, expected)
+		}
+	}
+}
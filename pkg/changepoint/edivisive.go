@@ -0,0 +1,158 @@
+package changepoint
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+
+	"golang.org/x/exp/rand"
+)
+
+const (
+	DefaultPermutations = 1000
+	DefaultMinSize      = 2
+)
+
+// cancellationCheck defines after how many permutations the context is checked
+const cancellationCheck = 10
+
+// ChangePoint is a change of the distribution of a series between Index-1 and Index
+type ChangePoint struct {
+	Index int
+	// Divergence is the (scaled) energy distance between the values before and after Index within their segment (see EDivisive)
+	Divergence float64
+	// PValue is the p-value of the permutation test of the change point
+	PValue float64
+}
+
+// EDivisive detects change points in series with the E-divisive means algorithm (Matteson and James, 2014): it repeatedly splits the segment (between the change points found so far) at the index maximizing the energy distance between the values before and (up to some later index) after it, as long as the split is significant.
+// The significance of a split is estimated with permutations random permutations (drawn from rng) of the values within the segments, and its p-value (1 + as extreme) / (1 + permutations) must be at most sigLevel.
+// Segments are at least minSize long. The change points are returned in index order.
+func EDivisive(ctx context.Context, series []float64, sigLevel float64, permutations, minSize int, rng *rand.Rand) ([]ChangePoint, error) {
+	if minSize < 1 {
+		return nil, fmt.Errorf("minimum segment size must be at least 1, was %d", minSize)
+	}
+	if permutations < 1 {
+		return nil, fmt.Errorf("number of permutations must be at least 1, was %d", permutations)
+	}
+
+	n := len(series)
+	dist := make([][]float64, n)
+	for i := range dist {
+		dist[i] = make([]float64, n)
+		for j := range dist[i] {
+			dist[i][j] = math.Abs(series[i] - series[j])
+		}
+	}
+
+	idx := make([]int, n)
+	for i := range idx {
+		idx[i] = i
+	}
+
+	var cps []ChangePoint
+	bounds := []int{0, n}
+	for {
+		index, divergence := bestSplit(dist, idx, bounds, minSize)
+		if index == -1 {
+			break
+		}
+
+		// permutations of the values within every segment
+		perm := make([]int, n)
+		var extreme int
+		for p := 0; p < permutations; p++ {
+			if p%cancellationCheck == 0 && ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			copy(perm, idx)
+			for s := 0; s < len(bounds)-1; s++ {
+				seg := perm[bounds[s]:bounds[s+1]]
+				rng.Shuffle(len(seg), func(i, j int) {
+					seg[i], seg[j] = seg[j], seg[i]
+				})
+			}
+			if _, d := bestSplit(dist, perm, bounds, minSize); d >= divergence {
+				extreme++
+			}
+		}
+
+		pValue := float64(1+extreme) / float64(1+permutations)
+		if pValue > sigLevel {
+			break
+		}
+
+		cps = append(cps, ChangePoint{
+			Index:      index,
+			Divergence: divergence,
+			PValue:     pValue,
+		})
+		bounds = append(bounds, index)
+		sort.Ints(bounds)
+	}
+
+	sort.Slice(cps, func(i, j int) bool {
+		return cps[i].Index < cps[j].Index
+	})
+	return cps, nil
+}
+
+// bestSplit returns the index (of the series) with the maximum divergence over all segments defined by bounds, where idx maps the positions to the values of the series (see dist), or -1 if no segment can be split
+func bestSplit(dist [][]float64, idx, bounds []int, minSize int) (int, float64) {
+	w := withinSums(dist, idx)
+	best, bestDivergence := -1, math.Inf(-1)
+	for s := 0; s < len(bounds)-1; s++ {
+		i, d := segmentSplit(w, bounds[s], bounds[s+1], minSize)
+		if i != -1 && d > bestDivergence {
+			best, bestDivergence = i, d
+		}
+	}
+	return best, bestDivergence
+}
+
+// withinSums returns the sums of the distances between all pairs of positions in [a, b) as w[a][b]
+func withinSums(dist [][]float64, idx []int) [][]float64 {
+	n := len(idx)
+	w := make([][]float64, n+1)
+	for a := range w {
+		w[a] = make([]float64, n+1)
+	}
+	for a := n - 2; a >= 0; a-- {
+		// sum of the distances between a and (a, b)
+		var row float64
+		for b := a + 2; b <= n; b++ {
+			row += dist[idx[a]][idx[b-1]]
+			w[a][b] = w[a+1][b] + row
+		}
+	}
+	return w
+}
+
+// segmentSplit returns the split index tau of the segment [start, end) with the maximum divergence between [start, tau) and [tau, kappa) for any kappa, or -1 if the segment is shorter than 2*minSize.
+// The divergence of m values before and n values after tau is m*n/(m+n) times their energy distance, i.e., 2 times the mean distance between the values before and after minus the mean distances within the values before and after.
+func segmentSplit(w [][]float64, start, end, minSize int) (int, float64) {
+	best, bestDivergence := -1, math.Inf(-1)
+	for tau := start + minSize; tau <= end-minSize; tau++ {
+		left := w[start][tau]
+		m := float64(tau - start)
+		for kappa := tau + minSize; kappa <= end; kappa++ {
+			right := w[tau][kappa]
+			across := w[start][kappa] - left - right
+			n := float64(kappa - tau)
+			divergence := m * n / (m + n) * (2*across/(m*n) - meanPairDistance(left, m) - meanPairDistance(right, n))
+			if divergence > bestDivergence {
+				best, bestDivergence = tau, divergence
+			}
+		}
+	}
+	return best, bestDivergence
+}
+
+// meanPairDistance returns the mean of the distances (with sum) between all pairs of n values, or 0 if there are none
+func meanPairDistance(sum, n float64) float64 {
+	if n < 2 {
+		return 0
+	}
+	return sum / (n * (n - 1) / 2)
+}
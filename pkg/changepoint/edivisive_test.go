@@ -0,0 +1,84 @@
+package changepoint_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/chrstphlbr/pa/pkg/changepoint"
+	"golang.org/x/exp/rand"
+)
+
+// seeded returns a random number generator with a fixed seed, which makes the permutation tests deterministic
+func seeded() *rand.Rand {
+	return rand.New(rand.NewSource(1))
+}
+
+// step returns a series with the values of levels, each repeated n times and with a small random jitter
+func step(n int, levels ...float64) []float64 {
+	rng := rand.New(rand.NewSource(1))
+	var s []float64
+	for _, l := range levels {
+		for i := 0; i < n; i++ {
+			s = append(s, l+0.1*(rng.Float64()-0.5))
+		}
+	}
+	return s
+}
+
+func indices(cps []changepoint.ChangePoint) []int {
+	is := make([]int, len(cps))
+	for i, cp := range cps {
+		is[i] = cp.Index
+	}
+	return is
+}
+
+func TestEDivisive(t *testing.T) {
+	for _, c := range []struct {
+		series   []float64
+		minSize  int
+		expected []int
+	}{
+		{step(20, 1), changepoint.DefaultMinSize, []int{}},
+		{step(20, 1, 2), changepoint.DefaultMinSize, []int{20}},
+		{step(15, 1, 2, 1), changepoint.DefaultMinSize, []int{15, 30}},
+		{step(15, 5, 3, 4), changepoint.DefaultMinSize, []int{15, 30}},
+		{append(step(20, 1), 3, 3, 3, 3, 3), changepoint.DefaultMinSize, []int{20}},
+		{[]float64{}, changepoint.DefaultMinSize, []int{}},
+	} {
+		// only changes without any as extreme permutation are significant
+		cps, err := changepoint.EDivisive(context.Background(), c.series, 0.001, 999, c.minSize, seeded())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		is := indices(cps)
+		if len(is) != len(c.expected) {
+			t.Fatalf("Unexpected change points: was %v, expected %v", is, c.expected)
+		}
+		for i := range is {
+			if is[i] != c.expected[i] {
+				t.Fatalf("Unexpected change points: was %v, expected %v", is, c.expected)
+			}
+			if p := cps[i].PValue; p <= 0 || p > 0.001 {
+				t.Fatalf("Unexpected p-value %f of change point %d", p, cps[i].Index)
+			}
+		}
+	}
+}
+
+func TestEDivisiveInvalid(t *testing.T) {
+	if _, err := changepoint.EDivisive(context.Background(), step(5, 1), 0.01, 100, 0, seeded()); err == nil {
+		t.Fatalf("Expected error for minimum size 0")
+	}
+	if _, err := changepoint.EDivisive(context.Background(), step(5, 1), 0.01, 0, 1, seeded()); err == nil {
+		t.Fatalf("Expected error for 0 permutations")
+	}
+}
+
+func TestEDivisiveCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := changepoint.EDivisive(ctx, step(20, 1, 2), 0.01, 100, 2, seeded()); err == nil {
+		t.Fatalf("Expected error for done context")
+	}
+}
@@ -0,0 +1,301 @@
+package permutation
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/chrstphlbr/pa/pkg/bench"
+	st "github.com/chrstphlbr/pa/pkg/stat"
+	"golang.org/x/exp/rand"
+)
+
+const DefaultPermutations = 10000
+
+// cancellationCheck defines after how many permutations a worker checks whether the context is done
+const cancellationCheck = 100
+
+// tolerance for permuted statistics that are as extreme as the observed one (e.g., the observed assignment itself)
+const tolerance = 1e-12
+
+// Level is the level of the execution hierarchy whose units are permuted between the versions
+type Level int
+
+const (
+	// AutoLevel permutes instances if both versions have at least two instances and forks otherwise
+	AutoLevel Level = iota
+	InstanceLevel
+	ForkLevel
+)
+
+func (l Level) String() string {
+	switch l {
+	case AutoLevel:
+		return "auto"
+	case InstanceLevel:
+		return "instance"
+	case ForkLevel:
+		return "fork"
+	}
+	return fmt.Sprintf("Level(%d)", int(l))
+}
+
+func ParseLevel(s string) (Level, error) {
+	switch s {
+	case "auto":
+		return AutoLevel, nil
+	case "instance":
+		return InstanceLevel, nil
+	case "fork":
+		return ForkLevel, nil
+	}
+	return AutoLevel, fmt.Errorf("invalid permutation level '%s' (available: auto, instance, fork)", s)
+}
+
+// Result is the result of a permutation test of two versions A and B
+type Result struct {
+	// Ratio is the statistic of B divided by the statistic of A
+	Ratio float64
+	// PValue is the two-sided p-value of the null hypothesis that both versions have the same distribution
+	PValue float64
+	// Permutations is the number of evaluated permutations (including the observed assignment if Exact)
+	Permutations int
+	// Exact defines whether all assignments of units to versions were evaluated
+	Exact bool
+	// Level is the permuted level
+	Level Level
+}
+
+type TestFunc = func(context.Context, bench.ExecutionSlice, bench.ExecutionSlice) (Result, error)
+
+func TestFuncSetup(permutations, maxNrWorkers int, statFunc st.StatisticFunc, level Level) TestFunc {
+	return func(ctx context.Context, a, b bench.ExecutionSlice) (Result, error) {
+		return Test(ctx, permutations, maxNrWorkers, statFunc, level, a, b)
+	}
+}
+
+// Test performs a hierarchical permutation test: the units of level (instances or forks) of both versions are pooled and reassigned to the versions, while keeping the number of units per version and the units themselves intact.
+// The test statistic is the ratio of the statistics (of the iterations' mean invocations, see bootstrap.CIRatio) of B and A, and a permutation is as extreme as the observed assignment if its ratio is at least as far from 1 on the log scale.
+// If there are at most permutations possible assignments, all of them are evaluated (exact test); otherwise permutations random assignments are evaluated by up to maxNrWorkers concurrent workers, and the p-value is (1 + extreme) / (1 + permutations).
+func Test(ctx context.Context, permutations, maxNrWorkers int, statFunc st.StatisticFunc, level Level, executionsA, executionsB bench.ExecutionSlice) (Result, error) {
+	fa, fb := executionsA.Flat(), executionsB.Flat()
+	if level == AutoLevel {
+		if fa.NrInstances() >= 2 && fb.NrInstances() >= 2 {
+			level = InstanceLevel
+		} else {
+			level = ForkLevel
+		}
+	}
+
+	ua, ub := units(fa, level), units(fb, level)
+	if len(ua) == 0 || len(ub) == 0 {
+		return Result{}, fmt.Errorf("permutation test requires at least one %s per version, had %d and %d", level, len(ua), len(ub))
+	}
+
+	p := &permuter{
+		units:    append(ua, ub...),
+		nrA:      len(ua),
+		statFunc: statFunc,
+	}
+	observed := p.ratio(p.identity())
+	res := Result{
+		Ratio: observed,
+		Level: level,
+	}
+	if math.IsNaN(observed) {
+		res.PValue = math.NaN()
+		return res, nil
+	}
+
+	threshold := math.Abs(math.Log(observed)) - tolerance
+
+	if c := binomial(len(p.units), p.nrA); c <= float64(permutations) {
+		extreme, err := p.exact(ctx, threshold)
+		if err != nil {
+			return Result{}, err
+		}
+		res.Permutations = int(c)
+		res.Exact = true
+		res.PValue = float64(extreme) / c
+		return res, nil
+	}
+
+	extreme, err := p.random(ctx, permutations, maxNrWorkers, threshold)
+	if err != nil {
+		return Result{}, err
+	}
+	res.Permutations = permutations
+	res.PValue = float64(1+extreme) / float64(1+permutations)
+	return res, nil
+}
+
+// units returns the iterations' mean invocations per unit of level
+func units(fe *bench.FlatExecution, level Level) [][]float64 {
+	// bounds of the units in terms of (global) iterations
+	var bounds []int
+	switch level {
+	case InstanceLevel:
+		bounds = make([]int, len(fe.Instances))
+		for i, t := range fe.Instances {
+			bounds[i] = fe.Forks[fe.Trials[t]]
+		}
+	default:
+		bounds = fe.Forks
+	}
+
+	us := make([][]float64, 0, len(bounds)-1)
+	for u := 0; u < len(bounds)-1; u++ {
+		if bounds[u] == bounds[u+1] {
+			continue
+		}
+		values := make([]float64, 0, bounds[u+1]-bounds[u])
+		for it := bounds[u]; it < bounds[u+1]; it++ {
+			values = append(values, bench.MeanInvocations(fe.IterationInvocations(it))...)
+		}
+		us = append(us, values)
+	}
+	return us
+}
+
+type permuter struct {
+	units    [][]float64
+	nrA      int
+	statFunc st.StatisticFunc
+}
+
+// identity returns the observed assignment, i.e., the units of A first
+func (p *permuter) identity() []int {
+	idx := make([]int, len(p.units))
+	for i := range idx {
+		idx[i] = i
+	}
+	return idx
+}
+
+// ratio computes the ratio of the statistics of the units idx[nrA:] (B) and idx[:nrA] (A)
+func (p *permuter) ratio(idx []int) float64 {
+	var a, b []float64
+	for i, u := range idx {
+		if i < p.nrA {
+			a = append(a, p.units[u]...)
+		} else {
+			b = append(b, p.units[u]...)
+		}
+	}
+	return p.statFunc(b) / p.statFunc(a)
+}
+
+func extreme(ratio, threshold float64) bool {
+	return math.Abs(math.Log(ratio)) >= threshold
+}
+
+// exact evaluates all assignments of nrA units to A and returns the number of extreme ones
+func (p *permuter) exact(ctx context.Context, threshold float64) (int, error) {
+	n := len(p.units)
+	// comb holds the units of A in ascending order
+	comb := make([]int, p.nrA)
+	for i := range comb {
+		comb[i] = i
+	}
+	idx := make([]int, n)
+	inA := make([]bool, n)
+
+	var count, evaluated int
+	for {
+		if evaluated%cancellationCheck == 0 && ctx.Err() != nil {
+			return 0, ctx.Err()
+		}
+		evaluated++
+
+		for i := range inA {
+			inA[i] = false
+		}
+		copy(idx, comb)
+		for _, u := range comb {
+			inA[u] = true
+		}
+		j := p.nrA
+		for u := 0; u < n; u++ {
+			if !inA[u] {
+				idx[j] = u
+				j++
+			}
+		}
+		if extreme(p.ratio(idx), threshold) {
+			count++
+		}
+
+		// next combination in lexicographic order
+		i := p.nrA - 1
+		for i >= 0 && comb[i] == n-p.nrA+i {
+			i--
+		}
+		if i < 0 {
+			return count, nil
+		}
+		comb[i]++
+		for k := i + 1; k < p.nrA; k++ {
+			comb[k] = comb[k-1] + 1
+		}
+	}
+}
+
+var workerSeed uint64
+
+// random evaluates permutations random assignments with up to maxNrWorkers concurrent workers and returns the number of extreme ones
+func (p *permuter) random(ctx context.Context, permutations, maxNrWorkers int, threshold float64) (int, error) {
+	nw := maxNrWorkers
+	if permutations < nw {
+		nw = permutations
+	}
+	if nw < 1 {
+		nw = 1
+	}
+
+	var count int64
+	var wg sync.WaitGroup
+	wg.Add(nw)
+	for i := 0; i < nw; i++ {
+		go func(i int) {
+			defer wg.Done()
+			seed := uint64(time.Now().UnixNano()) + atomic.AddUint64(&workerSeed, 1)
+			rng := rand.New(rand.NewSource(seed))
+			idx := p.identity()
+
+			var c int64
+			for perm, n := i, 0; perm < permutations; perm, n = perm+nw, n+1 {
+				if n%cancellationCheck == 0 && ctx.Err() != nil {
+					return
+				}
+				rng.Shuffle(len(idx), func(i, j int) {
+					idx[i], idx[j] = idx[j], idx[i]
+				})
+				if extreme(p.ratio(idx), threshold) {
+					c++
+				}
+			}
+			atomic.AddInt64(&count, c)
+		}(i)
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return int(count), nil
+}
+
+// binomial returns n choose k, or +Inf if it exceeds the float64 range
+func binomial(n, k int) float64 {
+	if k > n-k {
+		k = n - k
+	}
+	c := 1.0
+	for i := 1; i <= k; i++ {
+		c = c * float64(n-k+i) / float64(i)
+	}
+	return math.Round(c)
+}
@@ -0,0 +1,136 @@
+package permutation_test
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/chrstphlbr/pa/pkg/bench"
+	"github.com/chrstphlbr/pa/pkg/permutation"
+	"github.com/chrstphlbr/pa/pkg/stat"
+)
+
+// execution creates an execution with the values of every fork per instance, where every fork has 3 iterations with the fork's value
+func execution(t *testing.T, name string, instances ...[]float64) *bench.Execution {
+	b := bench.New(name)
+	e := bench.NewExecution(b)
+	for i, forks := range instances {
+		for f, v := range forks {
+			for it := 1; it <= 3; it++ {
+				err := e.AddInvocations(bench.InvocationsFlat{
+					Benchmark:   b,
+					Instance:    string(rune('a' + i)),
+					Trial:       1,
+					Fork:        f + 1,
+					Iteration:   it,
+					Invocations: bench.Invocations{Count: 1, Value: v},
+				})
+				if err != nil {
+					t.Fatalf("Could not add invocations: %v", err)
+				}
+			}
+		}
+	}
+	return e
+}
+
+func TestExact(t *testing.T) {
+	for _, c := range []struct {
+		a, b      []float64
+		ratio     float64
+		pValue    float64
+		nrResults int
+	}{
+		// only the observed assignment and its mirror are as extreme
+		{[]float64{10, 11, 12}, []float64{20, 21, 22}, 21.0 / 11, 2.0 / 20, 20},
+		// every assignment is as extreme
+		{[]float64{10, 10, 10}, []float64{10, 10, 10}, 1, 1, 20},
+		{[]float64{10}, []float64{20}, 2, 1, 2},
+	} {
+		ea := execution(t, "b1", c.a)
+		eb := execution(t, "b1", c.b)
+		res, err := permutation.Test(context.Background(), 1000, 2, stat.Mean, permutation.AutoLevel, ea, eb)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !res.Exact || res.Permutations != c.nrResults || res.Level != permutation.ForkLevel {
+			t.Fatalf("Unexpected exact test for %v and %v: %+v", c.a, c.b, res)
+		}
+		if math.Abs(res.Ratio-c.ratio) > 1e-12 || math.Abs(res.PValue-c.pValue) > 1e-12 {
+			t.Fatalf("Unexpected result for %v and %v: %+v, expected ratio %f and p-value %f", c.a, c.b, res, c.ratio, c.pValue)
+		}
+	}
+}
+
+func TestRandom(t *testing.T) {
+	var a, b []float64
+	for i := 0; i < 10; i++ {
+		a = append(a, 10+float64(i)/10)
+		b = append(b, 20+float64(i)/10)
+	}
+	ea := execution(t, "b1", a)
+	eb := execution(t, "b1", b)
+
+	// 20 choose 10 = 184756 > 1000 permutations
+	res, err := permutation.Test(context.Background(), 1000, 4, stat.Mean, permutation.ForkLevel, ea, eb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Exact || res.Permutations != 1000 {
+		t.Fatalf("Unexpected random test: %+v", res)
+	}
+	// a random assignment is as extreme with probability 2/184756
+	if res.PValue > 5.0/1001 {
+		t.Fatalf("Unexpected p-value: %f", res.PValue)
+	}
+}
+
+func TestInstanceLevel(t *testing.T) {
+	// forks differ within instances, but instances do not differ between versions
+	ea := execution(t, "b1", []float64{10, 20}, []float64{10, 20})
+	eb := execution(t, "b1", []float64{10, 20}, []float64{10, 20})
+
+	res, err := permutation.Test(context.Background(), 1000, 1, stat.Mean, permutation.AutoLevel, ea, eb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 4 choose 2 instance assignments
+	if res.Level != permutation.InstanceLevel || res.Permutations != 6 || !res.Exact || res.PValue != 1 {
+		t.Fatalf("Unexpected instance-level test: %+v", res)
+	}
+
+	res, err = permutation.Test(context.Background(), 1000, 1, stat.Mean, permutation.ForkLevel, ea, eb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 8 choose 4 fork assignments
+	if res.Level != permutation.ForkLevel || res.Permutations != 70 {
+		t.Fatalf("Unexpected fork-level test: %+v", res)
+	}
+}
+
+func TestCancelled(t *testing.T) {
+	ea := execution(t, "b1", []float64{10, 11, 12, 13, 14, 15, 16, 17, 18, 19})
+	eb := execution(t, "b1", []float64{20, 21, 22, 23, 24, 25, 26, 27, 28, 29})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, perms := range []int{1000, 1000000} {
+		if _, err := permutation.Test(ctx, perms, 2, stat.Mean, permutation.ForkLevel, ea, eb); err == nil {
+			t.Fatalf("Expected error for cancelled test with %d permutations", perms)
+		}
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for _, l := range []permutation.Level{permutation.AutoLevel, permutation.InstanceLevel, permutation.ForkLevel} {
+		pl, err := permutation.ParseLevel(l.String())
+		if err != nil || pl != l {
+			t.Fatalf("Unexpected level for '%s': %v (%v)", l, pl, err)
+		}
+	}
+	if _, err := permutation.ParseLevel("trial"); err == nil {
+		t.Fatalf("Expected error for unknown level")
+	}
+}
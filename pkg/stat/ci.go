@@ -11,10 +11,11 @@ import (
 type StatisticFunc func([]float64) float64
 
 type CI struct {
-	Metric float64
-	Lower  float64
-	Upper  float64
-	Level  float64
+	Metric      float64
+	Lower       float64
+	Upper       float64
+	Level       float64
+	Simulations int
 }
 
 type CIRatio struct {
@@ -0,0 +1,120 @@
+package stat
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Correction is a multiple-comparison correction of p-values
+type Correction int
+
+const (
+	NoCorrection Correction = iota
+	// Bonferroni controls the family-wise error rate
+	Bonferroni
+	// Holm controls the family-wise error rate and is uniformly more powerful than Bonferroni
+	Holm
+	// BenjaminiHochberg controls the false discovery rate
+	BenjaminiHochberg
+)
+
+func (c Correction) String() string {
+	switch c {
+	case NoCorrection:
+		return "none"
+	case Bonferroni:
+		return "bonferroni"
+	case Holm:
+		return "holm"
+	case BenjaminiHochberg:
+		return "bh"
+	}
+	return fmt.Sprintf("Correction(%d)", int(c))
+}
+
+func ParseCorrection(s string) (Correction, error) {
+	switch s {
+	case "none":
+		return NoCorrection, nil
+	case "bonferroni":
+		return Bonferroni, nil
+	case "holm":
+		return Holm, nil
+	case "bh", "fdr":
+		return BenjaminiHochberg, nil
+	}
+	return NoCorrection, fmt.Errorf("invalid correction '%s' (available: none, bonferroni, holm, bh)", s)
+}
+
+// Adjust returns the adjusted p-values (capped at 1) in the order of ps, which is not altered.
+// NaN p-values (e.g., of benchmarks without a test) stay NaN and do not count as comparisons.
+func (c Correction) Adjust(ps []float64) []float64 {
+	adj := make([]float64, len(ps))
+	copy(adj, ps)
+
+	// indices of the valid p-values in ascending order of p-value
+	idx := make([]int, 0, len(ps))
+	for i, p := range ps {
+		if !math.IsNaN(p) {
+			idx = append(idx, i)
+		}
+	}
+	sort.SliceStable(idx, func(i, j int) bool {
+		return ps[idx[i]] < ps[idx[j]]
+	})
+	m := float64(len(idx))
+
+	switch c {
+	case Bonferroni:
+		for _, i := range idx {
+			adj[i] = math.Min(1, ps[i]*m)
+		}
+	case Holm:
+		// step-down: p_(k) * (m-k+1), monotonically non-decreasing
+		max := 0.0
+		for k, i := range idx {
+			max = math.Max(max, ps[i]*(m-float64(k)))
+			adj[i] = math.Min(1, max)
+		}
+	case BenjaminiHochberg:
+		// step-up: p_(k) * m/k, monotonically non-increasing from the largest p-value
+		min := 1.0
+		for k := len(idx) - 1; k >= 0; k-- {
+			i := idx[k]
+			min = math.Min(min, ps[i]*m/float64(k+1))
+			adj[i] = min
+		}
+	}
+	return adj
+}
+
+// SigLevel returns the significance level of the CIs accompanying the adjusted p-values adjusted for significance level sl:
+// sl/m for Bonferroni and Holm (i.e., Bonferroni-adjusted CIs), R*sl/m for Benjamini-Hochberg with R the number of adjusted p-values at most sl (i.e., false coverage-statement rate adjusted CIs, Benjamini and Yekutieli 2005), and sl without correction.
+// NaN p-values do not count as comparisons.
+func (c Correction) SigLevel(sl float64, adjusted []float64) float64 {
+	m, r := 0, 0
+	for _, p := range adjusted {
+		if math.IsNaN(p) {
+			continue
+		}
+		m++
+		if p <= sl {
+			r++
+		}
+	}
+	if m == 0 {
+		return sl
+	}
+
+	switch c {
+	case Bonferroni, Holm:
+		return sl / float64(m)
+	case BenjaminiHochberg:
+		if r == 0 {
+			r = 1
+		}
+		return float64(r) * sl / float64(m)
+	}
+	return sl
+}
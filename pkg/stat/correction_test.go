@@ -0,0 +1,73 @@
+package stat_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/chrstphlbr/pa/pkg/stat"
+)
+
+func TestCorrectionAdjust(t *testing.T) {
+	ps := []float64{0.04, 0.01, math.NaN(), 0.03, 0.2}
+
+	for _, c := range []struct {
+		correction stat.Correction
+		expected   []float64
+	}{
+		{stat.NoCorrection, []float64{0.04, 0.01, math.NaN(), 0.03, 0.2}},
+		{stat.Bonferroni, []float64{0.16, 0.04, math.NaN(), 0.12, 0.8}},
+		{stat.Holm, []float64{0.09, 0.04, math.NaN(), 0.09, 0.2}},
+		{stat.BenjaminiHochberg, []float64{0.04 * 4 / 3, 0.04, math.NaN(), 0.04 * 4 / 3, 0.2}},
+	} {
+		adj := c.correction.Adjust(ps)
+		for i, e := range c.expected {
+			if math.IsNaN(e) != math.IsNaN(adj[i]) || math.Abs(adj[i]-e) > 1e-12 {
+				t.Fatalf("Unexpected %s-adjusted p-values: was %v, expected %v", c.correction, adj, c.expected)
+			}
+		}
+	}
+
+	if ps[0] != 0.04 || ps[1] != 0.01 {
+		t.Fatalf("p-values altered: %v", ps)
+	}
+
+	if adj := stat.Bonferroni.Adjust([]float64{0.5, 0.6}); adj[0] != 1 || adj[1] != 1 {
+		t.Fatalf("Expected adjusted p-values capped at 1: %v", adj)
+	}
+}
+
+func TestCorrectionSigLevel(t *testing.T) {
+	adj := []float64{0.001, 0.004, math.NaN(), 0.5, 0.02}
+
+	for _, c := range []struct {
+		correction stat.Correction
+		expected   float64
+	}{
+		{stat.NoCorrection, 0.01},
+		{stat.Bonferroni, 0.0025},
+		{stat.Holm, 0.0025},
+		// 2 of 4 adjusted p-values are significant
+		{stat.BenjaminiHochberg, 0.005},
+	} {
+		if sl := c.correction.SigLevel(0.01, adj); math.Abs(sl-c.expected) > 1e-12 {
+			t.Fatalf("Unexpected %s significance level: was %g, expected %g", c.correction, sl, c.expected)
+		}
+	}
+
+	// without significant p-values, BH corresponds to Bonferroni
+	if sl := stat.BenjaminiHochberg.SigLevel(0.01, []float64{0.5, 0.6}); sl != 0.005 {
+		t.Fatalf("Unexpected significance level: %g", sl)
+	}
+}
+
+func TestParseCorrection(t *testing.T) {
+	for _, c := range []stat.Correction{stat.NoCorrection, stat.Bonferroni, stat.Holm, stat.BenjaminiHochberg} {
+		pc, err := stat.ParseCorrection(c.String())
+		if err != nil || pc != c {
+			t.Fatalf("Unexpected correction for '%s': %v (%v)", c, pc, err)
+		}
+	}
+	if _, err := stat.ParseCorrection("sidak"); err == nil {
+		t.Fatalf("Expected error for unknown correction")
+	}
+}
@@ -0,0 +1,151 @@
+package stat
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// exactRankTestSize is the sample size up to which (exclusive) the Mann-Whitney U test computes exact p-values if there are no ties
+const exactRankTestSize = 50
+
+// RankResult is the result of a Mann-Whitney U test of two samples A and B
+type RankResult struct {
+	// U is the Mann-Whitney U statistic of B, i.e., the number of pairs where the value of B is greater than the value of A (ties count half)
+	U float64
+	// PValue is the two-sided p-value of the null hypothesis that both samples have the same distribution
+	PValue float64
+	// Exact defines whether the p-value is exact or from the normal approximation (with tie and continuity correction)
+	Exact bool
+	// A12 is the Vargha-Delaney effect size, i.e., the probability that a value of B is greater than a value of A (ties count half)
+	A12 float64
+	// CliffsDelta is Cliff's delta effect size, i.e., the probability that a value of B is greater minus the probability that it is smaller than a value of A
+	CliffsDelta float64
+}
+
+// MannWhitneyU performs a Mann-Whitney U (Wilcoxon rank-sum) test of the samples a and b and computes the effect sizes A12 and Cliff's delta.
+// The p-value is exact if both samples have less than 50 values and there are no ties, and otherwise from the normal approximation with tie and continuity correction.
+func MannWhitneyU(a, b []float64) (RankResult, error) {
+	na, nb := len(a), len(b)
+	if na == 0 || nb == 0 {
+		return RankResult{}, fmt.Errorf("Mann-Whitney U test requires non-empty samples, had %d and %d values", na, nb)
+	}
+
+	rankSumB, ties := rankSum(a, b)
+	fa, fb := float64(na), float64(nb)
+	u := rankSumB - fb*(fb+1)/2
+	a12 := u / (fa * fb)
+
+	res := RankResult{
+		U:           u,
+		A12:         a12,
+		CliffsDelta: 2*a12 - 1,
+	}
+
+	if na < exactRankTestSize && nb < exactRankTestSize && ties == 0 {
+		res.PValue = exactUPValue(u, na, nb)
+		res.Exact = true
+		return res, nil
+	}
+
+	n := fa + fb
+	mean := fa * fb / 2
+	variance := fa * fb / 12 * ((n + 1) - ties/(n*(n-1)))
+	if variance <= 0 {
+		// all values are equal
+		res.PValue = 1
+		return res, nil
+	}
+	d := u - mean
+	// continuity correction
+	switch {
+	case d > 0:
+		d = math.Max(0, d-0.5)
+	case d < 0:
+		d = math.Min(0, d+0.5)
+	}
+	z := d / math.Sqrt(variance)
+	res.PValue = math.Min(1, math.Erfc(math.Abs(z)/math.Sqrt2))
+	return res, nil
+}
+
+// VarghaDelaneyA12 computes the probability that a value of b is greater than a value of a (ties count half)
+func VarghaDelaneyA12(a, b []float64) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return math.NaN()
+	}
+	rankSumB, _ := rankSum(a, b)
+	fa, fb := float64(len(a)), float64(len(b))
+	return (rankSumB - fb*(fb+1)/2) / (fa * fb)
+}
+
+// CliffsDelta computes the probability that a value of b is greater minus the probability that it is smaller than a value of a
+func CliffsDelta(a, b []float64) float64 {
+	return 2*VarghaDelaneyA12(a, b) - 1
+}
+
+// rankSum returns the sum of the (average) ranks of b in the pooled samples and the tie correction term sum(t^3 - t) over all groups of t tied values
+func rankSum(a, b []float64) (rankSumB, ties float64) {
+	type value struct {
+		v float64
+		b bool
+	}
+	vs := make([]value, 0, len(a)+len(b))
+	for _, v := range a {
+		vs = append(vs, value{v: v})
+	}
+	for _, v := range b {
+		vs = append(vs, value{v: v, b: true})
+	}
+	sort.Slice(vs, func(i, j int) bool {
+		return vs[i].v < vs[j].v
+	})
+
+	for i := 0; i < len(vs); {
+		j := i + 1
+		for j < len(vs) && vs[j].v == vs[i].v {
+			j++
+		}
+		// ranks i+1 to j share their average
+		rank := float64(i+1+j) / 2
+		for k := i; k < j; k++ {
+			if vs[k].b {
+				rankSumB += rank
+			}
+		}
+		t := float64(j - i)
+		ties += t*t*t - t
+		i = j
+	}
+	return rankSumB, ties
+}
+
+// exactUPValue computes the exact two-sided p-value of U for samples of sizes na and nb without ties
+func exactUPValue(u float64, na, nb int) float64 {
+	// the number of assignments with U = k is the coefficient of q^k of the Gaussian binomial coefficient [na+nb choose na]_q = prod_{i=1}^{na} (1 - q^(nb+i)) / (1 - q^i)
+	max := na * nb
+	c := make([]float64, max+1)
+	c[0] = 1
+	for i := 1; i <= na; i++ {
+		// multiply by (1 - q^(nb+i))
+		for k := max; k >= nb+i; k-- {
+			c[k] -= c[k-nb-i]
+		}
+		// divide by (1 - q^i)
+		for k := i; k <= max; k++ {
+			c[k] += c[k-i]
+		}
+	}
+
+	var total, lower, upper float64
+	for k, ck := range c {
+		total += ck
+		if float64(k) <= u {
+			lower += ck
+		}
+		if float64(k) >= u {
+			upper += ck
+		}
+	}
+	return math.Min(1, 2*math.Min(lower, upper)/total)
+}
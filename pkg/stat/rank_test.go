@@ -0,0 +1,79 @@
+package stat_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/chrstphlbr/pa/pkg/stat"
+)
+
+func TestMannWhitneyUExact(t *testing.T) {
+	for _, c := range []struct {
+		a, b   []float64
+		u      float64
+		pValue float64
+	}{
+		// completely separated: 2 of C(6,3) = 20 assignments are as extreme
+		{[]float64{1, 2, 3}, []float64{4, 5, 6}, 9, 0.1},
+		{[]float64{4, 5, 6}, []float64{1, 2, 3}, 0, 0.1},
+		// R: wilcox.test(c(1.1, 2.2, 3.3, 4.4), c(3.9, 5.5, 6.6, 7.7))$p.value = 0.05714286
+		{[]float64{1.1, 2.2, 3.3, 4.4}, []float64{3.9, 5.5, 6.6, 7.7}, 15, 4.0 / 70},
+		{[]float64{1, 4}, []float64{2, 3}, 2, 1},
+	} {
+		res, err := stat.MannWhitneyU(c.a, c.b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !res.Exact || res.U != c.u || math.Abs(res.PValue-c.pValue) > 1e-9 {
+			t.Fatalf("Unexpected result for %v and %v: %+v, expected U %g and p-value %g", c.a, c.b, res, c.u, c.pValue)
+		}
+	}
+}
+
+func TestMannWhitneyUNormal(t *testing.T) {
+	// ties -> normal approximation
+	// R: wilcox.test(c(3, 4, 4, 5, 6), c(1, 2, 2, 3, 4), exact=FALSE, correct=TRUE)$p.value = 0.04322
+	res, err := stat.MannWhitneyU([]float64{1, 2, 2, 3, 4}, []float64{3, 4, 4, 5, 6})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Exact || res.U != 22.5 || math.Abs(res.PValue-0.04322) > 1e-4 {
+		t.Fatalf("Unexpected result: %+v", res)
+	}
+
+	res, err = stat.MannWhitneyU([]float64{1, 1, 1}, []float64{1, 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.PValue != 1 || res.A12 != 0.5 || res.CliffsDelta != 0 {
+		t.Fatalf("Unexpected result for equal samples: %+v", res)
+	}
+
+	if _, err := stat.MannWhitneyU(nil, []float64{1}); err == nil {
+		t.Fatalf("Expected error for empty sample")
+	}
+}
+
+func TestEffectSizes(t *testing.T) {
+	a := []float64{1, 2, 3, 4}
+	b := []float64{3, 4, 5, 6}
+	// B greater in 13 pairs, equal in 2 pairs, and smaller in 1 pair: (13 + 1) / 16
+	if a12 := stat.VarghaDelaneyA12(a, b); a12 != 14.0/16 {
+		t.Fatalf("Unexpected A12: %g", a12)
+	}
+	// (13 - 1) / 16
+	if d := stat.CliffsDelta(a, b); d != 12.0/16 {
+		t.Fatalf("Unexpected Cliff's delta: %g", d)
+	}
+	if a12 := stat.VarghaDelaneyA12(b, a); a12 != 2.0/16 {
+		t.Fatalf("Unexpected A12: %g", a12)
+	}
+
+	res, err := stat.MannWhitneyU(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.A12 != 14.0/16 || res.CliffsDelta != 12.0/16 {
+		t.Fatalf("Unexpected effect sizes: %+v", res)
+	}
+}
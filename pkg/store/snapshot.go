@@ -0,0 +1,83 @@
+package store
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"time"
+)
+
+// snapshotMagic are the first bytes of a snapshot
+const snapshotMagic = "PASN"
+
+// maxHeaderString bounds the length of the project and commit of a header
+const maxHeaderString = 1 << 16
+
+// header is the preamble of a snapshot, which is followed by its executions in the binary format (see bench.WriteBinary).
+// It consists of snapshotMagic, the snapshot version (one byte), the project and commit (strings as in the binary format), and the creation time (varint of Unix nanoseconds).
+type header struct {
+	Version int
+	Project string
+	Commit  string
+	Created time.Time
+}
+
+func (h header) check() error {
+	if h.Version != SnapshotVersion {
+		return fmt.Errorf("unsupported snapshot version %d (supported: %d)", h.Version, SnapshotVersion)
+	}
+	return nil
+}
+
+func (h header) write(w *bufio.Writer) error {
+	var buf [binary.MaxVarintLen64]byte
+	w.WriteString(snapshotMagic)
+	w.WriteByte(byte(h.Version))
+	for _, s := range []string{h.Project, h.Commit} {
+		n := binary.PutUvarint(buf[:], uint64(len(s)))
+		w.Write(buf[:n])
+		w.WriteString(s)
+	}
+	n := binary.PutVarint(buf[:], h.Created.UnixNano())
+	_, err := w.Write(buf[:n])
+	return err
+}
+
+// readHeader reads and checks the header of a snapshot
+func readHeader(r *bufio.Reader) (header, error) {
+	var h header
+	head := make([]byte, len(snapshotMagic)+1)
+	_, err := io.ReadFull(r, head)
+	if err != nil || string(head[:len(snapshotMagic)]) != snapshotMagic {
+		return h, fmt.Errorf("not a snapshot")
+	}
+	h.Version = int(head[len(snapshotMagic)])
+	err = h.check()
+	if err != nil {
+		return h, err
+	}
+
+	for _, s := range []*string{&h.Project, &h.Commit} {
+		n, err := binary.ReadUvarint(r)
+		if err != nil {
+			return h, err
+		}
+		if n > maxHeaderString {
+			return h, fmt.Errorf("header string length %d exceeds limit of %d bytes", n, maxHeaderString)
+		}
+		b := make([]byte, n)
+		_, err = io.ReadFull(r, b)
+		if err != nil {
+			return h, err
+		}
+		*s = string(b)
+	}
+
+	created, err := binary.ReadVarint(r)
+	if err != nil {
+		return h, err
+	}
+	h.Created = time.Unix(0, created)
+	return h, nil
+}
@@ -0,0 +1,385 @@
+package store
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/chrstphlbr/pa/pkg/bench"
+)
+
+// SnapshotVersion is the version of the snapshot format written by the store, i.e., of its header (see header) and the binary format of its executions (see bench.BinaryVersion)
+const SnapshotVersion = 2
+
+const snapshotExt = ".snap"
+
+// noProject is the directory of the snapshots of executions without project
+const noProject = "_"
+
+// Store is a directory of execution snapshots, with one snapshot per project and commit in '<dir>/<project>/<commit>.snap'
+type Store struct {
+	dir string
+}
+
+// Entry is a snapshot of a store
+type Entry struct {
+	Project string
+	Commit  string
+	Created time.Time
+	// Size is the size of the snapshot in bytes
+	Size int64
+	path string
+}
+
+func (e Entry) String() string {
+	if e.Project == "" {
+		return e.Commit
+	}
+	return e.Project + "/" + e.Commit
+}
+
+// Open opens the store in dir, which is created if it does not exist
+func Open(dir string) (*Store, error) {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, fmt.Errorf("could not create store '%s': %w", dir, err)
+	}
+	return &Store{
+		dir: dir,
+	}, nil
+}
+
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+func (s *Store) path(project, commit string) string {
+	pd := noProject
+	if project != "" {
+		pd = url.PathEscape(project)
+	}
+	return filepath.Join(s.dir, pd, url.PathEscape(commit)+snapshotExt)
+}
+
+// Add stores the executions of c with one snapshot per project and commit of the executions (see bench.FromCSVPerCommit), which replaces an existing snapshot of the same project and commit.
+// The snapshots are only stored if c is read completely without errors.
+func (s *Store) Add(ctx context.Context, c bench.Chan) ([]Entry, error) {
+	created := time.Now()
+	ws := make(map[string]*snapshotWriter)
+	var order []string
+	abort := func() {
+		for _, w := range ws {
+			w.abort()
+		}
+	}
+
+	for {
+		var ev bench.ExecutionValue
+		var ok bool
+		select {
+		case ev, ok = <-c:
+		case <-ctx.Done():
+			abort()
+			return nil, ctx.Err()
+		}
+		if !ok {
+			abort()
+			return nil, fmt.Errorf("input ended before all executions were read")
+		}
+
+		switch ev.Type {
+		case bench.ExecStart:
+			continue
+		case bench.ExecError:
+			abort()
+			return nil, ev.Err
+		case bench.ExecEnd:
+			entries := make([]Entry, 0, len(order))
+			for i, k := range order {
+				e, err := ws[k].close()
+				if err != nil {
+					for _, k := range order[i+1:] {
+						ws[k].abort()
+					}
+					return nil, err
+				}
+				entries = append(entries, e)
+			}
+			return entries, nil
+		}
+
+		e := ev.Exec
+		if e.Commit == "" {
+			abort()
+			return nil, fmt.Errorf("execution of %v has no commit", e.Benchmark)
+		}
+		p := s.path(e.Project, e.Commit)
+		w, ok := ws[p]
+		if !ok {
+			var err error
+			w, err = newSnapshotWriter(p, header{
+				Version: SnapshotVersion,
+				Project: e.Project,
+				Commit:  e.Commit,
+				Created: created,
+			})
+			if err != nil {
+				abort()
+				return nil, err
+			}
+			ws[p] = w
+			order = append(order, p)
+		}
+		err := w.write(e)
+		if err != nil {
+			abort()
+			return nil, err
+		}
+	}
+}
+
+// List returns all snapshots of the store, ordered by project and creation time
+func (s *Store) List() ([]Entry, error) {
+	pds, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var es []Entry
+	for _, pd := range pds {
+		if !pd.IsDir() {
+			continue
+		}
+		fis, err := ioutil.ReadDir(filepath.Join(s.dir, pd.Name()))
+		if err != nil {
+			return nil, err
+		}
+		for _, fi := range fis {
+			// skip temporary snapshots (see snapshotWriter)
+			if fi.IsDir() || filepath.Ext(fi.Name()) != snapshotExt || strings.HasPrefix(fi.Name(), ".") {
+				continue
+			}
+			p := filepath.Join(s.dir, pd.Name(), fi.Name())
+			e, err := readEntry(p)
+			if err != nil {
+				return nil, err
+			}
+			e.Size = fi.Size()
+			es = append(es, e)
+		}
+	}
+
+	sort.SliceStable(es, func(i, j int) bool {
+		if es[i].Project != es[j].Project {
+			return es[i].Project < es[j].Project
+		}
+		return es[i].Created.Before(es[j].Created)
+	})
+	return es, nil
+}
+
+// Find returns the snapshot of ref, which is either '<commit>' (if only one project has the commit) or '<project>/<commit>'
+func (s *Store) Find(ref string) (Entry, error) {
+	es, err := s.List()
+	if err != nil {
+		return Entry{}, err
+	}
+
+	var found []Entry
+	for _, e := range es {
+		if e.Commit == ref || e.String() == ref {
+			found = append(found, e)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return Entry{}, fmt.Errorf("no snapshot of '%s' in store '%s'", ref, s.dir)
+	case 1:
+		return found[0], nil
+	}
+	projects := make([]string, len(found))
+	for i, e := range found {
+		projects[i] = e.Project
+	}
+	return Entry{}, fmt.Errorf("commit '%s' is ambiguous, it is in the projects %s (use '<project>/<commit>')", ref, strings.Join(projects, ", "))
+}
+
+// Chan streams the executions of the snapshot e, in the same form as bench.FromCSVPerCommit
+func (s *Store) Chan(ctx context.Context, e Entry) (bench.Chan, error) {
+	f, err := os.Open(e.path)
+	if err != nil {
+		return nil, err
+	}
+	r := bufio.NewReader(f)
+	_, err = readHeader(r)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("could not read snapshot '%s': %w", e.path, err)
+	}
+	bc, err := bench.FromBinaryPerCommit(ctx, r, bench.Reduction{})
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("could not read snapshot '%s': %w", e.path, err)
+	}
+
+	c := make(bench.Chan)
+	go func() {
+		defer close(c)
+		defer f.Close()
+
+		for ev := range bc {
+			if ev.Type == bench.ExecError {
+				ev.Err = fmt.Errorf("could not read snapshot '%s': %w", e.path, ev.Err)
+			}
+			if !send(ctx, c, ev) {
+				return
+			}
+		}
+	}()
+
+	return c, nil
+}
+
+func send(ctx context.Context, c bench.Chan, ev bench.ExecutionValue) bool {
+	select {
+	case c <- ev:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func readEntry(path string) (Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Entry{}, err
+	}
+	defer f.Close()
+
+	h, err := readHeader(bufio.NewReader(f))
+	if err != nil {
+		return Entry{}, fmt.Errorf("could not read snapshot '%s': %w", path, err)
+	}
+	return Entry{
+		Project: h.Project,
+		Commit:  h.Commit,
+		Created: h.Created,
+		path:    path,
+	}, nil
+}
+
+// snapshotWriter writes a snapshot to a temporary file, which replaces the snapshot when closed.
+// The executions are written by bench.WriteBinary, which receives them from c.
+type snapshotWriter struct {
+	path string
+	h    header
+	f    *os.File
+	// cancel stops WriteBinary
+	cancel context.CancelFunc
+	c      bench.Chan
+	// done receives the error of WriteBinary
+	done chan error
+}
+
+func newSnapshotWriter(path string, h header) (*snapshotWriter, error) {
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return nil, err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(path), ".tmp-*"+snapshotExt)
+	if err != nil {
+		return nil, err
+	}
+	err = f.Chmod(0644)
+	if err == nil {
+		bw := bufio.NewWriter(f)
+		err = h.write(bw)
+		if err == nil {
+			err = bw.Flush()
+		}
+	}
+	if err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &snapshotWriter{
+		path:   path,
+		h:      h,
+		f:      f,
+		cancel: cancel,
+		c:      make(bench.Chan),
+		done:   make(chan error, 1),
+	}
+	go func() {
+		_, err := bench.WriteBinary(ctx, f, w.c)
+		w.done <- err
+	}()
+	w.c <- bench.ExecutionValue{Type: bench.ExecStart}
+	return w, nil
+}
+
+func (w *snapshotWriter) write(e *bench.Execution) error {
+	select {
+	case w.c <- bench.ExecutionValue{Type: bench.ExecNext, Exec: e}:
+		return nil
+	case err := <-w.done:
+		// WriteBinary only returns early on errors
+		w.done <- err
+		return err
+	}
+}
+
+func (w *snapshotWriter) close() (Entry, error) {
+	var err error
+	select {
+	case w.c <- bench.ExecutionValue{Type: bench.ExecEnd}:
+		err = <-w.done
+	case err = <-w.done:
+	}
+	w.cancel()
+	if err != nil {
+		w.f.Close()
+		os.Remove(w.f.Name())
+		return Entry{}, err
+	}
+	fi, err := w.f.Stat()
+	if err != nil {
+		w.f.Close()
+		os.Remove(w.f.Name())
+		return Entry{}, err
+	}
+	err = w.f.Close()
+	if err != nil {
+		os.Remove(w.f.Name())
+		return Entry{}, err
+	}
+	err = os.Rename(w.f.Name(), w.path)
+	if err != nil {
+		os.Remove(w.f.Name())
+		return Entry{}, err
+	}
+	return Entry{
+		Project: w.h.Project,
+		Commit:  w.h.Commit,
+		Created: w.h.Created,
+		Size:    fi.Size(),
+		path:    w.path,
+	}, nil
+}
+
+func (w *snapshotWriter) abort() {
+	w.cancel()
+	<-w.done
+	w.f.Close()
+	os.Remove(w.f.Name())
+}
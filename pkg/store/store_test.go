@@ -0,0 +1,220 @@
+package store_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/chrstphlbr/pa/pkg/bench"
+	"github.com/chrstphlbr/pa/pkg/store"
+)
+
+const input = `project;commit;benchmark;params;instance;trial;fork;iteration;mode;unit;value_count;value
+p1;c1;b1;size=1;i1;1;1;1;avgt;ns/op;2;1.5
+p1;c1;b1;size=1;i1;1;1;2;avgt;ns/op;1;1.7
+p1;c1;b1;size=1;i1;1;2;1;avgt;ns/op;1;1.6
+p1;c2;b1;size=1;i1;1;1;1;avgt;ns/op;1;2.5
+p1;c1;b1;size=2;i2;1;1;1;avgt;ns/op;1;3.5
+p1;c1;b2;;i1;1;1;1;avgt;ns/op;3;4.5
+p1;c2;b2;;i1;1;1;1;avgt;ns/op;1;5.5
+`
+
+func tempStore(t *testing.T) (*store.Store, func()) {
+	dir, err := ioutil.TempDir("", "pa-store")
+	if err != nil {
+		t.Fatalf("Could not create temporary directory: %v", err)
+	}
+	s, err := store.Open(dir)
+	if err != nil {
+		t.Fatalf("Could not open store: %v", err)
+	}
+	return s, func() { os.RemoveAll(dir) }
+}
+
+func perCommit(t *testing.T, in string) bench.Chan {
+	c, err := bench.FromCSVPerCommit(context.Background(), strings.NewReader(in), bench.Reduction{})
+	if err != nil {
+		t.Fatalf("Could not read CSV: %v", err)
+	}
+	return c
+}
+
+func executions(t *testing.T, c bench.Chan) []*bench.Execution {
+	var es []*bench.Execution
+	var ended bool
+	for ev := range c {
+		switch ev.Type {
+		case bench.ExecError:
+			t.Fatalf("Unexpected error: %v", ev.Err)
+		case bench.ExecNext:
+			es = append(es, ev.Exec)
+		case bench.ExecEnd:
+			ended = true
+		}
+	}
+	if !ended {
+		t.Fatalf("Expected end of executions")
+	}
+	return es
+}
+
+func TestAddAndChan(t *testing.T) {
+	s, cleanup := tempStore(t)
+	defer cleanup()
+
+	es, err := s.Add(context.Background(), perCommit(t, input))
+	if err != nil {
+		t.Fatalf("Could not add to store: %v", err)
+	}
+	if len(es) != 2 || es[0].String() != "p1/c1" || es[1].String() != "p1/c2" {
+		t.Fatalf("Unexpected entries: %v", es)
+	}
+
+	listed, err := s.List()
+	if err != nil {
+		t.Fatalf("Could not list store: %v", err)
+	}
+	if len(listed) != 2 {
+		t.Fatalf("Expected 2 entries, was %v", listed)
+	}
+
+	// executions per commit as read from the CSV
+	expected := map[string][]*bench.Execution{}
+	for _, e := range executions(t, perCommit(t, input)) {
+		expected[e.Commit] = append(expected[e.Commit], e)
+	}
+
+	for _, commit := range []string{"c1", "p1/c2"} {
+		e, err := s.Find(commit)
+		if err != nil {
+			t.Fatalf("Could not find %s: %v", commit, err)
+		}
+		c, err := s.Chan(context.Background(), e)
+		if err != nil {
+			t.Fatalf("Could not read %s: %v", commit, err)
+		}
+		got := executions(t, c)
+		exp := expected[e.Commit]
+		if len(got) != len(exp) {
+			t.Fatalf("Expected %d executions of %s, was %d", len(exp), commit, len(got))
+		}
+		for i := range got {
+			if !got[i].Benchmark.Equals(exp[i].Benchmark) {
+				t.Fatalf("Expected benchmark %v, was %v", exp[i].Benchmark, got[i].Benchmark)
+			}
+			if got[i].Project != "p1" || got[i].Commit != e.Commit {
+				t.Fatalf("Unexpected project and commit %s/%s", got[i].Project, got[i].Commit)
+			}
+			if got[i].ElementCount() != exp[i].ElementCount() {
+				t.Fatalf("Expected %d elements, was %d", exp[i].ElementCount(), got[i].ElementCount())
+			}
+			if !reflect.DeepEqual(got[i].Slice(bench.AllInvocations), exp[i].Slice(bench.AllInvocations)) {
+				t.Fatalf("Unexpected executions of %v: was %v, expected %v", got[i].Benchmark, got[i].Slice(bench.AllInvocations), exp[i].Slice(bench.AllInvocations))
+			}
+		}
+	}
+}
+
+func TestAddReplaces(t *testing.T) {
+	s, cleanup := tempStore(t)
+	defer cleanup()
+
+	for i := 0; i < 2; i++ {
+		_, err := s.Add(context.Background(), perCommit(t, input))
+		if err != nil {
+			t.Fatalf("Could not add to store: %v", err)
+		}
+	}
+	es, err := s.List()
+	if err != nil {
+		t.Fatalf("Could not list store: %v", err)
+	}
+	if len(es) != 2 {
+		t.Fatalf("Expected 2 entries, was %v", es)
+	}
+}
+
+func TestAddError(t *testing.T) {
+	s, cleanup := tempStore(t)
+	defer cleanup()
+
+	c := make(bench.Chan, 3)
+	e := bench.NewExecution(bench.New("b1"))
+	e.Commit = "c1"
+	c <- bench.ExecutionValue{Type: bench.ExecStart}
+	c <- bench.ExecutionValue{Type: bench.ExecNext, Exec: e}
+	c <- bench.ExecutionValue{Type: bench.ExecError, Err: errors.New("e")}
+	close(c)
+
+	_, err := s.Add(context.Background(), c)
+	if err == nil {
+		t.Fatalf("Expected error")
+	}
+	es, err := s.List()
+	if err != nil {
+		t.Fatalf("Could not list store: %v", err)
+	}
+	if len(es) != 0 {
+		t.Fatalf("Expected no entries, was %v", es)
+	}
+}
+
+func TestFind(t *testing.T) {
+	s, cleanup := tempStore(t)
+	defer cleanup()
+
+	in := input + strings.Replace(input[strings.Index(input, "\n")+1:], "p1;", "p2;", -1)
+	in = strings.Replace(in, "p2;c2", "p2;c3", -1)
+	_, err := s.Add(context.Background(), perCommit(t, in))
+	if err != nil {
+		t.Fatalf("Could not add to store: %v", err)
+	}
+
+	for _, c := range []struct {
+		ref      string
+		expected string
+	}{
+		{"c2", "p1/c2"},
+		{"c3", "p2/c3"},
+		{"p2/c1", "p2/c1"},
+		{"c1", ""},
+		{"c4", ""},
+	} {
+		e, err := s.Find(c.ref)
+		if c.expected == "" {
+			if err == nil {
+				t.Fatalf("Expected error for %s, was %v", c.ref, e)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Could not find %s: %v", c.ref, err)
+		}
+		if e.String() != c.expected {
+			t.Fatalf("Expected %s for %s, was %s", c.expected, c.ref, e)
+		}
+	}
+}
+
+func TestListInvalid(t *testing.T) {
+	s, cleanup := tempStore(t)
+	defer cleanup()
+
+	_, err := s.Add(context.Background(), perCommit(t, input))
+	if err != nil {
+		t.Fatalf("Could not add to store: %v", err)
+	}
+	err = ioutil.WriteFile(filepath.Join(s.Dir(), "p1", "c3.snap"), []byte("PASN\x01"), 0644)
+	if err != nil {
+		t.Fatalf("Could not write snapshot: %v", err)
+	}
+	_, err = s.List()
+	if err == nil {
+		t.Fatalf("Expected error for unsupported snapshot version")
+	}
+}
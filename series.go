@@ -0,0 +1,407 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/chrstphlbr/pa/internal/ordered"
+	"github.com/chrstphlbr/pa/pkg/bench"
+	"github.com/chrstphlbr/pa/pkg/bootstrap"
+	"github.com/chrstphlbr/pa/pkg/stat"
+)
+
+// seriesGroup is a version (e.g., a commit) of a series with its execution files
+type seriesGroup struct {
+	Label string
+	Files []string
+}
+
+// seriesGroupsFromArgs creates a group per argument, which is either a directory (with all its input files, see trimInputExt), a file, or stdin, labelled by its base name without extension
+func seriesGroupsFromArgs(args []string) ([]seriesGroup, error) {
+	gs := make([]seriesGroup, 0, len(args))
+	for _, arg := range args {
+		if arg == stdinFile {
+			gs = append(gs, seriesGroup{
+				Label: "stdin",
+				Files: []string{arg},
+			})
+			continue
+		}
+
+		fi, err := os.Stat(arg)
+		if err != nil {
+			return nil, err
+		}
+
+		label, ok := trimInputExt(filepath.Base(arg))
+		if !ok {
+			label = strings.TrimSuffix(label, filepath.Ext(label))
+		}
+		g := seriesGroup{
+			Label: label,
+		}
+		if !fi.IsDir() {
+			g.Files = []string{arg}
+			gs = append(gs, g)
+			continue
+		}
+
+		fis, err := ioutil.ReadDir(arg)
+		if err != nil {
+			return nil, err
+		}
+		for _, fi := range fis {
+			if _, ok := trimInputExt(fi.Name()); !fi.IsDir() && ok {
+				g.Files = append(g.Files, filepath.Join(arg, fi.Name()))
+			}
+		}
+		if len(g.Files) == 0 {
+			return nil, fmt.Errorf("directory '%s' has no input files", arg)
+		}
+		sort.Strings(g.Files)
+		gs = append(gs, g)
+	}
+	return gs, nil
+}
+
+// isDir returns whether path is an existing directory
+func isDir(path string) bool {
+	fi, err := os.Stat(path)
+	return err == nil && fi.IsDir()
+}
+
+// readManifest reads the groups of a series from a manifest with one '<label>;<file>' per line in version order, where consecutive lines with the same label form one group.
+// Relative files are relative to the manifest's directory. Empty lines and lines starting with '#' are ignored.
+func readManifest(path string) ([]seriesGroup, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var gs []seriesGroup
+	s := bufio.NewScanner(f)
+	nr := 0
+	for s.Scan() {
+		nr++
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.SplitN(line, ";", 2)
+		if len(fields) != 2 || strings.TrimSpace(fields[0]) == "" || strings.TrimSpace(fields[1]) == "" {
+			return nil, fmt.Errorf("manifest line %d: expected '<label>;<file>', was '%s'", nr, line)
+		}
+		label, file := strings.TrimSpace(fields[0]), strings.TrimSpace(fields[1])
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(filepath.Dir(path), file)
+		}
+
+		if l := len(gs); l > 0 && gs[l-1].Label == label {
+			gs[l-1].Files = append(gs[l-1].Files, file)
+			continue
+		}
+		gs = append(gs, seriesGroup{
+			Label: label,
+			Files: []string{file},
+		})
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return gs, nil
+}
+
+// groupInputs returns the filtered and transformed input per group
+func groupInputs(inputCtx context.Context, cfg *config) ([]bench.Chan, error) {
+	cs := make([]bench.Chan, len(cfg.groups))
+	for i, g := range cfg.groups {
+		c, err := mergedInput(inputCtx, cfg, g.Files)
+		if err != nil {
+			return nil, err
+		}
+		if cfg.filter.Filter != nil {
+			c = bench.FilterChanContext(inputCtx, cfg.filter.Filter, c)
+		}
+		if cfg.transformer1.ExecutionTransformer != nil {
+			c = bench.TransformChanContext(inputCtx, cfg.transformer1.ExecutionTransformer, c)
+		}
+		cs[i] = c
+	}
+	return cs, nil
+}
+
+// commitInput returns the filtered and transformed executions of the first file grouped by benchmark, with one execution per commit of its column 'commit' in the order of the rows (see bench.GroupCommitsContext)
+func commitInput(ctx, inputCtx context.Context, cfg *config) (<-chan bench.CommitExecutions, error) {
+	c, err := inputPerCommit(inputCtx, cfg, cfg.f1[0])
+	if err != nil {
+		return nil, err
+	}
+	if cfg.filter.Filter != nil {
+		c = bench.FilterChanContext(inputCtx, cfg.filter.Filter, c)
+	}
+	if cfg.transformer1.ExecutionTransformer != nil {
+		c = bench.TransformChanContext(inputCtx, cfg.transformer1.ExecutionTransformer, c)
+	}
+	return bench.GroupCommitsContext(ctx, c), nil
+}
+
+// seriesVersions holds the executions of a benchmark per version (nil if the version does not have the benchmark) and the label of every version
+type seriesVersions struct {
+	bench.AlignedExecutions
+	Labels []string
+}
+
+// groupVersions aligns the executions of the groups, where every group is a version labelled by the group's label
+func groupVersions(ctx context.Context, cs []bench.Chan, groups []seriesGroup) <-chan seriesVersions {
+	labels := make([]string, len(groups))
+	for i, g := range groups {
+		labels[i] = g.Label
+	}
+	out := make(chan seriesVersions)
+
+	go func() {
+		defer close(out)
+		for ae := range bench.AlignChansContext(ctx, cs...) {
+			select {
+			case out <- seriesVersions{AlignedExecutions: ae, Labels: labels}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out
+}
+
+// commitVersions turns the commits of every benchmark into versions labelled by their commits
+func commitVersions(ctx context.Context, cc <-chan bench.CommitExecutions) <-chan seriesVersions {
+	out := make(chan seriesVersions)
+
+	go func() {
+		defer close(out)
+		for ce := range cc {
+			sv := seriesVersions{
+				AlignedExecutions: bench.AlignedExecutions{
+					Execs: ce.Execs,
+					Err:   ce.Err,
+				},
+			}
+			if ce.Err == nil {
+				sv.Labels = ce.Commits()
+			}
+			select {
+			case out <- sv:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out
+}
+
+// seriesResult holds the CIs of a benchmark per version and its ratios to the previous version having the benchmark and to the baseline (the first version)
+type seriesResult struct {
+	Benchmark *bench.B
+	Labels    []string
+	CIs       [][]stat.CI
+	// Prev is the index of the previous version per version, or -1
+	Prev       []int
+	PrevRatios [][]stat.CIRatio
+	BaseRatios [][]stat.CIRatio
+	Err        error
+}
+
+func seriesResults(ctx context.Context, vc <-chan seriesVersions, ciFunc bootstrap.CIFunc, ciRatioFunc bootstrap.CIRatioFunc, maxInFlight int) <-chan seriesResult {
+	out := make(chan seriesResult)
+	o := ordered.New(ctx, maxInFlight, func() { close(out) })
+
+	go func() {
+		defer o.Close()
+		for sv := range vc {
+			sv := sv
+			o.Submit(func() func() {
+				res := seriesAnalysis(ctx, sv, ciFunc, ciRatioFunc)
+				return func() {
+					select {
+					case out <- res:
+					case <-ctx.Done():
+					}
+				}
+			})
+		}
+	}()
+
+	return out
+}
+
+func seriesAnalysis(ctx context.Context, sv seriesVersions, ciFunc bootstrap.CIFunc, ciRatioFunc bootstrap.CIRatioFunc) seriesResult {
+	if sv.Err != nil {
+		return seriesResult{
+			Err: sv.Err,
+		}
+	}
+
+	n := len(sv.Execs)
+	res := seriesResult{
+		Benchmark:  sv.Benchmark(),
+		Labels:     sv.Labels,
+		CIs:        make([][]stat.CI, n),
+		Prev:       make([]int, n),
+		PrevRatios: make([][]stat.CIRatio, n),
+		BaseRatios: make([][]stat.CIRatio, n),
+	}
+	fail := func(err error) seriesResult {
+		return seriesResult{
+			Benchmark: res.Benchmark,
+			Err:       fmt.Errorf("%v: %w", res.Benchmark, err),
+		}
+	}
+
+	prev := -1
+	base := sv.Execs[0]
+	for i, e := range sv.Execs {
+		res.Prev[i] = -1
+		if e == nil {
+			continue
+		}
+
+		cis, err := ciFunc(ctx, e)
+		if err != nil {
+			return fail(err)
+		}
+		res.CIs[i] = cis
+
+		if prev != -1 {
+			res.Prev[i] = prev
+			cirs, _, err := ciRatioFunc(ctx, sv.Execs[prev], e)
+			if err != nil {
+				return fail(err)
+			}
+			res.PrevRatios[i] = cirs
+			if prev == 0 {
+				res.BaseRatios[i] = cirs
+			}
+		}
+		if i > 0 && base != nil && res.BaseRatios[i] == nil {
+			cirs, _, err := ciRatioFunc(ctx, base, e)
+			if err != nil {
+				return fail(err)
+			}
+			res.BaseRatios[i] = cirs
+		}
+		prev = i
+	}
+	return res
+}
+
+// seriesLines formats the CSV rows of a series result, one per version having the benchmark and significance level, where the ratio columns are empty if there is no previous version or baseline
+func seriesLines(res seriesResult, outputMetric bool) []string {
+	b := res.Benchmark
+	ratioColumns := func(cirs []stat.CIRatio, l int) string {
+		if cirs == nil {
+			if outputMetric {
+				return ";;"
+			}
+			return ";"
+		}
+		ci := cirs[l].CIRatio
+		if outputMetric {
+			return fmt.Sprintf("%e;%e;%e", ci.Metric, ci.Lower, ci.Upper)
+		}
+		return fmt.Sprintf("%e;%e", ci.Lower, ci.Upper)
+	}
+
+	var lines []string
+	for i, cis := range res.CIs {
+		for l, ci := range cis {
+			prevLabel := ""
+			if p := res.Prev[i]; p != -1 {
+				prevLabel = res.Labels[p]
+			}
+
+			var line string
+			if outputMetric {
+				line = fmt.Sprintf(
+					"%s;%s;%s;%s;%e;%e;%e;%.2f",
+					b.Name, b.FunctionParams, b.PerfParams, res.Labels[i],
+					ci.Metric, ci.Lower, ci.Upper, ci.Level,
+				)
+			} else {
+				line = fmt.Sprintf(
+					"%s;%s;%s;%s;%e;%e;%.2f",
+					b.Name, b.FunctionParams, b.PerfParams, res.Labels[i],
+					ci.Lower, ci.Upper, ci.Level,
+				)
+			}
+			lines = append(lines, fmt.Sprintf("%s;%s;%s;%s", line, prevLabel, ratioColumns(res.PrevRatios[i], l), ratioColumns(res.BaseRatios[i], l)))
+		}
+	}
+	return lines
+}
+
+// firstChange returns the comment row of the first change between consecutive versions at the first significance level, i.e., the first ratio CI to the previous version that does not contain 1, or false if the benchmark did not change
+func firstChange(res seriesResult) (string, bool) {
+	for i, cirs := range res.PrevRatios {
+		if cirs == nil {
+			continue
+		}
+		prev := cirs[0].CIRatio
+		if prev.Lower > 1 || prev.Upper < 1 {
+			return fmt.Sprintf("# first change: %s: %s -> %s: ratio %e [%e, %e] at %.2f", res.Benchmark, res.Labels[res.Prev[i]], res.Labels[i], prev.Metric, prev.Lower, prev.Upper, prev.Level), true
+		}
+	}
+	return "", false
+}
+
+// series reports the CIs of every benchmark per version and its ratios to the previous version and the baseline, either with the commits of the column 'commit' of the first file as versions (if there are no groups) or with a version per group
+func series(ctx, inputCtx context.Context, cfg *config, ciFunc bootstrap.CIFunc, ciRatioFunc bootstrap.CIRatioFunc) {
+	var vc <-chan seriesVersions
+	if cfg.groups == nil {
+		cc, err := commitInput(ctx, inputCtx, cfg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return
+		}
+		vc = commitVersions(ctx, cc)
+	} else {
+		cs, err := groupInputs(inputCtx, cfg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return
+		}
+		vc = groupVersions(ctx, cs, cfg.groups)
+	}
+
+	rc := seriesResults(ctx, vc, ciFunc, ciRatioFunc, cfg.concurrentBenchmarks)
+
+	printMemStats(cfg.printMem)
+
+	var changes []string
+	for res := range rc {
+		if res.Err != nil {
+			fmt.Fprintf(os.Stderr, "Error while retrieving CI result: %v\n", res.Err)
+			continue
+		}
+
+		for _, line := range seriesLines(res, cfg.outputMetric) {
+			fmt.Fprintln(os.Stdout, line)
+		}
+		if c, ok := firstChange(res); ok {
+			changes = append(changes, c)
+		}
+		printMemStats(cfg.printMem)
+	}
+
+	for _, c := range changes {
+		fmt.Fprintln(os.Stdout, c)
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/chrstphlbr/pa/pkg/bench"
+	"github.com/chrstphlbr/pa/pkg/bootstrap"
+	"github.com/chrstphlbr/pa/pkg/stat"
+)
+
+// seriesExecution returns an execution of benchmark b with 5 iterations around value
+func seriesExecution(t *testing.T, b string, value float64) *bench.Execution {
+	var sb strings.Builder
+	sb.WriteString("project;commit;benchmark;params;instance;trial;fork;iteration;mode;unit;value_count;value\n")
+	for it := 1; it <= 5; it++ {
+		fmt.Fprintf(&sb, "p;c;%s;;i1;1;1;%d;avgt;ns/op;1;%g\n", b, it, value+float64(it)/10)
+	}
+
+	c, err := bench.FromCSV(context.Background(), strings.NewReader(sb.String()))
+	if err != nil {
+		t.Fatalf("Could not read CSV: %v", err)
+	}
+	var e *bench.Execution
+	for ev := range c {
+		switch ev.Type {
+		case bench.ExecNext:
+			e = ev.Exec
+		case bench.ExecError:
+			t.Fatalf("Unexpected error: %v", ev.Err)
+		}
+	}
+	if e == nil {
+		t.Fatalf("No execution")
+	}
+	return e
+}
+
+func TestSeriesMissingVersions(t *testing.T) {
+	ciFunc := bootstrap.CIFuncSetup(bootstrap.FixedSimulations(100), 1, stat.Mean, []float64{0.01}, bootstrap.ResampleAllLevels(bootstrap.MeanInvocations))
+	ciRatioFunc := bootstrap.CIRatioFuncSetup(bootstrap.FixedSimulations(100), 1, stat.Mean, []float64{0.01}, bootstrap.ResampleAllLevels(bootstrap.MeanInvocations))
+
+	// the baseline v0 and v2 do not have the benchmark
+	sv := seriesVersions{
+		AlignedExecutions: bench.AlignedExecutions{
+			Execs: []*bench.Execution{nil, seriesExecution(t, "b", 10), nil, seriesExecution(t, "b", 20)},
+		},
+		Labels: []string{"v0", "v1", "v2", "v3"},
+	}
+	res := seriesAnalysis(context.Background(), sv, ciFunc, ciRatioFunc)
+	if res.Err != nil {
+		t.Fatalf("Unexpected error: %v", res.Err)
+	}
+
+	expectedPrev := []int{-1, -1, -1, 1}
+	for i, p := range res.Prev {
+		if p != expectedPrev[i] {
+			t.Fatalf("Expected previous version %d of version %d, was %d", expectedPrev[i], i, p)
+		}
+	}
+	for i, cirs := range res.BaseRatios {
+		if cirs != nil {
+			t.Fatalf("Expected no baseline ratio of version %d, was %v", i, cirs)
+		}
+	}
+
+	for _, outputMetric := range []bool{false, true} {
+		lines := seriesLines(res, outputMetric)
+		if len(lines) != 2 {
+			t.Fatalf("Expected 2 lines, was %d: %v", len(lines), lines)
+		}
+
+		columns, ciColumns := 12, 2
+		if outputMetric {
+			columns, ciColumns = 15, 3
+		}
+		for i, line := range lines {
+			fields := strings.Split(line, ";")
+			if len(fields) != columns {
+				t.Fatalf("Expected %d columns, was %d: %s", columns, len(fields), line)
+			}
+			version, prevVersion := fields[3], fields[columns-2*ciColumns-1]
+			prev, base := fields[columns-2*ciColumns:columns-ciColumns], fields[columns-ciColumns:]
+
+			switch i {
+			case 0:
+				if version != "v1" || prevVersion != "" {
+					t.Fatalf("Expected version v1 without previous version, was: %s", line)
+				}
+				for _, f := range prev {
+					if f != "" {
+						t.Fatalf("Expected empty previous ratio columns, was: %s", line)
+					}
+				}
+			case 1:
+				if version != "v3" || prevVersion != "v1" {
+					t.Fatalf("Expected version v3 with previous version v1, was: %s", line)
+				}
+				for _, f := range prev {
+					if f == "" {
+						t.Fatalf("Expected previous ratio columns, was: %s", line)
+					}
+				}
+			}
+			for _, f := range base {
+				if f != "" {
+					t.Fatalf("Expected empty baseline ratio columns, was: %s", line)
+				}
+			}
+		}
+	}
+
+	c, ok := firstChange(res)
+	if !ok || !strings.Contains(c, "v1 -> v3") {
+		t.Fatalf("Expected first change from v1 to v3, was '%s'", c)
+	}
+}
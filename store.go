@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/chrstphlbr/pa/pkg/bench"
+	"github.com/chrstphlbr/pa/pkg/store"
+)
+
+// baselinePrefix references a snapshot of the store instead of a file, i.e., '@baseline:<commit>' or '@baseline:<project>/<commit>'
+const baselinePrefix = "@baseline:"
+
+// storeAdd adds the executions of every file to the store, with one snapshot per project and commit
+func storeAdd(ctx context.Context, cfg *config) {
+	s, err := store.Open(cfg.storeDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return
+	}
+
+	for _, fn := range cfg.f1 {
+		c, err := inputPerCommit(ctx, cfg, fn)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return
+		}
+		if cfg.filter.Filter != nil {
+			c = bench.FilterChanContext(ctx, cfg.filter.Filter, c)
+		}
+
+		es, err := s.Add(ctx, c)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Could not add file '%s' to store '%s': %v\n", fn, s.Dir(), err)
+			return
+		}
+		for _, e := range es {
+			fmt.Fprintln(os.Stdout, entryLine(e))
+		}
+	}
+}
+
+// storeList lists the snapshots of the store
+func storeList(cfg *config) {
+	s, err := store.Open(cfg.storeDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return
+	}
+
+	es, err := s.List()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not list store '%s': %v\n", s.Dir(), err)
+		return
+	}
+	for _, e := range es {
+		fmt.Fprintln(os.Stdout, entryLine(e))
+	}
+}
+
+func entryLine(e store.Entry) string {
+	return fmt.Sprintf("%s;%s;%s;%d", e.Project, e.Commit, e.Created.Format(time.RFC3339), e.Size)
+}